@@ -350,6 +350,7 @@ class Collector(object):
             'name': metric.getMetricPath(),
             'value': value,
             'type': metric.metric_type,
+            'timestamp': metric.timestamp,
             'dimensions': {
                 'prefix': metric.getPathPrefix(),
                 'collector': metric.getCollectorPath(),
@@ -57,7 +57,9 @@ func newHandler(name string, c config.Config, dps int) handler.Handler {
 	h := handler.New(name)
 	h.SetInterval(1)
 	h.SetMaxBufferSize(dps)
-	h.Configure(c.Handlers[name])
+	if err := h.Configure(c.Handlers[name]); err != nil {
+		log.Fatal("Handler ", name, " is misconfigured: ", err)
+	}
 	return h
 }
 
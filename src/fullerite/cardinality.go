@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/internalserver"
+	"fullerite/metric"
+
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// how many metric names and dimension keys the cardinality report lists
+const cardinalityReportTop = 10
+
+// cardinalityTracker counts the unique series a collector emits per interval
+// and enforces its cardinality limit. It is only used by the collector reader.
+type cardinalityTracker struct {
+	limit  int
+	policy string
+
+	series          map[string]bool
+	metricSeries    map[string]int
+	dimensionSeries map[string]int
+	overLimit       uint64
+}
+
+func newCardinalityTracker(c collector.Collector) *cardinalityTracker {
+	t := &cardinalityTracker{
+		limit:  c.CardinalityLimit(),
+		policy: c.CardinalityPolicy(),
+	}
+	t.reset()
+	return t
+}
+
+func (t *cardinalityTracker) reset() {
+	t.series = make(map[string]bool)
+	t.metricSeries = make(map[string]int)
+	t.dimensionSeries = make(map[string]int)
+	t.overLimit = 0
+}
+
+// observe records the series of the metric, it returns false
+// when the metric should be dropped for being past the limit
+func (t *cardinalityTracker) observe(m *metric.Metric) bool {
+	key := m.SeriesKey()
+	if t.series[key] {
+		return true
+	}
+
+	if t.limit > 0 && len(t.series) >= t.limit {
+		t.overLimit++
+		if t.policy != collector.CardinalityMerge {
+			return false
+		}
+		// fold it into one series per metric name
+		merged := map[string]string{}
+		if value, ok := m.GetDimensionValue("collector"); ok {
+			merged["collector"] = value
+		}
+		m.Dimensions = merged
+		key = m.SeriesKey()
+		if t.series[key] {
+			return true
+		}
+	}
+
+	t.series[key] = true
+	t.metricSeries[m.Name]++
+	for dimension := range m.Dimensions {
+		t.dimensionSeries[dimension]++
+	}
+	return true
+}
+
+// rollover reports the interval that ended and starts counting again,
+// along with the metric telling that the limit was exceeded if it was
+func (t *cardinalityTracker) rollover(collectorName string) (internalserver.CardinalityReport, *metric.Metric) {
+	report := internalserver.CardinalityReport{
+		Series:        len(t.series),
+		Limit:         t.limit,
+		OverLimit:     t.overLimit,
+		TopMetrics:    topSeriesCounts(t.metricSeries),
+		TopDimensions: topSeriesCounts(t.dimensionSeries),
+	}
+
+	var exceeded *metric.Metric
+	if t.overLimit > 0 {
+		m := metric.WithValue("fullerite.cardinality_exceeded", float64(t.overLimit))
+		m.AddDimension("collector", collectorName)
+		m.AddDimension("limit", fmt.Sprintf("%d", t.limit))
+		m.AddDimension("policy", t.policy)
+		exceeded = &m
+	}
+
+	t.reset()
+	return report, exceeded
+}
+
+func topSeriesCounts(counts map[string]int) []internalserver.SeriesCount {
+	top := make([]internalserver.SeriesCount, 0, len(counts))
+	for name, series := range counts {
+		top = append(top, internalserver.SeriesCount{Name: name, Series: series})
+	}
+	sort.Sort(bySeries(top))
+	if len(top) > cardinalityReportTop {
+		top = top[:cardinalityReportTop]
+	}
+	return top
+}
+
+type bySeries []internalserver.SeriesCount
+
+func (s bySeries) Len() int      { return len(s) }
+func (s bySeries) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySeries) Less(i, j int) bool {
+	if s[i].Series != s[j].Series {
+		return s[i].Series > s[j].Series
+	}
+	return s[i].Name < s[j].Name
+}
+
+// cardinalityReports keeps the last report of every running collector
+// for the internal server
+var cardinalityReports = struct {
+	sync.RWMutex
+	reports map[string]internalserver.CardinalityReport
+}{reports: make(map[string]internalserver.CardinalityReport)}
+
+func setCardinalityReport(collectorName string, report internalserver.CardinalityReport) {
+	cardinalityReports.Lock()
+	defer cardinalityReports.Unlock()
+	cardinalityReports.reports[collectorName] = report
+}
+
+func removeCardinalityReport(collectorName string) {
+	cardinalityReports.Lock()
+	defer cardinalityReports.Unlock()
+	delete(cardinalityReports.reports, collectorName)
+}
+
+func readCardinalityReports() map[string]internalserver.CardinalityReport {
+	cardinalityReports.RLock()
+	defer cardinalityReports.RUnlock()
+	reports := make(map[string]internalserver.CardinalityReport, len(cardinalityReports.reports))
+	for name, report := range cardinalityReports.reports {
+		reports[name] = report
+	}
+	return reports
+}
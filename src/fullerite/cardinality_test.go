@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/handler"
+	"fullerite/metric"
+
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildCardinalityTestCollector(policy string) collector.Collector {
+	col := collector.New("Test")
+	col.SetInterval(1)
+	col.Configure(map[string]interface{}{
+		"cardinality_limit":  2,
+		"cardinality_policy": policy,
+	})
+	return col
+}
+
+func buildCardinalityTestMetric(name, pid string) metric.Metric {
+	m := metric.New(name)
+	m.AddDimension("collector", "Test")
+	m.AddDimension("pid", pid)
+	return m
+}
+
+func TestCardinalityLimitDrops(t *testing.T) {
+	tracker := newCardinalityTracker(buildCardinalityTestCollector(collector.CardinalityDrop))
+
+	for _, pid := range []string{"1", "2", "1"} {
+		m := buildCardinalityTestMetric("cpu", pid)
+		assert.True(t, tracker.observe(&m))
+	}
+	m := buildCardinalityTestMetric("cpu", "3")
+	assert.False(t, tracker.observe(&m))
+
+	report, exceeded := tracker.rollover("Test")
+	assert.Equal(t, 2, report.Series)
+	assert.Equal(t, uint64(1), report.OverLimit)
+	assert.NotNil(t, exceeded)
+	assert.Equal(t, "fullerite.cardinality_exceeded", exceeded.Name)
+	assert.Equal(t, 1.0, exceeded.Value)
+	assert.Equal(t, "Test", exceeded.Dimensions["collector"])
+
+	// a new interval starts from scratch
+	m = buildCardinalityTestMetric("cpu", "3")
+	assert.True(t, tracker.observe(&m))
+	_, exceeded = tracker.rollover("Test")
+	assert.Nil(t, exceeded)
+}
+
+func TestCardinalityLimitMerges(t *testing.T) {
+	tracker := newCardinalityTracker(buildCardinalityTestCollector(collector.CardinalityMerge))
+
+	for _, pid := range []string{"1", "2", "3", "4"} {
+		m := buildCardinalityTestMetric("cpu", pid)
+		assert.True(t, tracker.observe(&m))
+		if pid == "3" || pid == "4" {
+			assert.Equal(t, map[string]string{"collector": "Test"}, m.Dimensions)
+		}
+	}
+
+	report, _ := tracker.rollover("Test")
+	assert.Equal(t, 3, report.Series)
+	assert.Equal(t, uint64(2), report.OverLimit)
+}
+
+func TestCardinalityReport(t *testing.T) {
+	tracker := newCardinalityTracker(collector.New("Test"))
+	for i := 0; i < 20; i++ {
+		m := buildCardinalityTestMetric(fmt.Sprintf("metric%d", i), "1")
+		tracker.observe(&m)
+		m = buildCardinalityTestMetric("noisy", fmt.Sprintf("%d", i))
+		assert.True(t, tracker.observe(&m), "there is no limit by default")
+	}
+
+	report, exceeded := tracker.rollover("Test")
+	assert.Nil(t, exceeded)
+	assert.Equal(t, 40, report.Series)
+	assert.Equal(t, cardinalityReportTop, len(report.TopMetrics))
+	assert.Equal(t, "noisy", report.TopMetrics[0].Name)
+	assert.Equal(t, 20, report.TopMetrics[0].Series)
+	assert.Equal(t, 2, len(report.TopDimensions))
+	assert.Equal(t, 40, report.TopDimensions[0].Series)
+}
+
+func TestReadFromCollectorReportsCardinality(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := buildCardinalityTestCollector(collector.CardinalityDrop)
+
+	collectorChannel := map[string]handler.CollectorEnd{
+		"Test": handler.CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 1},
+	}
+	testHandler := handler.New("Log")
+	testHandler.SetCollectorEndpoints(collectorChannel)
+
+	received := []string{}
+	reportedSeries := 0
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for m := range collectorChannel["Test"].Channel {
+			received = append(received, m.Name)
+			if m.Name == "fullerite.cardinality_exceeded" {
+				// the report is published before the metric is sent
+				reportedSeries = readCardinalityReports()["Test"].Series
+			}
+		}
+	}()
+	go func() {
+		for _, pid := range []string{"1", "2", "3"} {
+			col.Channel() <- buildCardinalityTestMetric("cpu", pid)
+		}
+		time.Sleep(1100 * time.Millisecond)
+		col.Channel() <- buildCardinalityTestMetric("cpu", "1")
+		close(col.Channel())
+	}()
+	readFromCollector(col, newHandlerSet([]handler.Handler{testHandler}))
+	close(collectorChannel["Test"].Channel)
+	wg.Wait()
+
+	assert.Equal(t, []string{"cpu", "cpu", "fullerite.cardinality_exceeded", "cpu"}, received)
+	assert.Equal(t, 2, reportedSeries)
+	assert.NotContains(t, readCardinalityReports(), "Test")
+}
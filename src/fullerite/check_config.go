@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/config"
+	"fullerite/handler"
+	"fullerite/internalserver"
+	"fullerite/processor"
+
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+func checkConfig(ctx *cli.Context) {
+	// only the problems are of interest here, not what the components log
+	logrus.SetLevel(logrus.FatalLevel)
+
+	configFile := ctx.String("config")
+	problems := checkConfigFile(configFile)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%s: %d problem(s) found\n", configFile, len(problems))
+		os.Exit(1)
+	}
+	fmt.Printf("%s: OK\n", configFile)
+}
+
+// checkConfigFile loads a fullerite configuration and the configurations of
+// all its collectors and handlers and returns everything that is wrong with
+// them. Every collector and handler is configured the way the daemon does it,
+// so a config passes only if the daemon would start all of them.
+func checkConfigFile(configFile string) []string {
+	raw, err := config.ReadFile(configFile)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	c, err := config.Parse(raw)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	problems := []string{}
+	for _, key := range config.UnknownKeys(raw) {
+		problems = append(problems, fmt.Sprintf("unknown key %q", key))
+	}
+	for _, problem := range internalserver.CheckConfig(c.InternalServerConfig) {
+		problems = append(problems, fmt.Sprintf("internalServer: %s", problem))
+	}
+	if len(c.Processors) > 0 {
+		if _, err := processor.New(c.Processors); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, name := range c.Collectors {
+		inst := collector.New(name)
+		if inst == nil {
+			problems = append(problems, fmt.Sprintf("collector %s: unknown collector", name))
+			continue
+		}
+		conf, err := c.GetCollectorConfig(name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("collector %s: %s", name, err))
+			continue
+		}
+		found := inst.Options().Check(conf)
+		found = append(found, configureProblems(configureCollector(inst, c, conf), found)...)
+		for _, problem := range found {
+			problems = append(problems, fmt.Sprintf("collector %s: %s", name, problem))
+		}
+	}
+	problems = append(problems, checkDiamondCollectors(c)...)
+
+	handlerNames := []string{}
+	for name := range c.Handlers {
+		handlerNames = append(handlerNames, name)
+	}
+	sort.Strings(handlerNames)
+	for _, name := range handlerNames {
+		inst := handler.New(name)
+		if inst == nil {
+			problems = append(problems, fmt.Sprintf("handler %s: unknown handler", name))
+			continue
+		}
+		found := inst.Options().Check(c.Handlers[name])
+		found = append(found, configureProblems(configureHandler(inst, c, c.Handlers[name]), found)...)
+		for _, problem := range found {
+			problems = append(problems, fmt.Sprintf("handler %s: %s", name, problem))
+		}
+	}
+	return problems
+}
+
+// configureProblems splits the error returned by Configure into problems,
+// leaving out the ones the options check already found
+func configureProblems(err error, found []string) []string {
+	if err == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(found))
+	for _, problem := range found {
+		known[problem] = true
+	}
+	problems := []string{}
+	for _, problem := range strings.Split(err.Error(), "; ") {
+		if !known[problem] {
+			known[problem] = true
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// checkDiamondCollectors checks the diamond collectors the way the diamond
+// server loads them: the first word of the name is the class of the collector,
+// which has to be in diamondCollectorsPath, and its config is the name with
+// underscores for spaces in collectorsConfigPath
+func checkDiamondCollectors(c config.Config) []string {
+	if len(c.DiamondCollectors) == 0 {
+		return nil
+	}
+
+	problems := []string{}
+	classes, err := diamondCollectorClasses(c.DiamondCollectorsPath)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("diamondCollectorsPath: %s", err))
+	}
+	for _, name := range c.DiamondCollectors {
+		words := strings.Fields(name)
+		if len(words) == 0 || !strings.Contains(words[0], "Collector") {
+			problems = append(problems, fmt.Sprintf("diamond collector %s: the name should start with a class containing Collector", name))
+			continue
+		}
+		if err == nil && !classes[words[0]] {
+			problems = append(problems, fmt.Sprintf("diamond collector %s: no class %s in %s", name, words[0], c.DiamondCollectorsPath))
+		}
+		configFile := strings.Replace(c.CollectorsConfigPath+"/"+name, " ", "_", -1) + ".conf"
+		if _, err := config.ReadFile(configFile); err != nil {
+			problems = append(problems, fmt.Sprintf("diamond collector %s: %s", name, err))
+		}
+	}
+	return problems
+}
+
+var pythonClass = regexp.MustCompile(`(?m)^class\s+(\w+)`)
+
+// diamondCollectorClasses lists the classes defined in the modules the
+// diamond server imports from the comma separated paths
+func diamondCollectorClasses(paths string) (map[string]bool, error) {
+	if strings.TrimSpace(paths) == "" {
+		return nil, fmt.Errorf("should be set to run diamond collectors")
+	}
+
+	classes := map[string]bool{}
+	for _, root := range strings.Split(paths, ",") {
+		err := filepath.Walk(strings.TrimSpace(root), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			name := info.Name()
+			if info.IsDir() {
+				if strings.HasSuffix(name, "tests") || strings.HasSuffix(name, "fixtures") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(name, ".py") || strings.HasPrefix(name, "test") || strings.HasPrefix(name, ".") {
+				return nil
+			}
+			source, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, match := range pythonClass.FindAllStringSubmatch(string(source), -1) {
+				classes[match[1]] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return classes, nil
+}
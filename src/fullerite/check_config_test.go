@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "test", "interval": "10"}`)
+	writeCollectorConfig(t, dir, "SocketQueue", `{"interval": "often"}`)
+	writeCollectorConfig(t, dir, "Test_other", `{"metricNames": "test"}`)
+	configFile := filepath.Join(dir, "fullerite.conf")
+	writeCollectorConfig(t, dir, "fullerite", fmt.Sprintf(`{
+		"collectorsConfigPath": %q,
+		"collectors": ["Test", "SocketQueue", "Test other", "Missing", "CPUInfo"],
+		"handlers": {
+			"Graphite": {"server": "localhost", "port": "2003"},
+			"Kairos": {"server": "localhost"},
+			"Unknown": {}
+		},
+		"colectors": []
+	}`, dir))
+
+	assert.Equal(t, []string{
+		`unknown key "colectors"`,
+		`collector SocketQueue: missing required key "PortList"`,
+		`collector SocketQueue: "interval" should be of type int, got string`,
+		`collector Test other: unknown key "metricNames"`,
+		`collector Missing: unknown collector`,
+		fmt.Sprintf("collector CPUInfo: open %s/CPUInfo.conf: no such file or directory", dir),
+		`handler Kairos: missing required key "port"`,
+		`handler Unknown: unknown handler`,
+	}, checkConfigFile(configFile))
+}
+
+func TestCheckConfigFileValid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "test"}`)
+	writeCollectorConfig(t, dir, "fullerite", fmt.Sprintf(`{
+		"collectorsConfigPath": %q,
+		"collectors": ["Test"],
+		"handlers": {"Log": {"interval": 10}}
+	}`, dir))
+	assert.Equal(t, []string{}, checkConfigFile(filepath.Join(dir, "fullerite.conf")))
+
+	writeCollectorConfig(t, dir, "broken", `{"collectors": [`)
+	problems := checkConfigFile(filepath.Join(dir, "broken.conf"))
+	assert.Equal(t, 1, len(problems))
+	assert.Contains(t, problems[0], "invalid JSON")
+}
+
+func TestCheckConfigFileConfiguresComponents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{
+		"metrics_blacklist": ["(["],
+		"schedule": "bad cron",
+		"cardinality_policy": "x"
+	}`)
+	writeCollectorConfig(t, dir, "fullerite", fmt.Sprintf(`{
+		"collectorsConfigPath": %q,
+		"collectors": ["Test"],
+		"handlers": {"Log": {
+			"overflowPolicy": "bogus",
+			"convertCumulativeCounters": "nope",
+			"processors": [{"type": "drop", "pattern": "(["}]
+		}},
+		"processors": [{"type": "nope"}],
+		"internalServer": {"port": "high", "pprof": "yes", "controlPort": 1}
+	}`, dir))
+
+	assert.Equal(t, []string{
+		`internalServer: "port" should be of type int, got string`,
+		`internalServer: "pprof" should be of type bool, got string`,
+		`internalServer: unknown key "controlPort"`,
+		`invalid processors: processor 0: unknown type "nope"`,
+		"collector Test: metrics_blacklist: invalid pattern ([: error parsing regexp: missing closing ]: `[`",
+		`collector Test: unknown cardinality_policy x`,
+		`collector Test: schedule: "bad cron" should have 5 fields: minute, hour, day of month, month and day of week`,
+		"handler Log: invalid processors: processor 0: drop: pattern: error parsing regexp: missing closing ]: `[`",
+		`handler Log: unknown convertCumulativeCounters nope, should be rate or delta`,
+		`handler Log: unknown overflowPolicy bogus`,
+	}, checkConfigFile(filepath.Join(dir, "fullerite.conf")))
+}
+
+func TestCheckConfigFileDiamondCollectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	collectorsPath := filepath.Join(dir, "collectors")
+	assert.Nil(t, os.MkdirAll(filepath.Join(collectorsPath, "cpu"), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(collectorsPath, "tests"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(collectorsPath, "cpu", "cpu.py"),
+		[]byte("import diamond\n\nclass CPUCollector(diamond.collector.Collector):\n    pass\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(collectorsPath, "tests", "test_disk.py"),
+		[]byte("class DiskCollector(object):\n    pass\n"), 0644))
+
+	writeCollectorConfig(t, dir, "CPUCollector", `{}`)
+	writeCollectorConfig(t, dir, "fullerite", fmt.Sprintf(`{
+		"collectorsConfigPath": %q,
+		"diamondCollectorsPath": %q,
+		"diamondCollectors": ["CPUCollector", "CPUCollector other", "DiskCollector", "CPU"]
+	}`, dir, collectorsPath))
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("diamond collector CPUCollector other: open %s/CPUCollector_other.conf: no such file or directory", dir),
+		fmt.Sprintf("diamond collector DiskCollector: no class DiskCollector in %s", collectorsPath),
+		fmt.Sprintf("diamond collector DiskCollector: open %s/DiskCollector.conf: no such file or directory", dir),
+		"diamond collector CPU: the name should start with a class containing Collector",
+	}, checkConfigFile(filepath.Join(dir, "fullerite.conf")))
+}
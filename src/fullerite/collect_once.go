@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/config"
+	"fullerite/handler"
+	"fullerite/metric"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+)
+
+// The formats collect-once can print the metrics in
+const (
+	jsonOutput     = "json"
+	tableOutput    = "table"
+	graphiteOutput = "graphite"
+)
+
+func collectOnceCommand(ctx *cli.Context) {
+	initLogrus(ctx)
+	// stdout is for the metrics
+	logrus.SetOutput(os.Stderr)
+
+	if len(ctx.Args()) != 1 {
+		log.Error("You need to name the collector to run, see 'fullerite help collect-once'")
+		os.Exit(1)
+	}
+	name := ctx.Args()[0]
+
+	format := ctx.String("format")
+	if format != jsonOutput && format != tableOutput && format != graphiteOutput {
+		log.Error("Unknown format ", format, ", should be json, table or graphite")
+		os.Exit(1)
+	}
+
+	// the global settings are optional when the collector config is given
+	collectorConfigFile := ctx.String("collector-config")
+	configFile := ctx.String("config")
+	c := config.Config{}
+	if _, err := os.Stat(configFile); err == nil || collectorConfigFile == "" {
+		if c, err = config.ReadConfig(configFile); err != nil {
+			os.Exit(1)
+		}
+	}
+
+	var conf map[string]interface{}
+	var err error
+	if collectorConfigFile != "" {
+		conf, err = config.ReadCollectorConfig(collectorConfigFile)
+	} else {
+		conf, err = c.GetCollectorConfig(name)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+
+	inst := newCollector(name, c, conf)
+	if inst == nil {
+		os.Exit(1)
+	}
+	if inst.CollectorType() == "listener" {
+		log.Error(name, " is a listener, it only emits the metrics pushed to it")
+		os.Exit(1)
+	}
+
+	wait := time.Duration(inst.Interval()) * time.Second
+	if ctx.Int("wait") > 0 {
+		wait = time.Duration(ctx.Int("wait")) * time.Second
+	}
+	times := ctx.Int("times")
+	for i := 0; i < times; i++ {
+		metrics := collectOnce(inst, wait)
+		if format == tableOutput && i > 0 {
+			fmt.Println()
+		}
+		printMetricsAs(os.Stdout, format, metrics)
+	}
+}
+
+// collectOnce runs Collect and gathers what the collector emits for as long
+// as wait, the collection is cancelled once wait is over
+func collectOnce(inst collector.Collector, wait time.Duration) []metric.Metric {
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+	go inst.Collect(ctx)
+
+	metrics := []metric.Metric{}
+	deadline := time.After(wait)
+	for {
+		select {
+		case m := <-inst.Channel():
+			if prepareMetric(inst, &m) {
+				metrics = append(metrics, m)
+			}
+		case <-deadline:
+			return metrics
+		}
+	}
+}
+
+func printMetricsAs(out io.Writer, format string, metrics []metric.Metric) {
+	switch format {
+	case tableOutput:
+		w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTYPE\tVALUE\tDIMENSIONS")
+		for _, m := range metrics {
+			fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", m.Name, m.MetricType, m.Value, formatDimensions(m.Dimensions))
+		}
+		w.Flush()
+	case graphiteOutput:
+		for _, m := range metrics {
+			fmt.Fprint(out, handler.FormatGraphite("", nil, m))
+		}
+	default:
+		encoder := json.NewEncoder(out)
+		for _, m := range metrics {
+			encoder.Encode(m)
+		}
+	}
+}
+
+func formatDimensions(dimensions map[string]string) string {
+	pairs := make([]string, 0, len(dimensions))
+	for k, v := range dimensions {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fullerite/config"
+	"fullerite/metric"
+
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectOnce(t *testing.T) {
+	inst := newCollector("Fullerite", config.Config{}, map[string]interface{}{
+		"prefix":            "dry.",
+		"metrics_whitelist": []interface{}{"^NumGC$"},
+	})
+
+	metrics := collectOnce(inst, 500*time.Millisecond)
+	assert.Equal(t, 1, len(metrics))
+	assert.Equal(t, "dry.NumGC", metrics[0].Name)
+	assert.Equal(t, "Fullerite", metrics[0].Dimensions["collector"])
+	assert.False(t, metrics[0].Timestamp.IsZero())
+}
+
+func TestPrintMetricsAs(t *testing.T) {
+	m := metric.WithValue("test.metric", 1.5)
+	m.AddDimension("collector", "Test")
+	m.AddDimension("app", "fullerite")
+	m.Timestamp = time.Unix(1500000000, 0)
+	out := new(bytes.Buffer)
+
+	printMetricsAs(out, jsonOutput, []metric.Metric{m, m})
+	assert.Equal(t, `{"name":"test.metric","type":"gauge","value":1.5,"dimensions":{"app":"fullerite","collector":"Test"},"timestamp":1500000000}`+"\n", out.String()[:out.Len()/2])
+
+	out.Reset()
+	printMetricsAs(out, tableOutput, []metric.Metric{m})
+	assert.Equal(t, "NAME         TYPE   VALUE  DIMENSIONS\n"+
+		"test.metric  gauge  1.5    app=fullerite,collector=Test\n", out.String())
+
+	out.Reset()
+	printMetricsAs(out, graphiteOutput, []metric.Metric{m})
+	assert.Equal(t, "test_metric.app.fullerite.collector.Test 1.500000 1500000000\n", out.String())
+}
@@ -2,11 +2,13 @@ package collector
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"os/exec"
 	"os/user"
 
 	"encoding/json"
+	"fullerite/config"
 	"fullerite/metric"
 
 	l "github.com/Sirupsen/logrus"
@@ -37,20 +39,41 @@ func newAdHoc(channel chan metric.Metric, initialInterval int, log *l.Entry) Col
 	return a
 }
 
+var adHocOptions = config.Options{
+	{Name: "collectorFile", Type: config.StringOption,
+		Description: "script whose JSON output is turned into metrics"},
+}
+
 // Configure Override default parameters
-func (a *AdHoc) Configure(configMap map[string]interface{}) {
-	if collectorFile, exists := configMap["collectorFile"]; exists {
-		a.collectorFile = collectorFile.(string)
+func (a *AdHoc) Configure(configMap map[string]interface{}) error {
+	values, err := adHocOptions.Decode(configMap)
+	if values.Has("collectorFile") {
+		a.collectorFile = values.String("collectorFile")
 		// chmod ugoa+rwx
 		os.Chmod(a.collectorFile, 0777)
 	}
-	a.configureCommonParams(configMap)
+	return config.JoinErrors(err, a.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the AdHoc collector
+func (a *AdHoc) Options() config.Options {
+	return append(adHocOptions, commonOptions...)
+}
+
+var adHocMetrics = []MetricDescription{
+	{Name: "<metric>", Type: "<type>", Dimensions: []string{"adhoc", "<dimension>"},
+		Description: "whatever the collector file writes to stdout"},
+}
+
+// Metrics : the metrics emitted by the AdHoc collector
+func (a *AdHoc) Metrics() []MetricDescription {
+	return append(adHocMetrics, commonMetrics...)
 }
 
 // Collect Emits the metrics produce by the AdHoc script
-func (a AdHoc) Collect() {
+func (a AdHoc) Collect(ctx context.Context) {
 	a.log.Info("Collecting...")
-	cmd := exec.Command(a.collectorFile, []string{""}...)
+	cmd := exec.CommandContext(ctx, a.collectorFile, []string{""}...)
 	output, err := cmd.Output()
 	if err != nil {
 		a.log.Error("Could not run command: ", err)
@@ -3,8 +3,15 @@ package collector
 import (
 	"fullerite/config"
 	"fullerite/metric"
+	"fullerite/processor"
 
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	l "github.com/Sirupsen/logrus"
 )
@@ -14,12 +21,36 @@ const (
 	DefaultCollectionInterval = 10
 )
 
+// What happens to the new series of a collector past its cardinality limit
+const (
+	// CardinalityDrop drops the metrics of the new series
+	CardinalityDrop = "drop"
+	// CardinalityMerge strips the dimensions of the new series so they merge per metric name
+	CardinalityMerge = "merge"
+)
+
 var defaultLog = l.WithFields(l.Fields{"app": "fullerite", "pkg": "collector"})
 
+// stopMu guards the lazily created stop channels of all collectors
+var stopMu sync.Mutex
+
 // Collector defines the interface of a generic collector.
 type Collector interface {
-	Collect()
-	Configure(map[string]interface{})
+	// Collect emits the metrics of one collection, it should give up
+	// on the calls it makes once ctx is done
+	Collect(ctx context.Context)
+	// Configure applies the settings of the collector config, the
+	// collector should not be started when it returns an error
+	Configure(map[string]interface{}) error
+	// Options declares the keys Configure understands
+	Options() config.Options
+	// Metrics declares the metrics Collect emits
+	Metrics() []MetricDescription
+
+	// Stop asks the collector to stop collecting, the channel
+	// returned by StopChannel is closed once Stop is called
+	Stop()
+	StopChannel() <-chan struct{}
 
 	// taken care of by the base class
 	Name() string
@@ -32,8 +63,18 @@ type Collector interface {
 	SetCanonicalName(string)
 	Prefix() string
 	SetPrefix(string)
-	Blacklist() []string
-	SetBlacklist([]string)
+	// MetricAllowed tells whether the metric passes the
+	// metrics_whitelist and the metrics_blacklist
+	MetricAllowed(*metric.Metric) bool
+	Processors() processor.Chain
+	SetProcessors(processor.Chain)
+	CardinalityLimit() int
+	CardinalityPolicy() string
+	// Schedule tells when the collector runs
+	Schedule() Schedule
+	// CollectionTimeout is how long a collection may take,
+	// 0 means until the next one is due
+	CollectionTimeout() time.Duration
 }
 
 var collectorConstructs map[string]func(chan metric.Metric, int, *l.Entry) Collector
@@ -46,6 +87,16 @@ func RegisterCollector(name string, f func(chan metric.Metric, int, *l.Entry) Co
 	collectorConstructs[name] = f
 }
 
+// Names lists the registered collectors in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(collectorConstructs))
+	for name := range collectorConstructs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // New creates a new Collector based on the requested collector name.
 func New(name string) Collector {
 	var collector Collector
@@ -68,9 +119,54 @@ func New(name string) Collector {
 		collector.SetCollectorType("collector")
 	}
 	collector.SetCanonicalName(name)
+	// make sure the stop channel exists before the collector gets copied around
+	collector.StopChannel()
 	return collector
 }
 
+// commonOptions are the keys every collector understands
+var commonOptions = config.Options{
+	{Name: "interval", Type: config.IntOption,
+		Description: "seconds between two collections, the global interval by default"},
+	{Name: "prefix", Type: config.StringOption,
+		Description: "prepended to the name of every metric"},
+	{Name: "metrics_whitelist", Type: config.ListOption,
+		Description: "only the metrics matching one of these patterns are emitted"},
+	{Name: "metrics_blacklist", Type: config.ListOption,
+		Description: "the metrics matching any of these patterns are dropped"},
+	{Name: "processors", Type: config.ListOption,
+		Description: "transformations applied to the metrics, in order"},
+	{Name: "cardinality_limit", Type: config.IntOption, Default: 0,
+		Description: "unique series emitted per interval, 0 means no limit"},
+	{Name: "cardinality_policy", Type: config.StringOption, Default: CardinalityDrop,
+		Description: "drop or merge the new series past the cardinality limit"},
+	{Name: "splay", Type: config.IntOption, Default: 0,
+		Description: "seconds, every collection is delayed by an offset picked at random up to this"},
+	{Name: "collection_timeout", Type: config.IntOption, Default: 0,
+		Description: "seconds a collection may take before it is cancelled, until the next one is due by default"},
+	{Name: "align", Type: config.BoolOption, Default: false,
+		Description: "run on the wall-clock multiples of the interval rather than from the start"},
+	{Name: "schedule", Type: config.StringOption,
+		Description: "cron expression (minute hour day-of-month month day-of-week) to run on instead of the interval"},
+}
+
+// MetricDescription documents a metric a collector emits. The parts of the
+// name and the dimensions between angle brackets depend on what is collected
+type MetricDescription struct {
+	Name        string
+	Type        string
+	Dimensions  []string
+	Description string
+}
+
+// commonMetrics are emitted for every collector by the collection loop
+var commonMetrics = []MetricDescription{
+	{Name: "fullerite.collection_time_exceeded", Type: metric.Gauge, Dimensions: []string{"interval"},
+		Description: "a collection took longer than the interval, collectors of the listener type never emit it"},
+	{Name: "fullerite.cardinality_exceeded", Type: metric.Gauge, Dimensions: []string{"collector", "limit", "policy"},
+		Description: "metrics dropped or merged in an interval because of the cardinality_limit"},
+}
+
 type baseCollector struct {
 	// fulfill most of the rote parts of the collector interface
 	channel       chan metric.Metric
@@ -79,27 +175,117 @@ type baseCollector struct {
 	collectorType string
 	canonicalName string
 	prefix        string
-	blacklist     []string
+	whitelist     []metricPattern
+	blacklist     []metricPattern
+	processors    processor.Chain
+	stopChannel   chan struct{}
+
+	// unique series allowed per interval, 0 means no limit
+	cardinalityLimit  int
+	cardinalityPolicy string
+
+	// when the collector runs besides its interval
+	splayOffset time.Duration
+	align       bool
+	cronExpr    string
+	cron        *cronSchedule
+
+	collectionTimeout time.Duration
 
 	// intentionally exported
 	log *l.Entry
 }
 
-func (col *baseCollector) configureCommonParams(configMap map[string]interface{}) {
-	if interval, exists := configMap["interval"]; exists {
-		col.interval = config.GetAsInt(interval, DefaultCollectionInterval)
+// configureCommonParams applies the settings every collector understands,
+// the ones that are invalid are left out and reported in the error
+func (col *baseCollector) configureCommonParams(configMap map[string]interface{}) error {
+	values, err := commonOptions.Decode(configMap)
+	errs := []error{err}
+
+	if values.Has("interval") {
+		col.interval = values.Int("interval")
 	}
 
-	if prefix, exists := configMap["prefix"]; exists {
-		if str, ok := prefix.(string); ok {
-			col.prefix = str
+	if values.Has("prefix") {
+		col.prefix = values.String("prefix")
+	}
+
+	if values.Has("metrics_whitelist") {
+		whitelist, err := compileMetricPatterns(values.List("metrics_whitelist"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("metrics_whitelist: %s", err))
 		}
+		col.whitelist = whitelist
+	}
+
+	if values.Has("metrics_blacklist") {
+		blacklist, err := compileMetricPatterns(values.List("metrics_blacklist"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("metrics_blacklist: %s", err))
+		}
+		col.blacklist = blacklist
+	}
+
+	if values.Has("processors") {
+		chain, err := processor.FromConfig(values.List("processors"))
+		errs = append(errs, err)
+		col.processors = chain
+	}
+
+	col.cardinalityLimit = values.Int("cardinality_limit")
+	switch policy := values.String("cardinality_policy"); policy {
+	case CardinalityDrop, CardinalityMerge:
+		col.cardinalityPolicy = policy
+	default:
+		errs = append(errs, fmt.Errorf("unknown cardinality_policy %s", policy))
+	}
+
+	col.splayOffset = 0
+	if splay := values.Int("splay"); splay > 0 {
+		col.splayOffset = time.Duration(rand.Int63n(int64(splay) * int64(time.Second)))
+	} else if splay < 0 {
+		errs = append(errs, fmt.Errorf("splay should not be negative, got %d", splay))
+	}
+
+	col.collectionTimeout = time.Duration(values.Int("collection_timeout")) * time.Second
+	if col.collectionTimeout < 0 {
+		errs = append(errs, fmt.Errorf("collection_timeout should not be negative, got %d", values.Int("collection_timeout")))
 	}
 
-	if asInterface, exists := configMap["metrics_blacklist"]; exists {
-		col.blacklist = config.GetAsSlice(asInterface)
+	col.align = values.Bool("align")
+	if values.Has("schedule") {
+		cron, err := parseCron(values.String("schedule"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule: %s", err))
+		} else if col.align {
+			errs = append(errs, fmt.Errorf("align and schedule can't be used together"))
+		} else {
+			col.cronExpr = values.String("schedule")
+			col.cron = cron
+		}
 	}
+	return config.JoinErrors(errs...)
+}
+
+// Options : the keys understood by the collectors that have no settings of their own
+func (col *baseCollector) Options() config.Options {
+	return commonOptions
+}
 
+// Metrics : the metrics emitted by every collector
+func (col *baseCollector) Metrics() []MetricDescription {
+	return commonMetrics
+}
+
+// Schedule : when the collector runs, the splay offset is picked by Configure
+func (col *baseCollector) Schedule() Schedule {
+	return Schedule{
+		Interval: time.Duration(col.interval) * time.Second,
+		Offset:   col.splayOffset,
+		Align:    col.align,
+		Cron:     col.cronExpr,
+		cron:     col.cron,
+	}
 }
 
 // SetInterval : set the interval to collect on
@@ -122,9 +308,9 @@ func (col *baseCollector) SetCanonicalName(name string) {
 	col.canonicalName = name
 }
 
-// SetBlacklist : set collector optional metrics blacklist
-func (col *baseCollector) SetBlacklist(blacklist []string) {
-	col.blacklist = blacklist
+// SetProcessors : set the chain the metrics of the collector go through
+func (col *baseCollector) SetProcessors(chain processor.Chain) {
+	col.processors = chain
 }
 
 // CanonicalName : collector canonical name
@@ -162,7 +348,58 @@ func (col baseCollector) String() string {
 	return col.Name() + "Collector"
 }
 
-// Blacklist returns the list of metrics to be blacklisted for this collector
-func (col *baseCollector) Blacklist() []string {
-	return col.blacklist
+// MetricAllowed : with a whitelist the metric has to match one of its patterns,
+// and it must not match any pattern of the blacklist
+func (col *baseCollector) MetricAllowed(m *metric.Metric) bool {
+	if len(col.whitelist) > 0 && !matchesAny(m, col.whitelist) {
+		return false
+	}
+	return !matchesAny(m, col.blacklist)
+}
+
+// Processors returns the chain the metrics of this collector go through
+func (col *baseCollector) Processors() processor.Chain {
+	return col.processors
+}
+
+// CardinalityLimit : how many unique series the collector can emit per interval
+func (col *baseCollector) CardinalityLimit() int {
+	return col.cardinalityLimit
+}
+
+// CardinalityPolicy : what happens to the new series past the cardinality limit
+func (col *baseCollector) CardinalityPolicy() string {
+	if col.cardinalityPolicy == "" {
+		return CardinalityDrop
+	}
+	return col.cardinalityPolicy
+}
+
+// CollectionTimeout : how long a collection may take, 0 means until the next one is due
+func (col *baseCollector) CollectionTimeout() time.Duration {
+	return col.collectionTimeout
+}
+
+// StopChannel : channel that is closed once the collector has been stopped
+func (col *baseCollector) StopChannel() <-chan struct{} {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	if col.stopChannel == nil {
+		col.stopChannel = make(chan struct{})
+	}
+	return col.stopChannel
+}
+
+// Stop : ask the collector to stop, calling it more than once is harmless
+func (col *baseCollector) Stop() {
+	stopMu.Lock()
+	defer stopMu.Unlock()
+	if col.stopChannel == nil {
+		col.stopChannel = make(chan struct{})
+	}
+	select {
+	case <-col.stopChannel:
+	default:
+		close(col.stopChannel)
+	}
 }
@@ -37,3 +37,35 @@ func TestNewInvalidCollector(t *testing.T) {
 	c := New("INVALID COLLECTOR")
 	assert.Nil(t, c, "should not create a Collector")
 }
+
+func TestStop(t *testing.T) {
+	c := New("Test")
+	select {
+	case <-c.StopChannel():
+		t.Fatal("stop channel should be open before Stop")
+	default:
+	}
+
+	c.Stop()
+	c.Stop()
+	select {
+	case <-c.StopChannel():
+	default:
+		t.Fatal("stop channel should be closed after Stop")
+	}
+}
+
+func TestCollectorsDescribeTheirMetrics(t *testing.T) {
+	names := Names()
+	assert.Contains(t, names, "DockerStats")
+
+	for _, name := range names {
+		metrics := New(name).Metrics()
+		assert.True(t, len(metrics) > len(commonMetrics), name+" should describe its own metrics")
+		for _, m := range metrics {
+			assert.NotEmpty(t, m.Name, name)
+			assert.NotEmpty(t, m.Type, name)
+			assert.NotEmpty(t, m.Description, name)
+		}
+	}
+}
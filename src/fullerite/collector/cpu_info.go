@@ -1,9 +1,11 @@
 package collector
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
 
 	"bufio"
+	"context"
 	"os"
 	"strings"
 
@@ -43,16 +45,35 @@ func newCPUInfo(channel chan metric.Metric, initialInterval int, log *l.Entry) C
 	return c
 }
 
+var cpuInfoOptions = config.Options{
+	{Name: "procPath", Type: config.StringOption, Default: defaultProcPath,
+		Description: "file the CPU information is read from"},
+}
+
 // Configure Override default parameters
-func (c *CPUInfo) Configure(configMap map[string]interface{}) {
-	if procPath, exists := configMap["procPath"]; exists == true {
-		c.procPath = procPath.(string)
-	}
-	c.configureCommonParams(configMap)
+func (c *CPUInfo) Configure(configMap map[string]interface{}) error {
+	values, err := cpuInfoOptions.Decode(configMap)
+	c.procPath = values.String("procPath")
+	return config.JoinErrors(err, c.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the CPUInfo collector
+func (c *CPUInfo) Options() config.Options {
+	return append(cpuInfoOptions, commonOptions...)
+}
+
+var cpuInfoMetrics = []MetricDescription{
+	{Name: "cpu_info", Type: metric.Gauge, Dimensions: []string{"model"},
+		Description: "number of physical CPUs, model is the CPU model or mixed"},
+}
+
+// Metrics : the metrics emitted by the CPUInfo collector
+func (c *CPUInfo) Metrics() []MetricDescription {
+	return append(cpuInfoMetrics, commonMetrics...)
 }
 
 // Collect Emits the no of CPUs and ModelName
-func (c CPUInfo) Collect() {
+func (c CPUInfo) Collect(ctx context.Context) {
 	value, model, err := c.getCPUInfo()
 	if err != nil {
 		c.log.Error("Error while collecting metrics: ", err)
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fullerite/metric"
 	"fullerite/test_utils"
 	"path"
@@ -20,7 +21,7 @@ func TestCpuInfoCollect(t *testing.T) {
 	cpuInfo := newCPUInfo(testChannel, 100, testLogger)
 	cpuInfo.Configure(config)
 
-	go cpuInfo.Collect()
+	go cpuInfo.Collect(context.Background())
 
 	select {
 	case m := <-cpuInfo.Channel():
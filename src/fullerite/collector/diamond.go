@@ -1,12 +1,16 @@
 package collector
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
+	"fullerite/util"
 
 	"bufio"
+	"context"
 	"encoding/json"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	l "github.com/Sirupsen/logrus"
@@ -24,6 +28,10 @@ type Diamond struct {
 	port          string
 	serverStarted bool
 	incoming      chan []byte
+
+	// listener is guarded by mu since Stop closes it from another goroutine
+	mu       sync.Mutex
+	listener *net.TCPListener
 }
 
 func init() {
@@ -46,12 +54,31 @@ func newDiamond(channel chan metric.Metric, initialInterval int, log *l.Entry) C
 	return d
 }
 
+var diamondOptions = config.Options{
+	{Name: "port", Type: config.StringOption, Default: DefaultDiamondCollectorPort,
+		Description: "TCP port the diamond collectors send their metrics to"},
+}
+
 // Configure the collector
-func (d *Diamond) Configure(configMap map[string]interface{}) {
-	if port, exists := configMap["port"]; exists {
-		d.port = port.(string)
-	}
-	d.configureCommonParams(configMap)
+func (d *Diamond) Configure(configMap map[string]interface{}) error {
+	values, err := diamondOptions.Decode(configMap)
+	d.port = values.String("port")
+	return config.JoinErrors(err, d.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the Diamond collector
+func (d *Diamond) Options() config.Options {
+	return append(diamondOptions, commonOptions...)
+}
+
+var diamondMetrics = []MetricDescription{
+	{Name: "<metric>", Type: "<type>", Dimensions: []string{"diamond", "<dimension>"},
+		Description: "whatever the diamond collectors send"},
+}
+
+// Metrics : the metrics emitted by the Diamond collector
+func (d *Diamond) Metrics() []MetricDescription {
+	return append(diamondMetrics, commonMetrics...)
 }
 
 // Port returns Diamond collectors listen port
@@ -59,6 +86,28 @@ func (d *Diamond) Port() string {
 	return d.port
 }
 
+// Stop closes the diamond socket and stops reading from it
+func (d *Diamond) Stop() {
+	d.baseCollector.Stop()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.listener != nil {
+		d.log.Info("Closing diamond socket on port ", d.port)
+		d.listener.Close()
+		d.listener = nil
+	}
+}
+
+func (d *Diamond) stopped() bool {
+	select {
+	case <-d.StopChannel():
+		return true
+	default:
+		return false
+	}
+}
+
 // collectDiamond opens up and reads from the a TCP socket and
 // writes what it's read to a local channel. Diamond handler (running in
 // separate processes) write to the same port.
@@ -77,21 +126,37 @@ func (d *Diamond) collectDiamond() {
 		d.log.Fatal("Cannot listen on diamond socket", err)
 	}
 
+	d.mu.Lock()
+	if d.stopped() {
+		d.mu.Unlock()
+		l.Close()
+		return
+	}
+	d.listener = l
+	d.mu.Unlock()
+
 	// figure out the port bind for Port()
 	d.port = strings.Split(l.Addr().String(), ":")[1]
 
 	for {
 		conn, err := l.AcceptTCP()
 		if err != nil {
+			if d.stopped() {
+				return
+			}
 			d.log.Fatal(err)
 		}
 		go d.readDiamondMetrics(conn)
 	}
 }
 
-// readDiamondMetrics reads from the connection
+// readDiamondMetrics reads from the connection, a panic closes it
+// and Diamond connects again
 func (d *Diamond) readDiamondMetrics(conn *net.TCPConn) {
 	defer conn.Close()
+	defer util.Recover(d.log, func(interface{}) {
+		CountPanic(d.CanonicalName())
+	})
 	conn.SetKeepAlive(true)
 	conn.SetKeepAlivePeriod(time.Second)
 	reader := bufio.NewReader(conn)
@@ -103,24 +168,38 @@ func (d *Diamond) readDiamondMetrics(conn *net.TCPConn) {
 			break
 		}
 		d.log.Debug("Read: ", string(line))
-		d.incoming <- line
+		select {
+		case d.incoming <- line:
+		case <-d.StopChannel():
+			d.log.Info("Collector stopped, closing connection: ", conn.RemoteAddr())
+			return
+		}
 	}
 	d.log.Info("Connection closed: ", conn.RemoteAddr())
 }
 
 // Collect reads metrics collected from Diamond collectors, converts
 // them to fullerite's Metric type and publishes them to handlers.
-func (d *Diamond) Collect() {
+func (d *Diamond) Collect(ctx context.Context) {
 	if !d.serverStarted {
 		d.serverStarted = true
 		go d.collectDiamond()
 	}
 
-	for line := range d.incoming {
-		if metrics, ok := d.parseMetrics(line); ok {
-			for _, metric := range metrics {
-				d.Channel() <- metric
+	for {
+		select {
+		case line := <-d.incoming:
+			if metrics, ok := d.parseMetrics(line); ok {
+				for _, metric := range metrics {
+					select {
+					case d.Channel() <- metric:
+					case <-d.StopChannel():
+						return
+					}
+				}
 			}
+		case <-d.StopChannel():
+			return
 		}
 	}
 }
@@ -1,3 +1,4 @@
+//go:build !race
 // +build !race
 
 package collector
@@ -6,6 +7,7 @@ import (
 	"fullerite/metric"
 	"fullerite/test_utils"
 
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -43,7 +45,7 @@ func TestDiamondConfigure(t *testing.T) {
 }
 
 // The test contains a data race. the method `connectToDiamondCollector()` reads the port inside *Diamond
-// while this is written by the `go d.Collect()` routine
+// while this is written by the `go d.Collect(context.Background())` routine
 func TestDiamondCollect(t *testing.T) {
 	config := make(map[string]interface{})
 	config["port"] = "0"
@@ -55,7 +57,7 @@ func TestDiamondCollect(t *testing.T) {
 	d.Configure(config)
 
 	// start collecting Diamond metrics
-	go d.Collect()
+	go d.Collect(context.Background())
 
 	conn, err := connectToDiamondCollector(d)
 	require.Nil(t, err, "should connect")
@@ -71,6 +73,38 @@ func TestDiamondCollect(t *testing.T) {
 	}
 }
 
+func TestDiamondStop(t *testing.T) {
+	config := make(map[string]interface{})
+	config["port"] = "0"
+
+	testChannel := make(chan metric.Metric)
+	testLog := test_utils.BuildLogger()
+
+	d := newDiamond(testChannel, 123, testLog).(*Diamond)
+	d.Configure(config)
+
+	done := make(chan bool)
+	go func() {
+		d.Collect(context.Background())
+		done <- true
+	}()
+
+	conn, err := connectToDiamondCollector(d)
+	require.Nil(t, err, "should connect")
+	require.NotNil(t, conn, "should connect")
+	defer conn.Close()
+
+	d.Stop()
+	select {
+	case <-done:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Collect should return once the collector is stopped")
+	}
+
+	_, err = net.DialTimeout("tcp", "localhost:"+d.Port(), time.Second)
+	assert.NotNil(t, err, "diamond socket should be closed")
+}
+
 func TestParseJsonToMetric(t *testing.T) {
 	rawData := []byte(`
 [{
@@ -128,3 +162,23 @@ func emitTestMetric(conn net.Conn) {
 	fmt.Fprintf(conn, string(b)+"\n")
 	fmt.Fprintf(conn, string(b)+"\n")
 }
+
+func TestParseJsonToMetricWithTimestamp(t *testing.T) {
+	rawData := []byte(`
+[{
+   "name": "foobar",
+   "type":  "GAUGE",
+   "value": 100.0,
+   "timestamp": 1476748800,
+   "dimensions": {
+      "host": "windrunner"
+   }
+}]
+        `)
+	d := newDiamond(nil, 12, nil).(*Diamond)
+	metrics, ok := d.parseMetrics(rawData)
+	assert.True(t, ok)
+	for _, metric := range metrics {
+		assert.Equal(t, int64(1476748800), metric.Timestamp.Unix())
+	}
+}
@@ -3,7 +3,9 @@ package collector
 import (
 	"fullerite/config"
 	"fullerite/metric"
-	"reflect"
+
+	"context"
+	"fmt"
 	"regexp"
 	"strings"
 	"sync"
@@ -72,53 +74,89 @@ func (d *DockerStats) GetEndpoint() string {
 	return d.endpoint
 }
 
+var dockerStatsOptions = config.Options{
+	{Name: "dockerStatsTimeout", Type: config.IntOption,
+		Description: "seconds to wait for the stats of a container, the interval at most and by default"},
+	{Name: "dockerEndPoint", Type: config.StringOption, Default: endpoint,
+		Description: "address of the Docker remote API"},
+	{Name: "emit_image_name", Type: config.BoolOption, Default: false,
+		Description: "add the image name as a dimension"},
+	{Name: "generatedDimensions", Type: config.MapOption,
+		Description: "dimensions extracted from environment variables, as {dimension: {variable: regex}}"},
+	{Name: "skipContainerRegex", Type: config.StringOption,
+		Description: "the containers whose name matches are not collected"},
+}
+
 // Configure takes a dictionary of values with which the handler can configure itself.
-func (d *DockerStats) Configure(configMap map[string]interface{}) {
-	if timeout, exists := configMap["dockerStatsTimeout"]; exists {
-		d.statsTimeout = min(config.GetAsInt(timeout, d.interval), d.interval)
+func (d *DockerStats) Configure(configMap map[string]interface{}) error {
+	values, err := dockerStatsOptions.Decode(configMap)
+	errs := []error{err}
+
+	if values.Has("dockerStatsTimeout") {
+		d.statsTimeout = min(values.Int("dockerStatsTimeout"), d.interval)
 	} else {
 		d.statsTimeout = d.interval
 	}
-	if dockerEndpoint, exists := configMap["dockerEndPoint"]; exists {
-		if str, ok := dockerEndpoint.(string); ok {
-			d.endpoint = str
-		} else {
-			d.log.Warn("Failed to cast dokerEndPoint: ", reflect.TypeOf(dockerEndpoint))
-		}
-	} else {
-		d.endpoint = endpoint
-	}
-	if emitImageName, exists := configMap["emit_image_name"]; exists {
-		if boolean, ok := emitImageName.(bool); ok {
-			d.emitImageName = boolean
-		} else {
-			d.log.Warn("Failed to cast emit_image_name: ", reflect.TypeOf(emitImageName))
-		}
-	}
+	d.endpoint = values.String("dockerEndPoint")
+	d.emitImageName = values.Bool("emit_image_name")
 
 	d.dockerClient, _ = docker.NewClient(d.endpoint)
-	if generatedDimensions, exists := configMap["generatedDimensions"]; exists {
-		for dimension, generator := range generatedDimensions.(map[string]interface{}) {
-			for key, regx := range config.GetAsMap(generator) {
-				re, err := regexp.Compile(regx)
-				if err != nil {
-					d.log.Warn("Failed to compile regex: ", regx, err)
-				} else {
-					d.compiledRegex[dimension] = &Regex{regex: re, tag: key}
-				}
+	for dimension, generator := range values.Map("generatedDimensions") {
+		for key, regx := range config.GetAsMap(generator) {
+			re, err := regexp.Compile(regx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("generatedDimensions %s: %s", dimension, err))
+			} else {
+				d.compiledRegex[dimension] = &Regex{regex: re, tag: key}
 			}
 		}
 	}
-	d.configureCommonParams(configMap)
-	if skipRegex, skipExists := configMap["skipContainerRegex"]; skipExists {
-		d.skipRegex = regexp.MustCompile(skipRegex.(string))
+	errs = append(errs, d.configureCommonParams(configMap))
+	if values.Has("skipContainerRegex") {
+		re, err := regexp.Compile(values.String("skipContainerRegex"))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skipContainerRegex: %s", err))
+		} else {
+			d.skipRegex = re
+		}
 	}
+	return config.JoinErrors(errs...)
+}
+
+// Options : the keys understood by the DockerStats collector
+func (d *DockerStats) Options() config.Options {
+	return append(dockerStatsOptions, commonOptions...)
+}
+
+var dockerStatsMetrics = []MetricDescription{
+	{Name: "DockerMemoryUsed", Type: metric.Gauge, Dimensions: []string{"container_id", "container_name", "<generated dimension>"},
+		Description: "memory used by the container in bytes, with emit_image_name the image_name dimension replaces container_id and container_name"},
+	{Name: "DockerMemoryLimit", Type: metric.Gauge, Dimensions: []string{"container_id", "container_name", "<generated dimension>"},
+		Description: "memory limit of the container in bytes"},
+	{Name: "DockerCpuPercentage", Type: metric.Gauge, Dimensions: []string{"container_id", "container_name", "<generated dimension>"},
+		Description: "CPU used by the container since the previous collection, in percent of one core"},
+	{Name: "DockerCpuThrottledPeriods", Type: metric.CumulativeCounter, Dimensions: []string{"container_id", "container_name", "<generated dimension>"},
+		Description: "periods the container was throttled"},
+	{Name: "DockerCpuThrottledNanoseconds", Type: metric.CumulativeCounter, Dimensions: []string{"container_id", "container_name", "<generated dimension>"},
+		Description: "time the container was throttled"},
+	{Name: "DockerTxBytes", Type: metric.CumulativeCounter, Dimensions: []string{"container_id", "container_name", "iface", "<generated dimension>"},
+		Description: "bytes sent by the container per network interface"},
+	{Name: "DockerRxBytes", Type: metric.CumulativeCounter, Dimensions: []string{"container_id", "container_name", "iface", "<generated dimension>"},
+		Description: "bytes received by the container per network interface"},
+	{Name: "DockerContainerCount", Type: metric.Counter, Dimensions: []string{"<generated dimension>"},
+		Description: "1 for every running container"},
+}
+
+// Metrics : the metrics emitted by the DockerStats collector
+func (d *DockerStats) Metrics() []MetricDescription {
+	return append(dockerStatsMetrics, commonMetrics...)
 }
 
 // Collect iterates on all the docker containers alive and, if possible, collects the correspondent
 // memory and cpu statistics.
-// For each container a gorutine is started to spin up the collection process.
-func (d *DockerStats) Collect() {
+// For each container a gorutine is started to spin up the collection process,
+// the containers left are skipped and the pending stats abandoned once ctx is done.
+func (d *DockerStats) Collect(ctx context.Context) {
 	if d.dockerClient == nil {
 		d.log.Error("Invalid endpoint: ", docker.ErrInvalidEndpoint)
 		return
@@ -128,7 +166,13 @@ func (d *DockerStats) Collect() {
 		d.log.Error("ListContainers() failed: ", err)
 		return
 	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
 	for _, apiContainer := range containers {
+		if ctx.Err() != nil {
+			d.log.Warn("Skipping the remaining containers: ", ctx.Err())
+			return
+		}
 		container, err := d.dockerClient.InspectContainer(apiContainer.ID)
 
 		if err != nil {
@@ -140,16 +184,22 @@ func (d *DockerStats) Collect() {
 			d.log.Info("Skip container: ", container.Name)
 			continue
 		}
+		d.mu.Lock()
 		if _, ok := d.previousCPUValues[container.ID]; !ok {
 			d.previousCPUValues[container.ID] = new(CPUValues)
 		}
-		go d.getDockerContainerInfo(container)
+		d.mu.Unlock()
+		wg.Add(1)
+		go func(container *docker.Container) {
+			defer wg.Done()
+			d.getDockerContainerInfo(ctx, container)
+		}(container)
 	}
 }
 
 // getDockerContainerInfo gets container statistics for the given container.
 // results is a channel to make possible the synchronization between the main process and the gorutines (wait-notify pattern).
-func (d *DockerStats) getDockerContainerInfo(container *docker.Container) {
+func (d *DockerStats) getDockerContainerInfo(ctx context.Context, container *docker.Container) {
 	errC := make(chan error, 1)
 	statsC := make(chan *docker.Stats, 1)
 	done := make(chan bool, 1)
@@ -174,6 +224,10 @@ func (d *DockerStats) getDockerContainerInfo(container *docker.Container) {
 		d.log.Error("Timed out collecting stats for container ", container.ID)
 		done <- true
 		break
+	case <-ctx.Done():
+		d.log.Error("Gave up collecting stats for container ", container.ID, ": ", ctx.Err())
+		done <- true
+		break
 	}
 }
 
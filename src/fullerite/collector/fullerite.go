@@ -3,6 +3,7 @@ package collector
 import (
 	"fullerite/metric"
 
+	"context"
 	"runtime"
 
 	l "github.com/Sirupsen/logrus"
@@ -34,12 +35,26 @@ func newFullerite(channel chan metric.Metric, initialInterval int, log *l.Entry)
 }
 
 // Configure this takes a dictionary of values with which the handler can configure itself
-func (f *Fullerite) Configure(configMap map[string]interface{}) {
-	f.configureCommonParams(configMap)
+func (f *Fullerite) Configure(configMap map[string]interface{}) error {
+	return f.configureCommonParams(configMap)
+}
+
+var fulleriteMetrics = []MetricDescription{
+	{Name: "NumGoroutine", Type: metric.Counter,
+		Description: "goroutines running in fullerite"},
+	{Name: "<memory stat>", Type: metric.Gauge,
+		Description: "the Go runtime memory statistics: Alloc, Sys, HeapAlloc, HeapSys, HeapIdle, HeapInuse, HeapReleased, HeapObjects, StackInuse, StackSys, MSpanInuse, MSpanSys, MCacheInuse, MCacheSys, BuckHashSys, GCSys, OtherSys, NextGC and LastGC"},
+	{Name: "<allocation stat>", Type: metric.Counter,
+		Description: "the Go runtime allocation and GC statistics: TotalAlloc, Lookups, Mallocs, Frees, PauseTotalNs and NumGC"},
+}
+
+// Metrics : the metrics emitted by the Fullerite collector
+func (f *Fullerite) Metrics() []MetricDescription {
+	return append(fulleriteMetrics, commonMetrics...)
 }
 
 // Collect produces some random test metrics.
-func (f Fullerite) Collect() {
+func (f Fullerite) Collect(ctx context.Context) {
 	for _, m := range f.getGoMetrics() {
 		f.Channel() <- m
 	}
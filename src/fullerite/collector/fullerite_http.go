@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"fullerite/config"
 	"fullerite/internalserver"
 	"fullerite/metric"
 
@@ -51,12 +52,35 @@ func newFulleriteHTTP(channel chan metric.Metric, initialInterval int, log *l.En
 	return inst
 }
 
-func (inst *fulleriteHTTP) Configure(configMap map[string]interface{}) {
-	if endpoint, exists := configMap["endpoint"]; exists {
-		inst.endpoint = endpoint.(string)
+var fulleriteHTTPOptions = config.Options{
+	{Name: "endpoint", Type: config.StringOption,
+		Description: "URL of the internal server of the fullerite to collect from"},
+}
+
+func (inst *fulleriteHTTP) Configure(configMap map[string]interface{}) error {
+	values, err := fulleriteHTTPOptions.Decode(configMap)
+	if values.Has("endpoint") {
+		inst.endpoint = values.String("endpoint")
 	}
 
-	inst.configureCommonParams(configMap)
+	return config.JoinErrors(err, inst.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the FulleriteHTTP collector
+func (inst *fulleriteHTTP) Options() config.Options {
+	return append(fulleriteHTTPOptions, commonOptions...)
+}
+
+var fulleriteHTTPMetrics = []MetricDescription{
+	{Name: "<metric>", Type: metric.CumulativeCounter, Dimensions: []string{"handler", "collector"},
+		Description: "counters of the memory, the handlers and the collectors of the remote fullerite"},
+	{Name: "<metric>", Type: metric.Gauge, Dimensions: []string{"handler", "collector"},
+		Description: "gauges of the memory, the handlers and the collectors of the remote fullerite"},
+}
+
+// Metrics : the metrics emitted by the fulleriteHTTP collector
+func (inst *fulleriteHTTP) Metrics() []MetricDescription {
+	return append(fulleriteHTTPMetrics, commonMetrics...)
 }
 
 func (inst fulleriteHTTP) handleError(err error) {
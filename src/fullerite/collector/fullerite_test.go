@@ -4,6 +4,7 @@ import (
 	"fullerite/metric"
 	"fullerite/test_utils"
 
+	"context"
 	"testing"
 	"time"
 
@@ -46,7 +47,7 @@ func TestFulleriteCollect(t *testing.T) {
 	f := newFullerite(testChannel, 123, testLog)
 	f.Configure(config)
 
-	go f.Collect()
+	go f.Collect(context.Background())
 
 	select {
 	case <-f.Channel():
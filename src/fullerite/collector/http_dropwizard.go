@@ -5,7 +5,9 @@ import (
 	"fullerite/dropwizard"
 	"fullerite/metric"
 
+	"context"
 	"fmt"
+	"sync"
 
 	l "github.com/Sirupsen/logrus"
 )
@@ -46,42 +48,73 @@ func newHTTPDropwizard(channel chan metric.Metric, initialInterval int, log *l.E
 	return col
 }
 
-func (h *httpDropwizardCollector) Configure(configMap map[string]interface{}) {
-	if val, exists := configMap["endpoints"]; exists {
-		val := val.([]interface{})
-		h.endpoints = make([]ServiceEndpoint, len(val))
-		index := 0
-		for _, e := range val {
+var httpDropwizardOptions = config.Options{
+	{Name: "endpoints", Type: config.ListOption,
+		Description: `services to query, as {"service_name": ..., "port": ..., "path": ...}`},
+	{Name: "http_timeout", Type: config.IntOption, Default: 3,
+		Description: "seconds to wait for a service to answer"},
+}
+
+func (h *httpDropwizardCollector) Configure(configMap map[string]interface{}) error {
+	values, err := httpDropwizardOptions.Decode(configMap)
+	errs := []error{err}
+
+	if values.Has("endpoints") {
+		h.endpoints = []ServiceEndpoint{}
+		for i, e := range values.List("endpoints") {
+			if !config.HasType(e, config.StringMapOption) {
+				errs = append(errs, fmt.Errorf("endpoint %d should map service_name, port and path to strings, got %v", i, e))
+				continue
+			}
 			endpoint := config.GetAsMap(e)
-			h.endpoints[index] = ServiceEndpoint{
+			h.endpoints = append(h.endpoints, ServiceEndpoint{
 				Name: endpoint["service_name"],
 				Port: endpoint["port"],
 				Path: endpoint["path"],
-			}
-			index++
+			})
 		}
 	}
 
-	if val, exists := configMap["http_timeout"]; exists {
-		h.timeout = config.GetAsInt(val, 2)
-	}
+	h.timeout = values.Int("http_timeout")
+
+	errs = append(errs, h.configureCommonParams(configMap))
+	return config.JoinErrors(errs...)
+}
+
+// Options : the keys understood by the HttpDropwizard collector
+func (h *httpDropwizardCollector) Options() config.Options {
+	return append(httpDropwizardOptions, commonOptions...)
+}
+
+var httpDropwizardMetrics = []MetricDescription{
+	{Name: "<metric>", Type: "<type>", Dimensions: []string{"service", "port", "<dimension>"},
+		Description: "the dropwizard metrics of every endpoint"},
+}
 
-	h.configureCommonParams(configMap)
+// Metrics : the metrics emitted by the HttpDropwizard collector
+func (h *httpDropwizardCollector) Metrics() []MetricDescription {
+	return append(httpDropwizardMetrics, commonMetrics...)
 }
 
-func (h *httpDropwizardCollector) Collect() {
+func (h *httpDropwizardCollector) Collect(ctx context.Context) {
+	var wg sync.WaitGroup
 	for _, endpoint := range h.endpoints {
-		go h.queryService(endpoint)
+		wg.Add(1)
+		go func(endpoint ServiceEndpoint) {
+			defer wg.Done()
+			h.queryService(ctx, endpoint)
+		}(endpoint)
 	}
+	wg.Wait()
 }
 
-func (h *httpDropwizardCollector) queryService(s ServiceEndpoint) {
+func (h *httpDropwizardCollector) queryService(ctx context.Context, s ServiceEndpoint) {
 	serviceLog := h.log.WithField("service", s.Name)
 
 	endpoint := fmt.Sprintf("http://localhost:%s/%s", s.Port, s.Path)
 	serviceLog.Debug("making GET request to ", endpoint)
 
-	rawResponse, schemaVer, err := queryEndpoint(endpoint, h.timeout)
+	rawResponse, schemaVer, err := queryEndpoint(ctx, endpoint, h.timeout)
 	if err != nil {
 		serviceLog.Warn("Failed to query endpoint ", endpoint, ": ", err)
 		return
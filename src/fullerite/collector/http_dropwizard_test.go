@@ -43,3 +43,21 @@ func TestConfigHttpDropwizard(t *testing.T) {
 	assert.Equal(t, "3400", inst.endpoints[0].Port)
 	assert.Equal(t, "path0/path1", inst.endpoints[0].Path)
 }
+
+func TestConfigHttpDropwizardInvalidEndpoints(t *testing.T) {
+	inst := getTestHTTPDropwizard()
+	err := inst.Configure(map[string]interface{}{"endpoints": "localhost:8080"})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, inst.endpoints)
+
+	err = inst.Configure(map[string]interface{}{
+		"endpoints": []interface{}{
+			map[string]interface{}{"service_name": "test_name", "port": 3400},
+		},
+	})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "endpoint 0")
+	assert.Equal(t, 0, len(inst.endpoints))
+}
@@ -1,7 +1,9 @@
 package collector
 
 import (
+	"context"
 	"fullerite/metric"
+	"fullerite/util"
 	"time"
 
 	"net/http"
@@ -20,10 +22,10 @@ type baseHTTPCollector struct {
 }
 
 // Collect first queries the config'd endpoint and then passes the results to the handler functions
-func (base baseHTTPCollector) Collect() {
+func (base baseHTTPCollector) Collect(ctx context.Context) {
 	base.log.Info("Starting to collect metrics from ", base.endpoint)
 
-	metrics := base.makeRequest()
+	metrics := base.makeRequest(ctx)
 	if metrics != nil {
 		for _, m := range metrics {
 			base.Channel() <- m
@@ -36,7 +38,7 @@ func (base baseHTTPCollector) Collect() {
 }
 
 // makeRequest is what is responsible for actually doing the HTTP GET
-func (base baseHTTPCollector) makeRequest() []metric.Metric {
+func (base baseHTTPCollector) makeRequest(ctx context.Context) []metric.Metric {
 	if base.endpoint == "" {
 		base.log.Warn("Ignoring attempt to make request because no endpoint provided")
 		return []metric.Metric{}
@@ -46,7 +48,7 @@ func (base baseHTTPCollector) makeRequest() []metric.Metric {
 		Timeout: time.Duration(2) * time.Second,
 	}
 
-	rsp, err := client.Get(base.endpoint)
+	rsp, err := util.HTTPGet(ctx, &client, base.endpoint)
 	if err != nil {
 		base.errHandler(err)
 		return nil
@@ -3,11 +3,13 @@ package collector
 import (
 	"fullerite/metric"
 
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	l "github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -52,7 +54,7 @@ func TestMisConfig(t *testing.T) {
 		return nil
 	}
 
-	go col.Collect()
+	go col.Collect(context.Background())
 }
 
 func TestWorkingGenericHTTP(t *testing.T) {
@@ -75,9 +77,39 @@ func TestWorkingGenericHTTP(t *testing.T) {
 		return []metric.Metric{metric.New("junk")}
 	}
 
-	go col.Collect()
+	go col.Collect(context.Background())
 	m := <-col.Channel()
 
 	assert.NotNil(t, m, "should have produced a single metric")
 	assert.True(t, ensureEmpty(col.Channel()), "There should have only been a single metric")
 }
+
+func TestGenericHTTPGivesUpWhenCancelled(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, rsp *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	col := buildBaseHTTPCollector(server.URL)
+	errs := make(chan error, 1)
+	col.errHandler = func(err error) {
+		errs <- err
+	}
+	col.rspHandler = func(rsp *http.Response) []metric.Metric {
+		t.Error("the request should have been cancelled")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go col.Collect(ctx)
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "context deadline exceeded")
+	case <-time.After(time.Second):
+		t.Fatal("the request should have been abandoned at the deadline")
+	}
+}
@@ -4,6 +4,7 @@ package collector
 //  leader and sends all well-formated metrics
 
 import (
+	"context"
 	"fmt"
 	"fullerite/config"
 	"fullerite/dropwizard"
@@ -56,23 +57,44 @@ func newMarathonStats(channel chan metric.Metric, initialInterval int, log *l.En
 	return m
 }
 
+var marathonStatsOptions = config.Options{
+	{Name: "marathonHost", Type: config.StringOption, Required: true,
+		Description: "host and port of the Marathon API"},
+}
+
 // Configure just calls the default configure
-func (m *MarathonStats) Configure(configMap map[string]interface{}) {
-	m.configureCommonParams(configMap)
+func (m *MarathonStats) Configure(configMap map[string]interface{}) error {
+	commonErr := m.configureCommonParams(configMap)
 
-	c := config.GetAsMap(configMap)
-	if marathonHost, exists := c["marathonHost"]; exists && len(marathonHost) > 0 {
+	values, err := marathonStatsOptions.Decode(configMap)
+	if marathonHost := values.String("marathonHost"); len(marathonHost) > 0 {
 		m.marathonHost = marathonHost
-	} else {
-		m.log.Error("Marathon host not specified in config")
+	} else if err == nil {
+		err = fmt.Errorf("marathonHost is empty")
 	}
+	return config.JoinErrors(commonErr, err)
+}
+
+// Options : the keys understood by the MarathonStats collector
+func (m *MarathonStats) Options() config.Options {
+	return append(marathonStatsOptions, commonOptions...)
+}
+
+var marathonMetrics = []MetricDescription{
+	{Name: "<metric>", Type: "<type>", Dimensions: []string{"service", "<dimension>"},
+		Description: "the dropwizard metrics of marathon, only sent by the leader"},
+}
+
+// Metrics : the metrics emitted by the MarathonStats collector
+func (m *MarathonStats) Metrics() []MetricDescription {
+	return append(marathonMetrics, commonMetrics...)
 }
 
 // Collect compares the leader against this hosts's hostaname and sends metrics if this is the leader
-func (m *MarathonStats) Collect() {
+func (m *MarathonStats) Collect(ctx context.Context) {
 	// Non-marathon-leaders forward requests to the leader, so only the leader's metrics matter
-	if leader, err := util.IsLeader(m.marathonHost, "v2/leader", m.client); leader && err == nil {
-		go sendMarathonMetrics(m)
+	if leader, err := util.IsLeader(ctx, m.marathonHost, "v2/leader", m.client); leader && err == nil {
+		sendMarathonMetrics(m, ctx)
 	} else if err != nil {
 		m.log.Error("Error finding leader: ", err)
 	} else {
@@ -80,17 +102,17 @@ func (m *MarathonStats) Collect() {
 	}
 }
 
-func (m *MarathonStats) sendMarathonMetrics() {
-	metrics := getMarathonMetrics(m)
+func (m *MarathonStats) sendMarathonMetrics(ctx context.Context) {
+	metrics := getMarathonMetrics(m, ctx)
 	for _, metric := range metrics {
 		m.Channel() <- metric
 	}
 }
 
-func (m *MarathonStats) getMarathonMetrics() []metric.Metric {
+func (m *MarathonStats) getMarathonMetrics(ctx context.Context) []metric.Metric {
 	url := getMarathonMetricsURL(m.marathonHost)
 
-	contents, err := util.MarathonGet(url, m.client)
+	contents, err := util.MarathonGet(ctx, url, m.client)
 	if err != nil {
 		m.log.Error("Could not load metrics from marathon: ", err.Error())
 		return nil
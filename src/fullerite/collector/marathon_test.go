@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -90,7 +91,7 @@ func TestMarathonStatsGetMarathonMetrics(t *testing.T) {
 		getMarathonMetricsURL = func(ip string) string { return ts.URL }
 
 		sut := newMarathonStats(nil, 10, defaultLog).(*MarathonStats)
-		actual := getMarathonMetrics(sut)
+		actual := getMarathonMetrics(sut, context.Background())
 
 		if test.err {
 			assert.True(t, actual == nil, test.msg)
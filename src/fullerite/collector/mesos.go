@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fullerite/config"
@@ -100,22 +101,44 @@ func newMesosStats(channel chan metric.Metric, intialInterval int, log *l.Entry)
 	return m
 }
 
+var mesosStatsOptions = config.Options{
+	{Name: "mesosNodes", Type: config.StringOption, Required: true,
+		Description: "comma separated URLs of the Mesos masters"},
+}
+
 // Configure Override *baseCollector.Configure(). Will create the required MesosLeaderElect instance.
-func (m *MesosStats) Configure(configMap map[string]interface{}) {
-	m.configureCommonParams(configMap)
+func (m *MesosStats) Configure(configMap map[string]interface{}) error {
+	commonErr := m.configureCommonParams(configMap)
 
-	c := config.GetAsMap(configMap)
-	if mesosNodes, exists := c["mesosNodes"]; exists && len(mesosNodes) > 0 {
+	values, err := mesosStatsOptions.Decode(configMap)
+	if mesosNodes := values.String("mesosNodes"); len(mesosNodes) > 0 {
 		m.mesosCache = newMLE()
 		m.mesosCache.Configure(mesosNodes, cacheTimeout)
-	} else {
-		m.log.Error("Require configuration not found: mesosNodes")
-		return
+	} else if err == nil {
+		err = fmt.Errorf("mesosNodes is empty")
 	}
+	return config.JoinErrors(commonErr, err)
+}
+
+// Options : the keys understood by the MesosStats collector
+func (m *MesosStats) Options() config.Options {
+	return append(mesosStatsOptions, commonOptions...)
+}
+
+var mesosMetrics = []MetricDescription{
+	{Name: "mesos.<metric>", Type: metric.Gauge,
+		Description: "the metrics snapshot of the leading mesos master"},
+	{Name: "mesos.<counter>", Type: metric.CumulativeCounter,
+		Description: "the counters of the metrics snapshot of the leading mesos master"},
+}
+
+// Metrics : the metrics emitted by the MesosStats collector
+func (m *MesosStats) Metrics() []MetricDescription {
+	return append(mesosMetrics, commonMetrics...)
 }
 
 // Collect Compares box IP against leader IP and if true, sends data.
-func (m *MesosStats) Collect() {
+func (m *MesosStats) Collect(ctx context.Context) {
 	if m.mesosCache == nil {
 		m.log.Error("No mesosCache, Configure() probably failed.")
 		return
@@ -124,21 +147,21 @@ func (m *MesosStats) Collect() {
 		return
 	}
 
-	go sendMetrics(m)
+	sendMetrics(m, ctx)
 }
 
 // sendMetrics Send to baseCollector channel.
-func (m *MesosStats) sendMetrics() {
-	for k, v := range getMetrics(m, m.IP) {
+func (m *MesosStats) sendMetrics(ctx context.Context) {
+	for k, v := range getMetrics(m, ctx, m.IP) {
 		s := buildMetric(k, v)
 		m.Channel() <- s
 	}
 }
 
 // getMetrics Get metrics from the :5050/metrics/snapshot mesos endpoint.
-func (m *MesosStats) getMetrics(ip string) map[string]float64 {
+func (m *MesosStats) getMetrics(ctx context.Context, ip string) map[string]float64 {
 	url := getMetricsURL(ip)
-	r, err := m.client.Get(url)
+	r, err := util.HTTPGet(ctx, &m.client, url)
 
 	if err != nil {
 		m.log.Error("Could not load metrics from mesos", err.Error())
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fullerite/config"
@@ -74,32 +75,52 @@ func newMesosSlaveStats(channel chan metric.Metric, intialInterval int, log *l.E
 	return m
 }
 
+var mesosSlaveStatsOptions = config.Options{
+	{Name: "httpTimeout", Type: config.IntOption, Default: httpDefaultTimeout,
+		Description: "seconds to wait for the slave to answer"},
+	{Name: "slaveSnapshotPort", Type: config.IntOption, Default: mesosDefaultSlaveSnapshotPort,
+		Description: "port of the metrics snapshot endpoint of the slave"},
+}
+
 // Configure Override *baseCollector.Configure(). Will create the required MesosLeaderElect instance.
-func (m *MesosSlaveStats) Configure(configMap map[string]interface{}) {
-	m.configureCommonParams(configMap)
-	c := config.GetAsMap(configMap)
+func (m *MesosSlaveStats) Configure(configMap map[string]interface{}) error {
+	commonErr := m.configureCommonParams(configMap)
+	values, err := mesosSlaveStatsOptions.Decode(configMap)
 
-	if httpTimeout, exists := c["httpTimeout"]; exists {
-		m.client.Timeout = time.Duration(config.GetAsInt(httpTimeout, httpDefaultTimeout)) * time.Second
-	}
+	m.client.Timeout = time.Duration(values.Int("httpTimeout")) * time.Second
+	m.snapshotPort = values.Int("slaveSnapshotPort")
+	return config.JoinErrors(commonErr, err)
+}
 
-	if slaveSnapshotPort, exists := c["slaveSnapshotPort"]; exists {
-		m.snapshotPort = config.GetAsInt(slaveSnapshotPort, mesosDefaultSlaveSnapshotPort)
-	}
+// Options : the keys understood by the MesosSlaveStats collector
+func (m *MesosSlaveStats) Options() config.Options {
+	return append(mesosSlaveStatsOptions, commonOptions...)
+}
+
+var mesosSlaveMetrics = []MetricDescription{
+	{Name: "mesos.<metric>", Type: metric.Gauge,
+		Description: "the metrics snapshot of the mesos slave"},
+	{Name: "mesos.<counter>", Type: metric.CumulativeCounter,
+		Description: "the counters of the metrics snapshot of the mesos slave"},
+}
+
+// Metrics : the metrics emitted by the MesosSlaveStats collector
+func (m *MesosSlaveStats) Metrics() []MetricDescription {
+	return append(mesosSlaveMetrics, commonMetrics...)
 }
 
 // Collect Compares box IP against leader IP and if true, sends data.
-func (m *MesosSlaveStats) Collect() {
+func (m *MesosSlaveStats) Collect(ctx context.Context) {
 	if m.IP == "" {
 		m.log.Error("Cannot get external IP. Skipping collection.")
 		return
 	}
-	go m.sendMetrics()
+	m.sendMetrics(ctx)
 }
 
 // sendMetrics Send to baseCollector channel.
-func (m *MesosSlaveStats) sendMetrics() {
-	for metricName, value := range getSlaveMetrics(m, m.IP) {
+func (m *MesosSlaveStats) sendMetrics(ctx context.Context) {
+	for metricName, value := range getSlaveMetrics(m, ctx, m.IP) {
 		s := m.buildMetric(metricName, value)
 
 		m.Channel() <- s
@@ -107,9 +128,9 @@ func (m *MesosSlaveStats) sendMetrics() {
 }
 
 // getMetrics Get metrics from the :5051/metrics/snapshot mesos endpoint.
-func (m *MesosSlaveStats) getSlaveMetrics(ip string) map[string]float64 {
+func (m *MesosSlaveStats) getSlaveMetrics(ctx context.Context, ip string) map[string]float64 {
 	url := getSlaveMetricsURL(m, ip)
-	r, err := m.client.Get(url)
+	r, err := util.HTTPGet(ctx, &m.client, url)
 
 	if err != nil {
 		m.log.Error("Could not load metrics from mesos", err.Error())
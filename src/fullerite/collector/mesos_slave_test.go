@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -69,8 +70,8 @@ func TestMesosSlaveStatsSendMetrics(t *testing.T) {
 	oldGetMetrics := getSlaveMetrics
 	defer func() { getSlaveMetrics = oldGetMetrics }()
 
-	expected := metric.Metric{"mesos.test", "gauge", 0.1, map[string]string{}}
-	getSlaveMetrics = func(m *MesosSlaveStats, ip string) map[string]float64 {
+	expected := metric.Metric{Name: "mesos.test", MetricType: "gauge", Value: 0.1, Dimensions: map[string]string{}}
+	getSlaveMetrics = func(m *MesosSlaveStats, ctx context.Context, ip string) map[string]float64 {
 		return map[string]float64{
 			"test": 0.1,
 		}
@@ -79,7 +80,7 @@ func TestMesosSlaveStatsSendMetrics(t *testing.T) {
 	c := make(chan metric.Metric)
 	sut := newMesosSlaveStats(c, 10, defaultLog).(*MesosSlaveStats)
 
-	go sut.sendMetrics()
+	go sut.sendMetrics(context.Background())
 	actual := <-c
 
 	assert.Equal(t, expected, actual)
@@ -111,7 +112,7 @@ func TestMesosSlaveStatsGetMetrics(t *testing.T) {
 		getSlaveMetricsURL = func(m *MesosSlaveStats, ip string) string { return ts.URL }
 
 		sut := newMesosSlaveStats(nil, 10, defaultLog).(*MesosSlaveStats)
-		actual := sut.getSlaveMetrics(httptest.DefaultRemoteAddr)
+		actual := sut.getSlaveMetrics(context.Background(), httptest.DefaultRemoteAddr)
 
 		assert.Equal(t, expected, actual)
 	}
@@ -126,7 +127,7 @@ func TestMesosSlaveStatsGetMetricsHandleErrors(t *testing.T) {
 	getSlaveMetricsURL = func(m *MesosSlaveStats, ip string) string { return "" }
 
 	sut := newMesosSlaveStats(nil, 10, defaultLog).(*MesosSlaveStats)
-	actual := sut.getSlaveMetrics(httptest.DefaultRemoteAddr)
+	actual := sut.getSlaveMetrics(context.Background(), httptest.DefaultRemoteAddr)
 
 	assert.Nil(t, actual, "Empty (invalid) URL, which means http client should throw an error; therefore, we expect a nil from getMetrics")
 }
@@ -146,7 +147,7 @@ func TestMesosSlaveStatsGetMetricsHandleNon200s(t *testing.T) {
 	getSlaveMetricsURL = func(m *MesosSlaveStats, ip string) string { return ts.URL }
 
 	sut := newMesosSlaveStats(nil, 10, defaultLog).(*MesosSlaveStats)
-	actual := sut.getSlaveMetrics(httptest.DefaultRemoteAddr)
+	actual := sut.getSlaveMetrics(context.Background(), httptest.DefaultRemoteAddr)
 
 	assert.Nil(t, actual, "Server threw a 500, so we should expect nil from getMetrics")
 }
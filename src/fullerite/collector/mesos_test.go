@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -101,8 +102,8 @@ func TestMesosStatsCollect(t *testing.T) {
 	newMLE = func() util.MesosLeaderElectInterface { return &MockMLE{} }
 
 	sendMetricsCalled := false
-	c := make(chan bool)
-	sendMetrics = func(m *MesosStats) {
+	c := make(chan bool, 1)
+	sendMetrics = func(m *MesosStats, ctx context.Context) {
 		sendMetricsCalled = true
 		c <- true
 	}
@@ -125,7 +126,7 @@ func TestMesosStatsCollect(t *testing.T) {
 
 		sut := newMesosStats(nil, 0, defaultLog).(*MesosStats)
 		sut.Configure(configMap)
-		sut.Collect()
+		sut.Collect(context.Background())
 
 		switch test.isSendMetricsCalled {
 		case false:
@@ -141,8 +142,8 @@ func TestMesosStatsSendMetrics(t *testing.T) {
 	oldGetMetrics := getMetrics
 	defer func() { getMetrics = oldGetMetrics }()
 
-	expected := metric.Metric{"mesos.test", "gauge", 0.1, map[string]string{}}
-	getMetrics = func(m *MesosStats, ip string) map[string]float64 {
+	expected := metric.Metric{Name: "mesos.test", MetricType: "gauge", Value: 0.1, Dimensions: map[string]string{}}
+	getMetrics = func(m *MesosStats, ctx context.Context, ip string) map[string]float64 {
 		return map[string]float64{
 			"test": 0.1,
 		}
@@ -151,7 +152,7 @@ func TestMesosStatsSendMetrics(t *testing.T) {
 	c := make(chan metric.Metric)
 	sut := newMesosStats(c, 10, defaultLog).(*MesosStats)
 
-	go sut.sendMetrics()
+	go sut.sendMetrics(context.Background())
 	actual := <-c
 
 	assert.Equal(t, expected, actual)
@@ -183,7 +184,7 @@ func TestMesosStatsGetMetrics(t *testing.T) {
 		getMetricsURL = func(ip string) string { return ts.URL }
 
 		sut := newMesosStats(nil, 10, defaultLog).(*MesosStats)
-		actual := getMetrics(sut, httptest.DefaultRemoteAddr)
+		actual := getMetrics(sut, context.Background(), httptest.DefaultRemoteAddr)
 
 		assert.Equal(t, expected, actual)
 	}
@@ -198,7 +199,7 @@ func TestMesosStatsGetMetricsHandleErrors(t *testing.T) {
 	getMetricsURL = func(ip string) string { return "" }
 
 	sut := newMesosStats(nil, 10, defaultLog).(*MesosStats)
-	actual := getMetrics(sut, httptest.DefaultRemoteAddr)
+	actual := getMetrics(sut, context.Background(), httptest.DefaultRemoteAddr)
 
 	assert.Nil(t, actual, "Empty (invalid) URL, which means http client should throw an error; therefore, we expect a nil from getMetrics")
 }
@@ -218,13 +219,13 @@ func TestMesosStatsGetMetricsHandleNon200s(t *testing.T) {
 	getMetricsURL = func(ip string) string { return ts.URL }
 
 	sut := newMesosStats(nil, 10, defaultLog).(*MesosStats)
-	actual := getMetrics(sut, httptest.DefaultRemoteAddr)
+	actual := getMetrics(sut, context.Background(), httptest.DefaultRemoteAddr)
 
 	assert.Nil(t, actual, "Server threw a 500, so we should expect nil from getMetrics")
 }
 
 func TestMesosStatsBuildMetric(t *testing.T) {
-	expected := metric.Metric{"mesos.test", "gauge", 0.1, map[string]string{}}
+	expected := metric.Metric{Name: "mesos.test", MetricType: "gauge", Value: 0.1, Dimensions: map[string]string{}}
 
 	actual := buildMetric("test", 0.1)
 
@@ -232,7 +233,7 @@ func TestMesosStatsBuildMetric(t *testing.T) {
 }
 
 func TestMesosStatsBuildMetricCumCounter(t *testing.T) {
-	expected := metric.Metric{"mesos.master.slave_reregistrations", metric.CumulativeCounter, 0.1, map[string]string{}}
+	expected := metric.Metric{Name: "mesos.master.slave_reregistrations", MetricType: metric.CumulativeCounter, Value: 0.1, Dimensions: map[string]string{}}
 
 	actual := buildMetric("master.slave_reregistrations", 0.1)
 
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"fullerite/metric"
+
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// metricPattern matches a metric on its name and on the values of some of its
+// dimensions. It is configured either as a regular expression on the name,
+// or as {"metric": "^DockerMemory", "dimensions": {"image_name": "^busybox"}}
+type metricPattern struct {
+	name       *regexp.Regexp
+	dimensions map[string]*regexp.Regexp
+}
+
+func (p metricPattern) matches(m *metric.Metric) bool {
+	if p.name != nil && !p.name.MatchString(m.Name) {
+		return false
+	}
+	for key, pattern := range p.dimensions {
+		value, ok := m.GetDimensionValue(key)
+		if !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(m *metric.Metric, patterns []metricPattern) bool {
+	for _, p := range patterns {
+		if p.matches(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileMetricPatterns compiles a metrics_whitelist or metrics_blacklist,
+// the entries that don't compile are left out and reported in the error
+func compileMetricPatterns(value interface{}) ([]metricPattern, error) {
+	var entries []interface{}
+	switch realValue := value.(type) {
+	case string:
+		if err := json.Unmarshal([]byte(realValue), &entries); err != nil {
+			return nil, fmt.Errorf("expected a list of patterns, got %s", realValue)
+		}
+	case []string:
+		for _, entry := range realValue {
+			entries = append(entries, entry)
+		}
+	case []interface{}:
+		entries = realValue
+	default:
+		return nil, fmt.Errorf("expected a list of patterns, got %v", reflect.TypeOf(value))
+	}
+
+	patterns := []metricPattern{}
+	var err error
+	for _, entry := range entries {
+		p, entryErr := compileMetricPattern(entry)
+		if entryErr != nil {
+			err = entryErr
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, err
+}
+
+func compileMetricPattern(entry interface{}) (metricPattern, error) {
+	p := metricPattern{}
+	var err error
+
+	switch realEntry := entry.(type) {
+	case string:
+		p.name, err = regexp.Compile(realEntry)
+	case map[string]interface{}:
+		if name, exists := realEntry["metric"]; exists {
+			str, _ := name.(string)
+			if p.name, err = regexp.Compile(str); err != nil {
+				break
+			}
+		}
+		dimensions, _ := realEntry["dimensions"].(map[string]interface{})
+		p.dimensions = make(map[string]*regexp.Regexp, len(dimensions))
+		for key, value := range dimensions {
+			str, _ := value.(string)
+			if p.dimensions[key], err = regexp.Compile(str); err != nil {
+				break
+			}
+		}
+		if p.name == nil && len(p.dimensions) == 0 && err == nil {
+			err = fmt.Errorf("%v needs a metric or dimensions to match", entry)
+		}
+	default:
+		err = fmt.Errorf("%v is not a pattern", entry)
+	}
+
+	if err != nil {
+		return p, fmt.Errorf("invalid pattern %v: %s", entry, err)
+	}
+	return p, nil
+}
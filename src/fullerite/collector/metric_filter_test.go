@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fullerite/metric"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildMetricFilterTestMetric(name string, dimensions map[string]string) *metric.Metric {
+	m := metric.New(name)
+	m.AddDimensions(dimensions)
+	return &m
+}
+
+func TestMetricBlacklist(t *testing.T) {
+	c := New("Test")
+	c.Configure(map[string]interface{}{
+		"metrics_blacklist": []interface{}{
+			"^DockerNet",
+			map[string]interface{}{
+				"metric":     "^DockerMemory",
+				"dimensions": map[string]interface{}{"image_name": "^busybox"},
+			},
+			map[string]interface{}{
+				"dimensions": map[string]interface{}{"env": "^test$"},
+			},
+		},
+	})
+
+	assert.False(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerNetRxBytes", nil)))
+	assert.False(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerMemoryUsed", map[string]string{"image_name": "busybox:latest"})))
+	assert.True(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerMemoryUsed", map[string]string{"image_name": "nginx"})))
+	assert.True(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerMemoryUsed", nil)))
+	assert.False(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerCpuUser", map[string]string{"env": "test"})))
+	assert.True(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerCpuUser", map[string]string{"env": "prod"})))
+}
+
+func TestMetricWhitelist(t *testing.T) {
+	c := New("Test")
+	c.Configure(map[string]interface{}{
+		"metrics_whitelist": `["^DockerCpu", "^DockerMemory"]`,
+		"metrics_blacklist": []string{"Throttled"},
+	})
+
+	assert.True(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerCpuUser", nil)))
+	assert.False(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerCpuThrottledPeriods", nil)))
+	assert.False(t, c.MetricAllowed(buildMetricFilterTestMetric("DockerNetRxBytes", nil)))
+}
+
+func TestCompileMetricPatterns(t *testing.T) {
+	patterns, err := compileMetricPatterns([]interface{}{
+		"(",
+		map[string]interface{}{"metric": "valid"},
+		map[string]interface{}{},
+		map[string]interface{}{"dimensions": map[string]interface{}{"pid": "["}},
+		42.0,
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(patterns), "the valid patterns are kept")
+
+	_, err = compileMetricPatterns(42)
+	assert.NotNil(t, err)
+
+	c := New("Test")
+	assert.True(t, c.MetricAllowed(buildMetricFilterTestMetric("anything", nil)), "everything is allowed by default")
+}
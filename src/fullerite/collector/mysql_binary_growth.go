@@ -2,11 +2,13 @@ package collector
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"strings"
 
+	"fullerite/config"
 	"fullerite/metric"
 	"fullerite/util"
 
@@ -63,16 +65,36 @@ func newMySQLBinlogGrowth(channel chan metric.Metric, initialInterval int, log *
 	return d
 }
 
+var mySQLBinlogGrowthOptions = config.Options{
+	{Name: "mycnf", Type: config.StringOption, Default: defaultCnfPath,
+		Description: "MySQL option file with the credentials to connect with"},
+}
+
 // Configure takes a dictionary of values with which the handler can configure itself.
-func (m *MySQLBinlogGrowth) Configure(configMap map[string]interface{}) {
-	m.configureCommonParams(configMap)
-	if myCnfPath, exists := configMap["mycnf"]; exists {
-		m.myCnfPath = myCnfPath.(string)
-	}
+func (m *MySQLBinlogGrowth) Configure(configMap map[string]interface{}) error {
+	commonErr := m.configureCommonParams(configMap)
+	values, err := mySQLBinlogGrowthOptions.Decode(configMap)
+	m.myCnfPath = values.String("mycnf")
+	return config.JoinErrors(commonErr, err)
+}
+
+// Options : the keys understood by the MySQLBinlogGrowth collector
+func (m *MySQLBinlogGrowth) Options() config.Options {
+	return append(mySQLBinlogGrowthOptions, commonOptions...)
+}
+
+var mySQLBinlogGrowthMetrics = []MetricDescription{
+	{Name: "mysql.binlog_growth_rate", Type: metric.CumulativeCounter,
+		Description: "size of the binary logs in bytes"},
+}
+
+// Metrics : the metrics emitted by the MySQLBinlogGrowth collector
+func (m *MySQLBinlogGrowth) Metrics() []MetricDescription {
+	return append(mySQLBinlogGrowthMetrics, commonMetrics...)
 }
 
 // Collect emits the tota size of the mysql binary logs
-func (m *MySQLBinlogGrowth) Collect() {
+func (m *MySQLBinlogGrowth) Collect(ctx context.Context) {
 	// read the bin-log and datadir values from my.cnf
 	binLog, dataDir := getBinlogPath(m)
 	if binLog == "" || dataDir == "" {
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -64,7 +65,7 @@ func TestMySQLBinlogGrowthCollect(t *testing.T) {
 	defer func() { getBinlogPath = oldGetBinlogPath }()
 	getBinlogPath = func(m *MySQLBinlogGrowth) (string, string) { return "path/to/binlog", "/datadir" }
 
-	m.Collect()
+	m.Collect(context.Background())
 
 	select {
 	case res := <-m.Channel():
@@ -78,7 +79,7 @@ func TestMySQLBinlogGrowthCollectNoMyCnf(t *testing.T) {
 	m := newMockMySQLBinlogGrowth()
 	m.Configure(map[string]interface{}{"mycnf": "/non/existing/path"})
 
-	m.Collect()
+	m.Collect(context.Background())
 
 	select {
 	case <-m.Channel():
@@ -3,6 +3,7 @@ package collector
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"fullerite/config"
@@ -13,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	l "github.com/Sirupsen/logrus"
@@ -80,34 +82,55 @@ func newNerveHTTPD(channel chan metric.Metric, initialInterval int, log *l.Entry
 	return c
 }
 
+var nerveHTTPDOptions = config.Options{
+	{Name: "queryPath", Type: config.StringOption, Default: "server-status?auto",
+		Description: "path of the Apache status page"},
+	{Name: "configFilePath", Type: config.StringOption, Default: "/etc/nerve/nerve.conf.json",
+		Description: "nerve config listing the services to collect from"},
+	{Name: "host", Type: config.StringOption, Default: "localhost",
+		Description: "host the services are queried on"},
+	{Name: "status_ttl", Type: config.IntOption, Default: 3600,
+		Description: "seconds the status of a service is cached for"},
+	{Name: "servicesWhitelist", Type: config.StringListOption,
+		Description: "only collect from these services"},
+}
+
 // Configure the collector
-func (c *NerveHTTPD) Configure(configMap map[string]interface{}) {
-	if val, exists := configMap["queryPath"]; exists {
-		c.queryPath = val.(string)
-	}
+func (c *NerveHTTPD) Configure(configMap map[string]interface{}) error {
+	values, err := nerveHTTPDOptions.Decode(configMap)
 
-	if val, exists := configMap["configFilePath"]; exists {
-		c.configFilePath = val.(string)
-	}
+	c.queryPath = values.String("queryPath")
+	c.configFilePath = values.String("configFilePath")
+	c.host = values.String("host")
 
-	if val, exists := configMap["host"]; exists {
-		c.host = val.(string)
-	}
+	c.statusTTL = time.Duration(values.Int("status_ttl")) * time.Second
 
-	if val, exists := configMap["status_ttl"]; exists {
-		tmpStatusTTL := config.GetAsInt(val, 3600)
-		c.statusTTL = time.Duration(tmpStatusTTL) * time.Second
+	if values.Has("servicesWhitelist") {
+		c.servicesWhitelist = values.StringList("servicesWhitelist")
 	}
 
-	if val, exists := configMap["servicesWhitelist"]; exists {
-		c.servicesWhitelist = config.GetAsSlice(val)
-	}
+	return config.JoinErrors(err, c.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the NerveHTTPD collector
+func (c *NerveHTTPD) Options() config.Options {
+	return append(nerveHTTPDOptions, commonOptions...)
+}
+
+var nerveHTTPDMetrics = []MetricDescription{
+	{Name: "<status>", Type: metric.Gauge, Dimensions: []string{"service_name", "service_namespace", "port"},
+		Description: "the values of the Apache server-status, like ReqPerSec, BusyWorkers or CPULoad, and the workers per state of the scoreboard"},
+	{Name: "TotalAccesses", Type: metric.CumulativeCounter, Dimensions: []string{"service_name", "service_namespace", "port"},
+		Description: "requests served by the Apache server"},
+}
 
-	c.configureCommonParams(configMap)
+// Metrics : the metrics emitted by the NerveHTTPD collector
+func (c *NerveHTTPD) Metrics() []MetricDescription {
+	return append(nerveHTTPDMetrics, commonMetrics...)
 }
 
 // Collect the metrics
-func (c *NerveHTTPD) Collect() {
+func (c *NerveHTTPD) Collect(ctx context.Context) {
 	rawFileContents, err := ioutil.ReadFile(c.configFilePath)
 	if err != nil {
 		c.log.Warn("Failed to read the contents of file ", c.configFilePath, " because ", err)
@@ -120,11 +143,17 @@ func (c *NerveHTTPD) Collect() {
 	}
 	c.log.Debug("Finished parsing Nerve config into ", services)
 
+	var wg sync.WaitGroup
 	for _, service := range services {
 		if c.serviceInWhitelist(service) {
-			go c.emitHTTPDMetric(service, service.Port)
+			wg.Add(1)
+			go func(service util.NerveService) {
+				defer wg.Done()
+				c.emitHTTPDMetric(ctx, service, service.Port)
+			}(service)
 		}
 	}
+	wg.Wait()
 }
 
 func (c *NerveHTTPD) serviceInWhitelist(service util.NerveService) bool {
@@ -136,22 +165,22 @@ func (c *NerveHTTPD) serviceInWhitelist(service util.NerveService) bool {
 	return false
 }
 
-func (c *NerveHTTPD) emitHTTPDMetric(service util.NerveService, port int) {
-	metrics := getNerveHTTPDMetrics(c, service, port)
+func (c *NerveHTTPD) emitHTTPDMetric(ctx context.Context, service util.NerveService, port int) {
+	metrics := getNerveHTTPDMetrics(c, ctx, service, port)
 	for _, metric := range metrics {
 		c.Channel() <- metric
 	}
 	c.Channel() <- metric.Sentinel()
 }
 
-func (c *NerveHTTPD) getMetrics(service util.NerveService, port int) []metric.Metric {
+func (c *NerveHTTPD) getMetrics(ctx context.Context, service util.NerveService, port int) []metric.Metric {
 	results := []metric.Metric{}
 	serviceLog := c.log.WithField("service", service.Name)
 
 	endpoint := fmt.Sprintf("http://%s:%d/%s", c.host, port, c.queryPath)
 	serviceLog.Debug("making GET request to ", endpoint)
 
-	httpResponse := fetchApacheMetrics(endpoint, port)
+	httpResponse := fetchApacheMetrics(ctx, endpoint, port)
 
 	if httpResponse.status != 200 {
 		serviceLog.Warn("Failed to query endpoint ", endpoint, ": ", httpResponse.err)
@@ -236,13 +265,13 @@ func extractScoreBoardMetrics(key, value string) []metric.Metric {
 	return results
 }
 
-func fetchApacheMetrics(endpoint string, timeout int) *nerveHTTPDResponse {
+func fetchApacheMetrics(ctx context.Context, endpoint string, timeout int) *nerveHTTPDResponse {
 	response := new(nerveHTTPDResponse)
 	client := http.Client{
 		Timeout: time.Duration(timeout) * time.Second,
 	}
 
-	rsp, err := client.Get(endpoint)
+	rsp, err := util.HTTPGet(ctx, &client, endpoint)
 	response.err = err
 	if rsp != nil {
 		response.status = rsp.StatusCode
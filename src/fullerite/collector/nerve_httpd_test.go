@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"fullerite/metric"
@@ -88,7 +89,7 @@ func TestFetchApacheMetrics(t *testing.T) {
 	}))
 	defer ts.Close()
 	endpoint := ts.URL + "/server-status?auto=close"
-	httpResponse := fetchApacheMetrics(endpoint, 10)
+	httpResponse := fetchApacheMetrics(context.Background(), endpoint, 10)
 	assert.Equal(t, 404, httpResponse.status)
 }
 
@@ -100,7 +101,7 @@ func TestErrorFetchingMetrics(t *testing.T) {
 	endpoint := ts.URL + "/server-status?auto=close"
 	ts.Close()
 
-	httpResponse := fetchApacheMetrics(endpoint, 10)
+	httpResponse := fetchApacheMetrics(context.Background(), endpoint, 10)
 	assert.Equal(t, 0, httpResponse.status)
 }
 
@@ -133,7 +134,7 @@ func TestNerveHTTPDCollect(t *testing.T) {
 	inst := getNerveHTTPDCollector()
 	inst.Configure(cfg)
 
-	inst.Collect()
+	go inst.Collect(context.Background())
 	actual := []metric.Metric{}
 	for i := 0; i < 17; i++ {
 		actual = append(actual, <-inst.Channel())
@@ -191,7 +192,7 @@ func TestNerveHTTPDCollectWhiteList(t *testing.T) {
 	inst := getNerveHTTPDCollector()
 	inst.Configure(cfg)
 
-	inst.Collect()
+	go inst.Collect(context.Background())
 	actual := []metric.Metric{}
 	flag := true
 	for flag == true {
@@ -245,7 +246,7 @@ func TestNerveHTTPDCollectWithEmptyWhiteList(t *testing.T) {
 	inst := getNerveHTTPDCollector()
 	inst.Configure(cfg)
 
-	inst.Collect()
+	go inst.Collect(context.Background())
 	actual := []metric.Metric{}
 	flag := true
 	for flag == true {
@@ -290,7 +291,7 @@ func TestNerveHTTPDCollectWhiteListNotConfigured(t *testing.T) {
 	inst := getNerveHTTPDCollector()
 	inst.Configure(cfg)
 
-	inst.Collect()
+	go inst.Collect(context.Background())
 	actual := []metric.Metric{}
 	flag := true
 	for flag == true {
@@ -6,11 +6,13 @@ import (
 	"fullerite/metric"
 	"fullerite/util"
 
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	l "github.com/Sirupsen/logrus"
@@ -51,25 +53,46 @@ func newNerveUWSGI(channel chan metric.Metric, initialInterval int, log *l.Entry
 	return col
 }
 
-func (n *nerveUWSGICollector) Configure(configMap map[string]interface{}) {
-	if val, exists := configMap["queryPath"]; exists {
-		n.queryPath = val.(string)
-	}
-	if val, exists := configMap["configFilePath"]; exists {
-		n.configFilePath = val.(string)
-	}
-	if val, exists := configMap["servicesWhitelist"]; exists {
-		n.servicesWhitelist = config.GetAsSlice(val)
-	}
+var nerveUWSGIOptions = config.Options{
+	{Name: "queryPath", Type: config.StringOption, Default: "status/metrics",
+		Description: "path of the metrics endpoint of the services"},
+	{Name: "configFilePath", Type: config.StringOption, Default: "/etc/nerve/nerve.conf.json",
+		Description: "nerve config listing the services to collect from"},
+	{Name: "servicesWhitelist", Type: config.StringListOption,
+		Description: "only collect from these services"},
+	{Name: "http_timeout", Type: config.IntOption, Default: 2,
+		Description: "seconds to wait for a service to answer"},
+}
+
+func (n *nerveUWSGICollector) Configure(configMap map[string]interface{}) error {
+	values, err := nerveUWSGIOptions.Decode(configMap)
 
-	if val, exists := configMap["http_timeout"]; exists {
-		n.timeout = config.GetAsInt(val, 2)
+	n.queryPath = values.String("queryPath")
+	n.configFilePath = values.String("configFilePath")
+	if values.Has("servicesWhitelist") {
+		n.servicesWhitelist = values.StringList("servicesWhitelist")
 	}
+	n.timeout = values.Int("http_timeout")
+
+	return config.JoinErrors(err, n.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the NerveUWSGI collector
+func (n *nerveUWSGICollector) Options() config.Options {
+	return append(nerveUWSGIOptions, commonOptions...)
+}
+
+var nerveUWSGIMetrics = []MetricDescription{
+	{Name: "<metric>", Type: "<type>", Dimensions: []string{"service", "port", "<dimension>"},
+		Description: "the dropwizard metrics of every uWSGI service in nerve"},
+}
 
-	n.configureCommonParams(configMap)
+// Metrics : the metrics emitted by the NerveUWSGI collector
+func (n *nerveUWSGICollector) Metrics() []MetricDescription {
+	return append(nerveUWSGIMetrics, commonMetrics...)
 }
 
-func (n *nerveUWSGICollector) Collect() {
+func (n *nerveUWSGICollector) Collect(ctx context.Context) {
 	rawFileContents, err := ioutil.ReadFile(n.configFilePath)
 	if err != nil {
 		n.log.Warn("Failed to read the contents of file ", n.configFilePath, " because ", err)
@@ -83,18 +106,24 @@ func (n *nerveUWSGICollector) Collect() {
 	}
 	n.log.Debug("Finished parsing Nerve config into ", services)
 
+	var wg sync.WaitGroup
 	for _, service := range services {
-		go n.queryService(service.Name, service.Port)
+		wg.Add(1)
+		go func(service util.NerveService) {
+			defer wg.Done()
+			n.queryService(ctx, service.Name, service.Port)
+		}(service)
 	}
+	wg.Wait()
 }
 
-func (n *nerveUWSGICollector) queryService(serviceName string, port int) {
+func (n *nerveUWSGICollector) queryService(ctx context.Context, serviceName string, port int) {
 	serviceLog := n.log.WithField("service", serviceName)
 
 	endpoint := fmt.Sprintf("http://localhost:%d/%s", port, n.queryPath)
 	serviceLog.Debug("making GET request to ", endpoint)
 
-	rawResponse, schemaVer, err := queryEndpoint(endpoint, n.timeout)
+	rawResponse, schemaVer, err := queryEndpoint(ctx, endpoint, n.timeout)
 	if err != nil {
 		serviceLog.Warn("Failed to query endpoint ", endpoint, ": ", err)
 		return
@@ -115,12 +144,12 @@ func (n *nerveUWSGICollector) queryService(serviceName string, port int) {
 	}
 }
 
-func queryEndpoint(endpoint string, timeout int) ([]byte, string, error) {
+func queryEndpoint(ctx context.Context, endpoint string, timeout int) ([]byte, string, error) {
 	client := http.Client{
 		Timeout: time.Duration(timeout) * time.Second,
 	}
 
-	rsp, err := client.Get(endpoint)
+	rsp, err := util.HTTPGet(ctx, &client, endpoint)
 
 	if rsp != nil {
 		defer func() {
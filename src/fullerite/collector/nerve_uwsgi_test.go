@@ -5,6 +5,7 @@ import (
 	"fullerite/metric"
 	"fullerite/util"
 
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -365,7 +366,7 @@ func TestErrorQueryEndpointResponse(t *testing.T) {
 	endpoint := ts.URL + "/status/metrics"
 	ts.Close()
 
-	_, _, queryEndpointError := queryEndpoint(endpoint, 10)
+	_, _, queryEndpointError := queryEndpoint(context.Background(), endpoint, 10)
 	assert.NotNil(t, queryEndpointError)
 
 	//Socket closed test
@@ -374,7 +375,7 @@ func TestErrorQueryEndpointResponse(t *testing.T) {
 	}))
 	tsClosed.Close()
 	closedEndpoint := tsClosed.URL + "/status/metrics"
-	_, queryClosedEndpointResponse, queryClosedEndpointError := queryEndpoint(closedEndpoint, 10)
+	_, queryClosedEndpointResponse, queryClosedEndpointError := queryEndpoint(context.Background(), closedEndpoint, 10)
 	assert.NotNil(t, queryClosedEndpointError)
 	assert.Equal(t, "", queryClosedEndpointResponse)
 
@@ -410,7 +411,7 @@ func TestNerveUWSGICollect(t *testing.T) {
 	inst := getTestNerveUWSGI()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	for i := 0; i < 5; i++ {
@@ -454,7 +455,7 @@ func TestNerveUWSGICollectWithSchema(t *testing.T) {
 	inst := getTestNerveUWSGI()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	for i := 0; i < 5; i++ {
@@ -498,7 +499,7 @@ func TestNerveJavaCollectWithSchema(t *testing.T) {
 	inst := getTestNerveUWSGI()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	for i := 0; i < 8; i++ {
@@ -542,7 +543,7 @@ func TestNerveJavaCollectWithSchemaCumulativeCountersEnabled(t *testing.T) {
 	inst := getTestNerveUWSGI()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	flag := true
@@ -616,7 +617,7 @@ func TestNonConflictingServiceQueries(t *testing.T) {
 	inst := getTestNerveUWSGI()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	for i := 0; i < 5; i++ {
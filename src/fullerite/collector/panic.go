@@ -0,0 +1,23 @@
+package collector
+
+import "sync"
+
+// panics counts the recovered panics of every collector by canonical name
+var panics = struct {
+	sync.Mutex
+	counts map[string]uint64
+}{counts: make(map[string]uint64)}
+
+// CountPanic records a recovered panic of the collector
+func CountPanic(canonicalName string) {
+	panics.Lock()
+	defer panics.Unlock()
+	panics.counts[canonicalName]++
+}
+
+// Panics : how many panics of the collector were recovered
+func Panics(canonicalName string) uint64 {
+	panics.Lock()
+	defer panics.Unlock()
+	return panics.counts[canonicalName]
+}
@@ -4,6 +4,7 @@ import (
 	"fullerite/config"
 	"fullerite/metric"
 
+	"fmt"
 	"regexp"
 
 	l "github.com/Sirupsen/logrus"
@@ -47,32 +48,60 @@ func newProcStatus(channel chan metric.Metric, initialInterval int, log *l.Entry
 	return ps
 }
 
+var procStatusOptions = config.Options{
+	{Name: "pattern", Type: config.StringOption,
+		Description: "only the processes matching this regex are collected"},
+	{Name: "matchCommandLine", Type: config.BoolOption, Default: true,
+		Description: "match the pattern against the command line rather than the process name"},
+	{Name: "generatedDimensions", Type: config.StringMapOption,
+		Description: "dimensions extracted from the command line, as {dimension: regex}"},
+}
+
 // Configure this takes a dictionary of values with which the handler can configure itself
-func (ps *ProcStatus) Configure(configMap map[string]interface{}) {
-	if pattern, exists := configMap["pattern"]; exists {
-		re, err := regexp.Compile(pattern.(string))
+func (ps *ProcStatus) Configure(configMap map[string]interface{}) error {
+	values, err := procStatusOptions.Decode(configMap)
+	errs := []error{err}
+
+	if values.Has("pattern") {
+		re, err := regexp.Compile(values.String("pattern"))
 		if err != nil {
-			ps.log.Warn("Failed to compile regex: ", err)
+			errs = append(errs, fmt.Errorf("pattern: %s", err))
 		} else {
 			ps.pattern = re
 		}
 	}
 
-	if matchCommandLine, exists := configMap["matchCommandLine"]; exists {
-		ps.matchCommandLine = matchCommandLine.(bool)
-	}
+	ps.matchCommandLine = values.Bool("matchCommandLine")
 
-	if generatedDimensions, exists := configMap["generatedDimensions"]; exists {
-		for dimension, generator := range config.GetAsMap(generatedDimensions) {
-			//don't use MustCompile otherwise program will panic due to misformated regex
-			re, err := regexp.Compile(generator)
-			if err != nil {
-				ps.log.Warn("Failed to compile regex: ", generator, err)
-			} else {
-				ps.compiledRegex[dimension] = re
-			}
+	for dimension, generator := range values.StringMap("generatedDimensions") {
+		//don't use MustCompile otherwise program will panic due to misformated regex
+		re, err := regexp.Compile(generator)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("generatedDimensions %s: %s", dimension, err))
+		} else {
+			ps.compiledRegex[dimension] = re
 		}
 	}
 
-	ps.configureCommonParams(configMap)
+	errs = append(errs, ps.configureCommonParams(configMap))
+	return config.JoinErrors(errs...)
+}
+
+// Options : the keys understood by the ProcStatus collector
+func (ps *ProcStatus) Options() config.Options {
+	return append(procStatusOptions, commonOptions...)
+}
+
+var procStatusMetrics = []MetricDescription{
+	{Name: "VirtualMemory", Type: metric.Gauge, Dimensions: []string{"processName", "pid", "<generated dimension>"},
+		Description: "virtual memory of the process in bytes"},
+	{Name: "ResidentMemory", Type: metric.Gauge, Dimensions: []string{"processName", "pid", "<generated dimension>"},
+		Description: "resident memory of the process in bytes"},
+	{Name: "CPUTime", Type: metric.CumulativeCounter, Dimensions: []string{"processName", "pid", "<generated dimension>"},
+		Description: "CPU time of the process in seconds"},
+}
+
+// Metrics : the metrics emitted by the ProcStatus collector
+func (ps *ProcStatus) Metrics() []MetricDescription {
+	return append(procStatusMetrics, commonMetrics...)
 }
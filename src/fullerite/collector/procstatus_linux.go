@@ -1,3 +1,4 @@
+//go:build linux
 // +build linux
 
 package collector
@@ -5,6 +6,7 @@ package collector
 import (
 	"fullerite/metric"
 
+	"context"
 	"strconv"
 	"strings"
 
@@ -12,7 +14,7 @@ import (
 )
 
 // Collect produces some random test metrics.
-func (ps ProcStatus) Collect() {
+func (ps ProcStatus) Collect(ctx context.Context) {
 	for _, m := range ps.procStatusMetrics() {
 		ps.Channel() <- m
 	}
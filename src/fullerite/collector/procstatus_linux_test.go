@@ -1,3 +1,4 @@
+//go:build linux
 // +build linux
 
 package collector
@@ -6,6 +7,7 @@ import (
 	"fullerite/metric"
 	"fullerite/test_utils"
 
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -29,7 +31,7 @@ func TestProcStatusCollect(t *testing.T) {
 	ps := newProcStatus(channel, 12, testLog).(*ProcStatus)
 	ps.Configure(config)
 
-	go ps.Collect()
+	go ps.Collect(context.Background())
 
 	select {
 	case <-ps.Channel():
@@ -55,7 +57,7 @@ func TestProcStatusCollectMetricTypes(t *testing.T) {
 	ps := newProcStatus(channel, 12, testLog).(*ProcStatus)
 	ps.Configure(config)
 
-	go ps.Collect()
+	go ps.Collect(context.Background())
 
 	select {
 	case <-ps.Channel():
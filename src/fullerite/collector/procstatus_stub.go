@@ -2,8 +2,10 @@
 
 package collector
 
+import "context"
+
 // Collect metrics
-func (ps ProcStatus) Collect() {
+func (ps ProcStatus) Collect(ctx context.Context) {
 	// This does nothing. Procstatus is a linux-only collector and
 	// we don't need to have it on other platforms.
 }
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a collector runs: every interval from the moment it
+// starts, on the wall-clock multiples of the interval when aligned, or at the
+// times of a cron expression. Offset, picked at random up to the splay of the
+// collector, delays every collection so that hosts don't collect in lockstep.
+type Schedule struct {
+	Interval time.Duration
+	Offset   time.Duration
+	Align    bool
+	Cron     string
+
+	cron *cronSchedule
+}
+
+// Next returns the first collection strictly after now of a collector started at start
+func (s Schedule) Next(start, now time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.next(now.Add(-s.Offset)).Add(s.Offset)
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultCollectionInterval * time.Second
+	}
+	anchor := start.Add(s.Offset)
+	if s.Align {
+		anchor = time.Unix(0, 0).Add(s.Offset)
+	}
+	// the collections happen at anchor + k * interval
+	elapsed := now.Sub(anchor)
+	periods := elapsed / interval
+	if elapsed < 0 && elapsed%interval != 0 {
+		periods--
+	}
+	return anchor.Add((periods + 1) * interval)
+}
+
+// String describes the schedule for the logs
+func (s Schedule) String() string {
+	description := fmt.Sprintf("every %s", s.Interval)
+	if s.cron != nil {
+		description = fmt.Sprintf("on %q", s.Cron)
+	} else if s.Align {
+		description += " aligned on the clock"
+	}
+	if s.Offset > 0 {
+		description += fmt.Sprintf(" with a splay of %s", s.Offset)
+	}
+	return description
+}
+
+// cronSchedule is a parsed cron expression made of the minute, hour, day of
+// the month, month and day of the week fields. Every field is a list of
+// values, ranges like 1-5 and * optionally followed by a step like */15.
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek []bool
+	// with both days restricted a day matching either of them is enough
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	// 7 is Sunday as well as 0
+	{"day of week", 0, 7},
+}
+
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%q should have 5 fields: minute, hour, day of month, month and day of week", expression)
+	}
+
+	parsed := make([][]bool, len(fields))
+	for i, field := range fields {
+		values, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = values
+	}
+	if parsed[4][7] {
+		parsed[4][0] = true
+	}
+
+	c := &cronSchedule{
+		minute:        parsed[0],
+		hour:          parsed[1],
+		dayOfMonth:    parsed[2],
+		month:         parsed[3],
+		dayOfWeek:     parsed[4],
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}
+	if c.next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%q never matches", expression)
+	}
+	return c, nil
+}
+
+func parseCronField(field string, spec cronField) ([]bool, error) {
+	values := make([]bool, spec.max+1)
+	for _, part := range strings.Split(field, ",") {
+		invalid := fmt.Errorf("%q is not a valid %s, it should be between %d and %d", part, spec.name, spec.min, spec.max)
+
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return nil, invalid
+			}
+			rangePart = part[:i]
+		}
+
+		from, to := spec.min, spec.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, invalid
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, invalid
+				}
+			} else if step > 1 {
+				// 5/15 means from 5 to the end every 15
+				to = spec.max
+			}
+		}
+		if from < spec.min || to > spec.max || from > to {
+			return nil, invalid
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// next returns the first minute matching the expression strictly after t,
+// the zero time when there is none within the next 5 years
+func (c *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !c.month[month]:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !c.hour[t.Hour()]:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth[t.Day()]
+	dayOfWeek := c.dayOfWeek[t.Weekday()]
+	if !c.anyDayOfMonth && !c.anyDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNextInterval(t *testing.T) {
+	start := time.Unix(1000, 0)
+	s := Schedule{Interval: 10 * time.Second}
+
+	assert.Equal(t, time.Unix(1010, 0), s.Next(start, start))
+	assert.Equal(t, time.Unix(1020, 0), s.Next(start, time.Unix(1010, 0)))
+	assert.Equal(t, time.Unix(1020, 0), s.Next(start, time.Unix(1015, 0)))
+
+	s.Offset = 3 * time.Second
+	assert.Equal(t, time.Unix(1003, 0), s.Next(start, start))
+	assert.Equal(t, time.Unix(1013, 0), s.Next(start, time.Unix(1003, 0)))
+}
+
+func TestScheduleNextAligned(t *testing.T) {
+	s := Schedule{Interval: time.Minute, Align: true}
+	assert.Equal(t, time.Unix(120, 0), s.Next(time.Unix(65, 0), time.Unix(65, 0)))
+	assert.Equal(t, time.Unix(180, 0), s.Next(time.Unix(65, 0), time.Unix(120, 0)))
+
+	s.Offset = 5 * time.Second
+	assert.Equal(t, time.Unix(125, 0), s.Next(time.Unix(65, 0), time.Unix(65, 0)))
+}
+
+func TestScheduleNextCron(t *testing.T) {
+	cron, err := parseCron("*/15 2 * * *")
+	assert.Nil(t, err)
+	s := Schedule{Cron: "*/15 2 * * *", cron: cron}
+
+	now := time.Date(2016, 3, 1, 1, 59, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2016, 3, 1, 2, 0, 0, 0, time.UTC), s.Next(now, now))
+	now = time.Date(2016, 3, 1, 2, 45, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2016, 3, 2, 2, 0, 0, 0, time.UTC), s.Next(now, now))
+
+	s.Offset = 20 * time.Second
+	now = time.Date(2016, 3, 1, 2, 0, 10, 0, time.UTC)
+	assert.Equal(t, time.Date(2016, 3, 1, 2, 0, 20, 0, time.UTC), s.Next(now, now))
+}
+
+func TestCronDays(t *testing.T) {
+	// the 1st of the month or any Monday
+	cron, err := parseCron("0 0 1 * 1")
+	assert.Nil(t, err)
+	// Saturday the 27th of February 2016
+	now := time.Date(2016, 2, 27, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC), cron.next(now))
+	assert.Equal(t, time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC), cron.next(time.Date(2016, 2, 29, 0, 0, 0, 0, time.UTC)))
+
+	// Sundays, 7 being the same as 0
+	cron, err = parseCron("30 4 * 1-6 7")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2016, 2, 28, 4, 30, 0, 0, time.UTC), cron.next(now))
+	assert.Equal(t, time.Date(2017, 1, 1, 4, 30, 0, 0, time.UTC), cron.next(time.Date(2016, 6, 27, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for expression, message := range map[string]string{
+		"* * * *":       `"* * * *" should have 5 fields`,
+		"60 * * * *":    `"60" is not a valid minute, it should be between 0 and 59`,
+		"* 5-3 * * *":   `"5-3" is not a valid hour`,
+		"* * 0 * *":     `"0" is not a valid day of month`,
+		"*/0 * * * *":   `"*/0" is not a valid minute`,
+		"* * * jan *":   `"jan" is not a valid month`,
+		"0 0 30 2 *":    `"0 0 30 2 *" never matches`,
+		"1,2,x * * * *": `"x" is not a valid minute`,
+	} {
+		_, err := parseCron(expression)
+		if assert.NotNil(t, err, expression) {
+			assert.Contains(t, err.Error(), message)
+		}
+	}
+}
+
+func TestConfigureSchedule(t *testing.T) {
+	c := New("Test")
+	c.SetInterval(10)
+	assert.Nil(t, c.Configure(map[string]interface{}{"splay": 5, "align": true}))
+	s := c.Schedule()
+	assert.Equal(t, 10*time.Second, s.Interval)
+	assert.True(t, s.Align)
+	assert.True(t, s.Offset >= 0 && s.Offset < 5*time.Second, "the offset should be within the splay")
+
+	c = New("Test")
+	assert.Nil(t, c.Configure(map[string]interface{}{"schedule": "0 3 * * *"}))
+	assert.Equal(t, "0 3 * * *", c.Schedule().Cron)
+	assert.Equal(t, time.Duration(0), c.Schedule().Offset)
+	assert.Equal(t, `on "0 3 * * *"`, c.Schedule().String())
+
+	err := New("Test").Configure(map[string]interface{}{"schedule": "0 3 * *", "splay": -1})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "splay should not be negative")
+		assert.Contains(t, err.Error(), "schedule: ")
+	}
+	err = New("Test").Configure(map[string]interface{}{"schedule": "0 3 * * *", "align": true})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "align and schedule can't be used together")
+	}
+}
@@ -12,6 +12,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"fullerite/config"
 	"fullerite/metric"
@@ -46,7 +47,7 @@ type SmemStats struct {
 
 var (
 	requiredConfigs      = []string{"user", "procsWhitelist"}
-	execCommand          = exec.Command
+	execCommand          = exec.CommandContext
 	commandOutput        = (*exec.Cmd).Output
 	getSmemStats         = (*SmemStats).getSmemStats
 	getCmdLineDimensions = (*SmemStats).getCmdLineDimensions
@@ -68,51 +69,69 @@ func newSmemStats(channel chan metric.Metric, initialInterval int, log *l.Entry)
 	return s
 }
 
+var smemStatsOptions = config.Options{
+	{Name: "user", Type: config.StringOption, Required: true,
+		Description: "user smem runs as"},
+	{Name: "procsWhitelist", Type: config.StringOption, Required: true,
+		Description: "regex of the processes to collect"},
+	{Name: "smemPath", Type: config.StringOption, Required: true,
+		Description: "path of the smem executable"},
+	{Name: "metricsBlacklist", Type: config.StringListOption,
+		Description: "smem metrics not to emit, among rss, vss, pss and uss"},
+	{Name: "dimensionsFromCmdline", Type: config.StringMapOption,
+		Description: "dimensions extracted from the command line, as {dimension: regex}"},
+	{Name: "dimensionsFromEnv", Type: config.StringMapOption,
+		Description: "dimensions read from environment variables, as {dimension: variable}"},
+}
+
 // Configure Override *baseCollector.Configure(); will fetch the whitelisted processes
-func (s *SmemStats) Configure(configMap map[string]interface{}) {
-	s.configureCommonParams(configMap)
+func (s *SmemStats) Configure(configMap map[string]interface{}) error {
+	commonErr := s.configureCommonParams(configMap)
+	values, err := smemStatsOptions.Decode(configMap)
 
-	if user, exists := configMap["user"]; exists {
-		s.user = user.(string)
-	} else {
-		s.log.Warn("Required config does not exist for SmemStats: user")
-	}
+	s.user = values.String("user")
+	s.whitelistedProcs = values.String("procsWhitelist")
+	s.smemPath = values.String("smemPath")
 
-	if whitelist, exists := configMap["procsWhitelist"]; exists {
-		s.whitelistedProcs = whitelist.(string)
+	if values.Has("metricsBlacklist") {
+		s.whitelistedMetrics = getWhitelistedMetrics(values.StringList("metricsBlacklist"))
 	} else {
-		s.log.Warn("Required config does not exist for SmemStats: procsWhitelist")
+		s.whitelistedMetrics = allMetrics
 	}
 
-	if smemPath, exists := configMap["smemPath"]; exists {
-		s.smemPath = smemPath.(string)
-	} else {
-		s.log.Warn("Required config does not exist for SmemStats: smemPath")
+	if values.Has("dimensionsFromCmdline") {
+		s.dimensionsFromCmdline = values.StringMap("dimensionsFromCmdline")
 	}
 
-	if blacklist, exists := configMap["metricsBlacklist"]; exists {
-		s.whitelistedMetrics = getWhitelistedMetrics(config.GetAsSlice(blacklist))
-	} else {
-		s.whitelistedMetrics = allMetrics
+	if values.Has("dimensionsFromEnv") {
+		s.dimensionsFromEnv = values.StringMap("dimensionsFromEnv")
 	}
+	return config.JoinErrors(commonErr, err)
+}
 
-	if dimensionsFromCmdline, exists := configMap["dimensionsFromCmdline"]; exists {
-		s.dimensionsFromCmdline = config.GetAsMap(dimensionsFromCmdline)
-	}
+// Options : the keys understood by the SmemStats collector
+func (s *SmemStats) Options() config.Options {
+	return append(smemStatsOptions, commonOptions...)
+}
 
-	if dimensionsFromEnv, exists := configMap["dimensionsFromEnv"]; exists {
-		s.dimensionsFromEnv = config.GetAsMap(dimensionsFromEnv)
-	}
+var smemStatsMetrics = []MetricDescription{
+	{Name: "<process>.smem.<pss|uss|vss|rss>", Type: metric.Gauge, Dimensions: []string{"<dimension from cmdline or env>"},
+		Description: "memory of every whitelisted process, the metrics are picked by metricsBlacklist"},
+}
+
+// Metrics : the metrics emitted by the SmemStats collector
+func (s *SmemStats) Metrics() []MetricDescription {
+	return append(smemStatsMetrics, commonMetrics...)
 }
 
 // Collect calls smem periodically
-func (s *SmemStats) Collect() {
+func (s *SmemStats) Collect(ctx context.Context) {
 	if s.whitelistedProcs == "" || s.user == "" || s.smemPath == "" {
 		return
 	}
 
-	for _, stat := range getSmemStats(s) {
-		dims := s.getCustomDimensions(stat.pid)
+	for _, stat := range getSmemStats(s, ctx) {
+		dims := s.getCustomDimensions(ctx, stat.pid)
 		for _, element := range s.whitelistedMetrics {
 			var m metric.Metric
 			switch element {
@@ -131,24 +150,24 @@ func (s *SmemStats) Collect() {
 	}
 }
 
-func (s *SmemStats) getCustomDimensions(pid int) map[string]string {
-	dims := getEnvDimensions(s, pid)
+func (s *SmemStats) getCustomDimensions(ctx context.Context, pid int) map[string]string {
+	dims := getEnvDimensions(s, ctx, pid)
 
-	for k, v := range getCmdLineDimensions(s, pid) {
+	for k, v := range getCmdLineDimensions(s, ctx, pid) {
 		dims[k] = v
 	}
 
 	return dims
 }
 
-func (s *SmemStats) getEnvDimensions(pid int) map[string]string {
+func (s *SmemStats) getEnvDimensions(ctx context.Context, pid int) map[string]string {
 	dims := make(map[string]string)
 
 	if pid == 0 || len(s.dimensionsFromEnv) == 0 {
 		return dims
 	}
 
-	environ := s.getEnviron(pid)
+	environ := s.getEnviron(ctx, pid)
 
 	if environ == "" {
 		return dims
@@ -166,14 +185,14 @@ func (s *SmemStats) getEnvDimensions(pid int) map[string]string {
 	return dims
 }
 
-func (s *SmemStats) getCmdLineDimensions(pid int) map[string]string {
+func (s *SmemStats) getCmdLineDimensions(ctx context.Context, pid int) map[string]string {
 	dims := make(map[string]string)
 
 	if pid == 0 || len(s.dimensionsFromCmdline) == 0 {
 		return dims
 	}
 
-	data := s.getCmdLine(pid)
+	data := s.getCmdLine(ctx, pid)
 
 	if data == "" {
 		return dims
@@ -190,7 +209,7 @@ func (s *SmemStats) getCmdLineDimensions(pid int) map[string]string {
 	return dims
 }
 
-func (s *SmemStats) getSmemStats() []smemStatLine {
+func (s *SmemStats) getSmemStats(ctx context.Context) []smemStatLine {
 	cmdLine := []string{
 		"/usr/bin/sudo",
 		"-u", s.user,
@@ -198,7 +217,7 @@ func (s *SmemStats) getSmemStats() []smemStatLine {
 		"-P", s.whitelistedProcs,
 		"-c", "pss uss rss vss name pid"}
 
-	out := s.runCommand(cmdLine)
+	out := s.runCommand(ctx, cmdLine)
 
 	if out == nil {
 		return nil
@@ -207,16 +226,16 @@ func (s *SmemStats) getSmemStats() []smemStatLine {
 	return s.parseSmemLines(string(out))
 }
 
-func (s *SmemStats) getCmdLine(pid int) string {
+func (s *SmemStats) getCmdLine(ctx context.Context, pid int) string {
 	cmdLine := []string{
 		"/bin/cat",
 		fmt.Sprintf("/proc/%d/cmdline", pid),
 	}
 
-	return string(s.runCommand(cmdLine))
+	return string(s.runCommand(ctx, cmdLine))
 }
 
-func (s *SmemStats) getEnviron(pid int) string {
+func (s *SmemStats) getEnviron(ctx context.Context, pid int) string {
 	cmdLine := []string{
 		"/usr/bin/sudo",
 		"-u", s.user,
@@ -224,7 +243,7 @@ func (s *SmemStats) getEnviron(pid int) string {
 		fmt.Sprintf("/proc/%d/environ", pid),
 	}
 
-	environ := s.runCommand(cmdLine)
+	environ := s.runCommand(ctx, cmdLine)
 
 	if environ == nil {
 		return ""
@@ -233,11 +252,11 @@ func (s *SmemStats) getEnviron(pid int) string {
 	return string(environ)
 }
 
-func (s *SmemStats) runCommand(cmdLine []string) []byte {
+func (s *SmemStats) runCommand(ctx context.Context, cmdLine []string) []byte {
 	var out []byte
 	var err error
 
-	cmd := execCommand(cmdLine[0], cmdLine[1:]...)
+	cmd := execCommand(ctx, cmdLine[0], cmdLine[1:]...)
 
 	if out, err = commandOutput(cmd); err != nil {
 		s.log.Error(err.Error())
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"errors"
 	"fullerite/metric"
 	"os/exec"
@@ -84,7 +85,7 @@ func TestSmemStatsCollect(t *testing.T) {
 		getEnvDimensions = oldGetEnvDimensions
 	}()
 
-	execCommand = func(string, ...string) *exec.Cmd {
+	execCommand = func(context.Context, string, ...string) *exec.Cmd {
 		return &exec.Cmd{}
 	}
 
@@ -92,13 +93,13 @@ func TestSmemStatsCollect(t *testing.T) {
 		return []byte(smemOutput), nil
 	}
 
-	getCmdLineDimensions = func(*SmemStats, int) map[string]string {
+	getCmdLineDimensions = func(*SmemStats, context.Context, int) map[string]string {
 		return map[string]string{
 			"dim1": "val1",
 		}
 	}
 
-	getEnvDimensions = func(*SmemStats, int) map[string]string {
+	getEnvDimensions = func(*SmemStats, context.Context, int) map[string]string {
 		return map[string]string{
 			"dim2": "val2",
 		}
@@ -119,7 +120,7 @@ func TestSmemStatsCollect(t *testing.T) {
 	sut.whitelistedProcs = "some|whitelist"
 	sut.smemPath = "/path/to/smem"
 	sut.whitelistedMetrics = []string{"pss", "uss", "vss", "rss"}
-	go sut.Collect()
+	go sut.Collect(context.Background())
 
 	for i := 0; i < len(expected); i++ {
 		actual = append(actual, <-c)
@@ -133,7 +134,7 @@ func TestSmemStatsCollectNotCalled(t *testing.T) {
 	defer func() { getSmemStats = oldGetSmemStats }()
 
 	getSmemStatsCalled := false
-	getSmemStats = func(*SmemStats) []smemStatLine {
+	getSmemStats = func(*SmemStats, context.Context) []smemStatLine {
 		getSmemStatsCalled = true
 		return nil
 	}
@@ -165,7 +166,7 @@ func TestSmemStatsCollectNotCalled(t *testing.T) {
 		sut.smemPath = test.smemPath
 		sut.whitelistedMetrics = test.whitelistedMetrics
 
-		sut.Collect()
+		sut.Collect(context.Background())
 
 		assert.False(t, getSmemStatsCalled)
 	}
@@ -209,13 +210,13 @@ func TestGetCmdLineDimensions(t *testing.T) {
 		msg                   string
 	}{
 		{
-			pid: 0,
+			pid:                   0,
 			dimensionsFromCmdLine: map[string]string{},
 			expectedDimensions:    map[string]string{},
 			msg:                   "PID is 0; so no dimensions should be reported",
 		},
 		{
-			pid: 1234,
+			pid:                   1234,
 			dimensionsFromCmdLine: map[string]string{},
 			expectedDimensions:    map[string]string{},
 			msg:                   "Although PID is not 0, the dimensionsFromEnv is empty; so no dimensions should be reported",
@@ -250,7 +251,7 @@ func TestGetCmdLineDimensions(t *testing.T) {
 		s := newSmemStats(nil, 0, defaultLog.WithFields(l.Fields{"collector": "SmemStats"})).(*SmemStats)
 		s.dimensionsFromCmdline = test.dimensionsFromCmdLine
 
-		execCommand = func(string, ...string) *exec.Cmd {
+		execCommand = func(context.Context, string, ...string) *exec.Cmd {
 			return &exec.Cmd{}
 		}
 
@@ -258,7 +259,7 @@ func TestGetCmdLineDimensions(t *testing.T) {
 			return []byte(test.cmdLineData), test.cmdLineReadError
 		}
 
-		assert.Equal(t, test.expectedDimensions, getCmdLineDimensions(s, test.pid), test.msg)
+		assert.Equal(t, test.expectedDimensions, getCmdLineDimensions(s, context.Background(), test.pid), test.msg)
 	}
 }
 
@@ -321,7 +322,7 @@ func TestGetEnvDimensions(t *testing.T) {
 		s := newSmemStats(nil, 0, defaultLog.WithFields(l.Fields{"collector": "SmemStats"})).(*SmemStats)
 		s.dimensionsFromEnv = test.dimensionsFromEnv
 
-		execCommand = func(string, ...string) *exec.Cmd {
+		execCommand = func(context.Context, string, ...string) *exec.Cmd {
 			return &exec.Cmd{}
 		}
 
@@ -329,6 +330,6 @@ func TestGetEnvDimensions(t *testing.T) {
 			return []byte(test.environ), test.environReadError
 		}
 
-		assert.Equal(t, test.expectedDimensions, getEnvDimensions(s, test.pid), test.msg)
+		assert.Equal(t, test.expectedDimensions, getEnvDimensions(s, context.Background(), test.pid), test.msg)
 	}
 }
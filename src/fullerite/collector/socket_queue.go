@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fullerite/config"
 	"fullerite/metric"
 	"fullerite/util"
@@ -37,18 +38,36 @@ func newSocketQueue(channel chan metric.Metric, initialInterval int, log *l.Entr
 	return ss
 }
 
+var socketQueueOptions = config.Options{
+	{Name: "PortList", Type: config.StringListOption, Required: true,
+		Description: "ports whose receive queue is collected"},
+}
+
 // Configure Override default parameters
-func (ss *SocketQueue) Configure(configMap map[string]interface{}) {
-	ss.configureCommonParams(configMap)
-	if asInterface, exists := configMap["PortList"]; exists {
-		ss.portList = config.GetAsSlice(asInterface)
-	} else {
-		ss.log.Warn("Required config 'PortList' does not exist")
-	}
+func (ss *SocketQueue) Configure(configMap map[string]interface{}) error {
+	commonErr := ss.configureCommonParams(configMap)
+	values, err := socketQueueOptions.Decode(configMap)
+	ss.portList = values.StringList("PortList")
+	return config.JoinErrors(commonErr, err)
+}
+
+// Options : the keys understood by the SocketQueue collector
+func (ss *SocketQueue) Options() config.Options {
+	return append(socketQueueOptions, commonOptions...)
+}
+
+var socketQueueMetrics = []MetricDescription{
+	{Name: "sq.listen", Type: metric.Gauge, Dimensions: []string{"port"},
+		Description: "connections waiting to be accepted on the port"},
+}
+
+// Metrics : the metrics emitted by the SocketQueue collector
+func (ss *SocketQueue) Metrics() []MetricDescription {
+	return append(socketQueueMetrics, commonMetrics...)
 }
 
 // Collect the receive queue size (RecvQ)
-func (ss SocketQueue) Collect() {
+func (ss SocketQueue) Collect(ctx context.Context) {
 	if len(ss.portList) == 0 {
 		ss.log.Warn("At least one port must be specified in the config")
 		return
@@ -59,7 +78,7 @@ func (ss SocketQueue) Collect() {
 	*/
 	filter := "sport = :" + strings.Join(ss.portList, " or sport = :")
 
-	cmd := exec.Command("ss", "-ntl", filter)
+	cmd := exec.CommandContext(ctx, "ss", "-ntl", filter)
 	output, err := cmdOutput(cmd)
 	if err != nil {
 		ss.log.Error("Error while collecting metrics: ", err)
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fullerite/metric"
 	"os/exec"
 	"testing"
@@ -58,7 +59,7 @@ func TestCollectSocketQueue(t *testing.T) {
 		"PortList": []string{"9080", "1234", "1224"},
 	}
 	sscol.Configure(cfg)
-	go sscol.Collect()
+	go sscol.Collect(context.Background())
 
 	for range expected {
 		actual := <-sscol.Channel()
@@ -1,8 +1,10 @@
 package collector
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
 
+	"context"
 	"math/rand"
 	"time"
 
@@ -40,16 +42,35 @@ func NewTest(channel chan metric.Metric, initialInterval int, log *l.Entry) Coll
 	return t
 }
 
+var testOptions = config.Options{
+	{Name: "metricName", Type: config.StringOption, Default: "TestMetric",
+		Description: "name of the random metric"},
+}
+
 // Configure this takes a dictionary of values with which the handler can configure itself
-func (t *Test) Configure(configMap map[string]interface{}) {
-	if metricName, exists := configMap["metricName"]; exists {
-		t.metricName = metricName.(string)
-	}
-	t.configureCommonParams(configMap)
+func (t *Test) Configure(configMap map[string]interface{}) error {
+	values, err := testOptions.Decode(configMap)
+	t.metricName = values.String("metricName")
+	return config.JoinErrors(err, t.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the Test collector
+func (t *Test) Options() config.Options {
+	return append(testOptions, commonOptions...)
+}
+
+var testMetrics = []MetricDescription{
+	{Name: "<metricName>", Type: metric.Gauge, Dimensions: []string{"testing"},
+		Description: "a random value"},
+}
+
+// Metrics : the metrics emitted by the Test collector
+func (t *Test) Metrics() []MetricDescription {
+	return append(testMetrics, commonMetrics...)
 }
 
 // Collect produces some random test metrics.
-func (t Test) Collect() {
+func (t Test) Collect(ctx context.Context) {
 	metric := metric.New(t.metricName)
 	metric.Value = t.generator()
 	metric.AddDimension("testing", "yes")
@@ -4,6 +4,7 @@ import (
 	"fullerite/metric"
 	"fullerite/test_utils"
 
+	"context"
 	"testing"
 	"time"
 
@@ -47,7 +48,7 @@ func TestTestConfigureMetricName(t *testing.T) {
 	test := NewTest(testChannel, 123, testLogger).(*Test)
 	test.Configure(config)
 
-	go test.Collect()
+	go test.Collect(context.Background())
 
 	select {
 	case m := <-test.Channel():
@@ -73,7 +74,7 @@ func TestTestCollect(t *testing.T) {
 	test.Configure(config)
 	test.generator = mockGen
 
-	go test.Collect()
+	go test.Collect(context.Background())
 
 	select {
 	case m := <-test.Channel():
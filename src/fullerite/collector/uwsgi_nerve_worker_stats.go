@@ -5,6 +5,7 @@ import (
 	"fullerite/metric"
 	"fullerite/util"
 
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	l "github.com/Sirupsen/logrus"
@@ -50,27 +52,48 @@ func newUWSGINerveWorkerStats(channel chan metric.Metric, initialInterval int, l
 	return col
 }
 
+var uWSGINerveWorkerStatsOptions = config.Options{
+	{Name: "queryPath", Type: config.StringOption, Default: "status/uwsgi",
+		Description: "path of the uWSGI stats endpoint of the services"},
+	{Name: "configFilePath", Type: config.StringOption, Default: "/etc/nerve/nerve.conf.json",
+		Description: "nerve config listing the services to collect from"},
+	{Name: "servicesWhitelist", Type: config.StringListOption,
+		Description: "only collect from these services"},
+	{Name: "http_timeout", Type: config.IntOption, Default: 2,
+		Description: "seconds to wait for a service to answer"},
+}
+
 // Rewrites config variables from the global config
-func (n *uWSGINerveWorkerStatsCollector) Configure(configMap map[string]interface{}) {
-	if val, exists := configMap["queryPath"]; exists {
-		n.queryPath = val.(string)
-	}
-	if val, exists := configMap["configFilePath"]; exists {
-		n.configFilePath = val.(string)
-	}
-	if val, exists := configMap["servicesWhitelist"]; exists {
-		n.servicesWhitelist = config.GetAsSlice(val)
-	}
+func (n *uWSGINerveWorkerStatsCollector) Configure(configMap map[string]interface{}) error {
+	values, err := uWSGINerveWorkerStatsOptions.Decode(configMap)
 
-	if val, exists := configMap["http_timeout"]; exists {
-		n.timeout = config.GetAsInt(val, 2)
+	n.queryPath = values.String("queryPath")
+	n.configFilePath = values.String("configFilePath")
+	if values.Has("servicesWhitelist") {
+		n.servicesWhitelist = values.StringList("servicesWhitelist")
 	}
+	n.timeout = values.Int("http_timeout")
+
+	return config.JoinErrors(err, n.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the UWSGINerveWorkerStats collector
+func (n *uWSGINerveWorkerStatsCollector) Options() config.Options {
+	return append(uWSGINerveWorkerStatsOptions, commonOptions...)
+}
+
+var uWSGINerveWorkerStatsMetrics = []MetricDescription{
+	{Name: "<state>Workers", Type: metric.Gauge, Dimensions: []string{"service", "port"},
+		Description: "uWSGI workers per state: Idle, Busy, Sig, Pause, Cheap or UnknownState"},
+}
 
-	n.configureCommonParams(configMap)
+// Metrics : the metrics emitted by the UWSGINerveWorkerStats collector
+func (n *uWSGINerveWorkerStatsCollector) Metrics() []MetricDescription {
+	return append(uWSGINerveWorkerStatsMetrics, commonMetrics...)
 }
 
 // Parses nerve config from HTTP uWSGI stats endpoints
-func (n *uWSGINerveWorkerStatsCollector) Collect() {
+func (n *uWSGINerveWorkerStatsCollector) Collect(ctx context.Context) {
 	rawFileContents, err := ioutil.ReadFile(n.configFilePath)
 	if err != nil {
 		n.log.Warn("Failed to read the contents of file ", n.configFilePath, " because ", err)
@@ -84,21 +107,27 @@ func (n *uWSGINerveWorkerStatsCollector) Collect() {
 	}
 	n.log.Debug("Finished parsing Nerve config into ", services)
 
+	var wg sync.WaitGroup
 	for _, service := range services {
 		if n.serviceInWhitelist(service) {
-			go n.queryService(service.Name, service.Port)
+			wg.Add(1)
+			go func(service util.NerveService) {
+				defer wg.Done()
+				n.queryService(ctx, service.Name, service.Port)
+			}(service)
 		}
 	}
+	wg.Wait()
 }
 
 // Fetches and computes status stats from an HTTP endpoint
-func (n *uWSGINerveWorkerStatsCollector) queryService(serviceName string, port int) {
+func (n *uWSGINerveWorkerStatsCollector) queryService(ctx context.Context, serviceName string, port int) {
 	serviceLog := n.log.WithField("service", serviceName)
 
 	endpoint := fmt.Sprintf("http://localhost:%d/%s", port, n.queryPath)
 	serviceLog.Debug("making GET request to ", endpoint)
 
-	rawResponse, err := readJSONFromEndpoint(endpoint, n.timeout)
+	rawResponse, err := readJSONFromEndpoint(ctx, endpoint, n.timeout)
 	if err != nil {
 		serviceLog.Warn("Failed to query endpoint ", endpoint, ": ", err)
 		return
@@ -175,12 +204,12 @@ func parseJSONData(raw []byte) ([]metric.Metric, error) {
 }
 
 // Fetches the JSON stats content from HTTP endpoint
-func readJSONFromEndpoint(endpoint string, timeout int) ([]byte, error) {
+func readJSONFromEndpoint(ctx context.Context, endpoint string, timeout int) ([]byte, error) {
 	client := http.Client{
 		Timeout: time.Duration(timeout) * time.Second,
 	}
 
-	rsp, err := client.Get(endpoint)
+	rsp, err := util.HTTPGet(ctx, &client, endpoint)
 
 	if rsp != nil {
 		defer func() {
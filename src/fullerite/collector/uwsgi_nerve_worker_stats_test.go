@@ -4,6 +4,7 @@ import (
 	"fullerite/metric"
 	"fullerite/util"
 
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -381,7 +382,7 @@ func TestErrorQueryStatsEndpointResponse(t *testing.T) {
 	endpoint := ts.URL + "/status/uwsgi"
 	ts.Close()
 
-	_, _, queryEndpointError := queryEndpoint(endpoint, 10)
+	_, _, queryEndpointError := queryEndpoint(context.Background(), endpoint, 10)
 	assert.NotNil(t, queryEndpointError)
 
 	//Socket closed test
@@ -390,7 +391,7 @@ func TestErrorQueryStatsEndpointResponse(t *testing.T) {
 	}))
 	tsClosed.Close()
 	closedEndpoint := tsClosed.URL + "/status/uwsgi"
-	_, queryClosedEndpointResponse, queryClosedEndpointError := queryEndpoint(closedEndpoint, 10)
+	_, queryClosedEndpointResponse, queryClosedEndpointError := queryEndpoint(context.Background(), closedEndpoint, 10)
 	assert.NotNil(t, queryClosedEndpointError)
 	assert.Equal(t, "", queryClosedEndpointResponse)
 }
@@ -457,7 +458,7 @@ func DoTesting(t *testing.T, firstResponse string, secondResponse string, result
 	inst := getTestNerveUWSGIWorkerStats()
 	inst.Configure(cfg)
 
-	go inst.Collect()
+	go inst.Collect(context.Background())
 
 	actual := []metric.Metric{}
 	for i := 0; i < 6; i++ {
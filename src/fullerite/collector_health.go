@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/metric"
+
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// collectorHealth is how the collections of a running collector went
+type collectorHealth struct {
+	// updated by the reader on every metric without the registry lock,
+	// first for their alignment: the metrics sent to the handlers since
+	// the last collection started and when the last one was sent,
+	// in unix nanoseconds
+	runMetrics uint64
+	lastMetric int64
+
+	// the collector instance the health belongs to, so that a collector
+	// replaced on reload leaves the health of its successor alone
+	owner           collector.Collector
+	started         time.Time
+	running         bool
+	lastRunStart    time.Time
+	lastRunDuration time.Duration
+	lastSuccess     time.Time
+	errors          uint64
+	timeouts        uint64
+
+	// errors logged before the collection in progress started
+	errorsAtStart uint64
+	// metrics sent to the handlers during the collection before the one in progress
+	lastRunMetrics uint64
+}
+
+// collectorsHealth keeps the health of every running collector
+// for the internal server
+var collectorsHealth = struct {
+	sync.Mutex
+	collectors map[string]*collectorHealth
+}{collectors: make(map[string]*collectorHealth)}
+
+// addCollectorHealth registers the health of the collector
+// in place of the one of a collector with the same name
+func addCollectorHealth(c collector.Collector) *collectorHealth {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	h := &collectorHealth{owner: c, started: time.Now()}
+	collectorsHealth.collectors[c.CanonicalName()] = h
+	return h
+}
+
+// removeCollectorHealth removes the health of the collector
+// unless another collector with the same name replaced it
+func removeCollectorHealth(c collector.Collector) {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	if h, exists := collectorsHealth.collectors[c.CanonicalName()]; exists && h.owner == c {
+		delete(collectorsHealth.collectors, c.CanonicalName())
+	}
+}
+
+// collectorHealthOf returns the health of the collector,
+// nil when it isn't registered
+func collectorHealthOf(c collector.Collector) *collectorHealth {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	if h, exists := collectorsHealth.collectors[c.CanonicalName()]; exists && h.owner == c {
+		return h
+	}
+	return nil
+}
+
+// updateCollectorHealth calls update with the health of the collector
+// when it is running
+func updateCollectorHealth(collectorName string, update func(*collectorHealth)) {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	if h, exists := collectorsHealth.collectors[collectorName]; exists {
+		update(h)
+	}
+}
+
+// update calls update with the health under the registry lock,
+// it does nothing for a collector that isn't registered
+func (h *collectorHealth) update(update func(*collectorHealth)) {
+	if h == nil {
+		return
+	}
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	update(h)
+}
+
+// collectionStarted records the start of a collection, the metrics
+// counted so far are those of the previous one
+func (h *collectorHealth) collectionStarted(start time.Time) {
+	h.update(func(h *collectorHealth) {
+		h.running = true
+		h.lastRunStart = start
+		h.errorsAtStart = h.errors
+		h.lastRunMetrics = atomic.SwapUint64(&h.runMetrics, 0)
+	})
+}
+
+// collectionFinished records the end of a collection, it succeeded when
+// Collect returned in time without panicking or logging an error
+func (h *collectorHealth) collectionFinished(end time.Time, succeeded bool) {
+	h.update(func(h *collectorHealth) {
+		h.running = false
+		h.lastRunDuration = end.Sub(h.lastRunStart)
+		if succeeded && h.errors == h.errorsAtStart {
+			h.lastSuccess = end
+		}
+	})
+}
+
+func (h *collectorHealth) countTimeout() {
+	h.update(func(h *collectorHealth) {
+		h.timeouts++
+	})
+}
+
+// countMetric records a metric sent to the handlers
+func (h *collectorHealth) countMetric() {
+	if h == nil {
+		return
+	}
+	atomic.AddUint64(&h.runMetrics, 1)
+	atomic.StoreInt64(&h.lastMetric, time.Now().UnixNano())
+}
+
+// lastMetricTime is when the collector last sent a metric to the handlers
+func (h *collectorHealth) lastMetricTime() time.Time {
+	last := atomic.LoadInt64(&h.lastMetric)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+func countCollectorError(collectorName string) {
+	updateCollectorHealth(collectorName, func(h *collectorHealth) {
+		h.errors++
+	})
+}
+
+// healthStats returns the internal metrics describing
+// the health of every running collector
+func healthStats() map[string]metric.InternalMetrics {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	stats := make(map[string]metric.InternalMetrics, len(collectorsHealth.collectors))
+	for name, h := range collectorsHealth.collectors {
+		// the metrics of a collection keep coming until the next one starts
+		lastRunMetrics := atomic.LoadUint64(&h.runMetrics)
+		if h.running {
+			lastRunMetrics = h.lastRunMetrics
+		}
+		m := metric.NewInternalMetrics()
+		m.Counters["fullerite.collector_errors"] = float64(h.errors)
+		m.Counters["fullerite.collection_timeouts"] = float64(h.timeouts)
+		m.Gauges["fullerite.last_collection_start"] = unixOrZero(h.lastRunStart)
+		m.Gauges["fullerite.last_collection_duration"] = h.lastRunDuration.Seconds()
+		m.Gauges["fullerite.last_successful_collection"] = unixOrZero(h.lastSuccess)
+		m.Gauges["fullerite.last_collection_metrics"] = float64(lastRunMetrics)
+		stats[name] = *m
+	}
+	return stats
+}
+
+// unixOrZero is the unix timestamp of t, 0 when t isn't set
+func unixOrZero(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
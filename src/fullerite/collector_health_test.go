@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/metric"
+
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectorHealth(t *testing.T) {
+	col := collector.New("Test")
+	col.SetCanonicalName("Health")
+	h := addCollectorHealth(col)
+	defer removeCollectorHealth(col)
+
+	start := time.Unix(1000, 0)
+	h.collectionStarted(start)
+	h.countMetric()
+	h.countMetric()
+	h.collectionFinished(start.Add(2*time.Second), true)
+	h.countMetric()
+
+	gauges := healthStats()["Health"].Gauges
+	assert.Equal(t, 1000.0, gauges["fullerite.last_collection_start"])
+	assert.Equal(t, 2.0, gauges["fullerite.last_collection_duration"])
+	assert.Equal(t, 1002.0, gauges["fullerite.last_successful_collection"])
+	assert.Equal(t, 3.0, gauges["fullerite.last_collection_metrics"], "the metrics still coming belong to the last collection")
+
+	h.collectionStarted(start.Add(10 * time.Second))
+	h.countMetric()
+	countCollectorError("Health")
+	assert.Equal(t, 3.0, healthStats()["Health"].Gauges["fullerite.last_collection_metrics"],
+		"the collection in progress should not be reported yet")
+
+	h.countTimeout()
+	h.collectionFinished(start.Add(15*time.Second), true)
+	stats := healthStats()["Health"]
+	assert.Equal(t, 1002.0, stats.Gauges["fullerite.last_successful_collection"], "a collection that logged an error did not succeed")
+	assert.Equal(t, 1.0, stats.Gauges["fullerite.last_collection_metrics"])
+	assert.Equal(t, 1.0, stats.Counters["fullerite.collector_errors"])
+	assert.Equal(t, 1.0, stats.Counters["fullerite.collection_timeouts"])
+}
+
+func TestCollectorHealthIgnoresCollectorsNotRunning(t *testing.T) {
+	countCollectorError("NotRunning")
+	_, exists := healthStats()["NotRunning"]
+	assert.False(t, exists)
+}
+
+func TestCollectorHealthOfReplacedCollector(t *testing.T) {
+	replaced := collector.New("Test")
+	replaced.SetCanonicalName("Health")
+	replacement := collector.New("Test")
+	replacement.SetCanonicalName("Health")
+
+	addCollectorHealth(replaced)
+	h := addCollectorHealth(replacement)
+	defer removeCollectorHealth(replacement)
+	assert.Nil(t, collectorHealthOf(replaced))
+
+	removeCollectorHealth(replaced)
+	assert.True(t, h == collectorHealthOf(replacement), "the replaced collector should leave the health of its replacement alone")
+	assert.Contains(t, healthStats(), "Health")
+}
+
+func TestReadCollectorStat(t *testing.T) {
+	col := collector.New("Test")
+	col.SetCanonicalName("Health")
+	addCollectorHealth(col)
+	defer removeCollectorHealth(col)
+
+	statChan := make(chan metric.CollectorEmission)
+	stats := readCollectorStat(statChan)
+	statChan <- metric.CollectorEmission{Name: "Health", EmissionCount: 42}
+	close(statChan)
+
+	var m metric.InternalMetrics
+	for i := 0; i < 100 && m.Counters["fullerite.collector_datapoints"] == 0; i++ {
+		time.Sleep(time.Millisecond)
+		m = stats()["Health"]
+	}
+	assert.Equal(t, 42.0, m.Counters["fullerite.collector_datapoints"])
+	assert.Equal(t, 0.0, m.Counters["fullerite.collector_errors"])
+	_, exists := m.Gauges["fullerite.last_collection_start"]
+	assert.True(t, exists, "the health of the collector should be merged in")
+}
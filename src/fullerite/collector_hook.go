@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	"fullerite/handler"
 	"fullerite/metric"
 
 	"github.com/Sirupsen/logrus"
@@ -12,7 +11,7 @@ import (
 
 // LogErrorHook to send errors via handlers.
 type LogErrorHook struct {
-	handlers []handler.Handler
+	handlers *handlerSet
 
 	// intentionally exported
 	log *logrus.Entry
@@ -20,7 +19,7 @@ type LogErrorHook struct {
 
 // NewLogErrorHook creates a hook to be added to the collector logger
 // so that errors are forwarded as a metric to the handlers.
-func NewLogErrorHook(handlers []handler.Handler) *LogErrorHook {
+func NewLogErrorHook(handlers *handlerSet) *LogErrorHook {
 	hookLog := log.WithFields(logrus.Fields{"hook": "LogErrorHook"})
 	return &LogErrorHook{handlers, hookLog}
 }
@@ -33,6 +32,9 @@ func (hook *LogErrorHook) Fire(entry *logrus.Entry) error {
 		return err
 	}
 
+	if collectorName, ok := entry.Data["collector"].(string); ok {
+		countCollectorError(collectorName)
+	}
 	go hook.reportErrors(entry)
 	return nil
 }
@@ -53,6 +55,6 @@ func (hook *LogErrorHook) reportErrors(entry *logrus.Entry) {
 		metric.AddDimension("collector", val.(string))
 	}
 
-	writeToHandlers(hook.handlers, metric)
+	hook.handlers.writeToHandlers(metric)
 	return
 }
@@ -1,20 +1,21 @@
 package main
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"fullerite/collector"
 	"fullerite/handler"
 	"fullerite/metric"
-	"fullerite/test_utils"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCollectorLogsErrors(t *testing.T) {
-	testLogger := test_utils.BuildLogger()
-	testLogger = testLogger.WithField("collector", "Test")
+	// a logger of its own, so that the hook doesn't outlive the test
+	testLogger := logrus.New().WithField("collector", "Test")
 
 	channel := make(chan metric.Metric)
 	config := make(map[string]interface{})
@@ -25,10 +26,10 @@ func TestCollectorLogsErrors(t *testing.T) {
 	timeout := time.Duration(5 * time.Second)
 	h := handler.NewTest(channel, 10, 10, timeout, testLogger)
 
-	hook := NewLogErrorHook([]handler.Handler{h})
+	hook := NewLogErrorHook(newHandlerSet([]handler.Handler{h}))
 	testLogger.Logger.Hooks.Add(hook)
 
-	go testCol.Collect()
+	go testCol.Collect(context.Background())
 	testLogger.Error("testing Error log")
 
 	select {
@@ -3,11 +3,13 @@ package main
 import (
 	"fullerite/collector"
 	"fullerite/config"
-	"fullerite/handler"
 	"fullerite/metric"
+	"fullerite/processor"
+	"fullerite/util"
 
+	"context"
 	"fmt"
-	"regexp"
+	"sync"
 	"time"
 )
 
@@ -31,57 +33,224 @@ func startCollectors(c config.Config) (collectors []collector.Collector) {
 
 func startCollector(name string, globalConfig config.Config, instanceConfig map[string]interface{}) collector.Collector {
 	log.Debug("Starting collector ", name)
-	collectorInst := collector.New(name)
+	collectorInst := newCollector(name, globalConfig, instanceConfig)
 	if collectorInst == nil {
 		return nil
 	}
 
-	// apply the global configs
-	collectorInst.SetInterval(config.GetAsInt(globalConfig.Interval, collector.DefaultCollectionInterval))
+	log.Info("Running ", collectorInst)
+	// registered before the reader of the collector looks them up
+	// and before a collector it replaces is done with its own
+	addCollectorSchedule(collectorInst)
+	addCollectorHealth(collectorInst)
+	go runCollector(collectorInst)
+	return collectorInst
+}
 
-	// apply the instance configs
-	collectorInst.Configure(instanceConfig)
+// newCollector creates and configures a collector without starting it,
+// it returns nil when the collector is unknown or misconfigured
+func newCollector(name string, globalConfig config.Config, instanceConfig map[string]interface{}) collector.Collector {
+	collectorInst := collector.New(name)
+	if collectorInst == nil {
+		return nil
+	}
 
-	go runCollector(collectorInst)
+	if err := configureCollector(collectorInst, globalConfig, instanceConfig); err != nil {
+		log.Error("Collector ", name, " is misconfigured, not starting it: ", err)
+		return nil
+	}
+
+	// the global processors run before the ones of the collector
+	if len(globalConfig.Processors) > 0 {
+		chain, err := processor.New(globalConfig.Processors)
+		if err != nil {
+			log.Error(err)
+		}
+		collectorInst.SetProcessors(append(chain, collectorInst.Processors()...))
+	}
 	return collectorInst
 }
 
+// configureCollector applies the global and the instance configs to the
+// collector, check-config goes through it too so that it fails the same
+// configs the daemon does
+func configureCollector(collectorInst collector.Collector, globalConfig config.Config, instanceConfig map[string]interface{}) error {
+	collectorInst.SetInterval(config.GetAsInt(globalConfig.Interval, collector.DefaultCollectionInterval))
+	return collectorInst.Configure(instanceConfig)
+}
+
+// runCollector collects on the schedule of the collector until it is stopped.
+// Every collection gets a context which is cancelled at its deadline, and the
+// collections due while the previous one is still running are skipped. The
+// collector is held back for a while after a panic, and for good once it
+// panicked too many times in a row. The control API can pause the collector
+// and have it collect out of schedule.
 func runCollector(collector collector.Collector) {
-	log.Info("Running ", collector)
+	schedule := collector.Schedule()
+	log.Info("Collector ", collector.CanonicalName(), " runs ", schedule)
+	// the entries registered when the collector was started, a
+	// collector replacing it on reload registers its own
+	registered := collectorScheduleOf(collector)
+	if registered == nil {
+		registered = &collectorSchedule{owner: collector}
+	}
+	defer removeCollectorSchedule(collector)
+	defer removeCollectorHealth(collector)
+
+	start := time.Now()
+	next := schedule.Next(start, start)
+	registered.set(schedule, next)
+	timer := time.NewTimer(next.Sub(start))
+	defer timer.Stop()
+
+	// closed once the collection in progress, if any, is over
+	var running <-chan struct{}
+	cancel := context.CancelFunc(func() {})
+	defer func() { cancel() }()
+
+	backoff := new(util.PanicBackoff)
+
+	// listeners can't collect on demand
+	var trigger chan struct{}
+	if collector.CollectorType() != "listener" {
+		trigger = make(chan struct{}, 1)
+	}
+	registered.setTrigger(trigger)
+
+	staggerValue := time.Second
+	collect := func(now time.Time) {
+		if !backoff.Ready(now) {
+			return
+		}
+
+		if collector.CollectorType() == "listener" {
+			collectSafely(context.Background(), collector, backoff)
+			return
+		}
 
-	ticker := time.NewTicker(time.Duration(collector.Interval()) * time.Second)
-	collect := ticker.C
+		if running != nil {
+			select {
+			case <-running:
+			default:
+				log.Warn(collector.Name(), " collector is still running, skipping this collection")
+				registered.countSkipped()
+				return
+			}
+		}
 
-	staggerValue := 1
-	collectionDeadline := time.Duration(collector.Interval() + staggerValue)
+		// the collection should be over by the time the next one starts
+		timeout := collector.CollectionTimeout()
+		if timeout <= 0 {
+			timeout = next.Sub(now) + staggerValue
+		}
+		running, cancel = startCollection(collector, timeout, backoff)
+	}
 
 	for {
 		select {
-		case <-collect:
-			if collector.CollectorType() == "listener" {
-				collector.Collect()
-			} else {
-				countdownTimer := time.AfterFunc(collectionDeadline*time.Second, func() {
-					reportCollector(collector)
-				})
-				collector.Collect()
-				countdownTimer.Stop()
+		case <-timer.C:
+			now := time.Now()
+			next = schedule.Next(start, now)
+			registered.set(schedule, next)
+			timer.Reset(next.Sub(now))
+
+			if registered.isPaused() {
+				continue
 			}
+			collect(now)
+		case <-trigger:
+			log.Info("Collector ", collector.CanonicalName(), " collects on demand")
+			collect(time.Now())
+		case <-collector.StopChannel():
+			return
 		}
 	}
-	ticker.Stop()
 }
 
+// startCollection runs Collect with a context cancelled after timeout,
+// the collector is reported as too long when it hasn't returned by then.
+// The returned channel is closed once Collect returns.
+func startCollection(collector collector.Collector, timeout time.Duration, backoff *util.PanicBackoff) (<-chan struct{}, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer cancel()
+		countdownTimer := time.AfterFunc(timeout, func() {
+			reportCollector(collector)
+		})
+		defer countdownTimer.Stop()
+		collectSafely(ctx, collector, backoff)
+	}()
+	return done, cancel
+}
+
+// collectSafely runs Collect, a panic is logged with its stack trace,
+// counted and recorded in the backoff of the collector. The collection
+// is recorded in the health of the collector.
+func collectSafely(ctx context.Context, col collector.Collector, backoff *util.PanicBackoff) {
+	name := col.CanonicalName()
+	health := collectorHealthOf(col)
+	health.collectionStarted(time.Now())
+	succeeded := false
+	defer func() { health.collectionFinished(time.Now(), succeeded) }()
+	defer util.Recover(log.WithField("collector", name), func(interface{}) {
+		collector.CountPanic(name)
+		if delay, disabled := backoff.Panicked(time.Now()); disabled {
+			log.Error("Collector ", name, " keeps panicking, disabling it")
+			collectorScheduleOf(col).disable()
+		} else {
+			log.Warn("Collector ", name, " panicked, restarting it in ", delay)
+		}
+	})
+	col.Collect(ctx)
+	backoff.Succeeded()
+	succeeded = ctx.Err() == nil
+}
+
+func stopCollectors(collectors []collector.Collector) {
+	log.Info("Stopping collectors...")
+	for _, c := range collectors {
+		c.Stop()
+	}
+}
+
+// readFromCollectors starts reading from every collector and returns
+// a WaitGroup which is done once all of them have been stopped.
+// readFromCollector closes the stat channels it is given when it is done,
+// so each collector writes to its own channel which is forwarded
+// to the shared ones.
 func readFromCollectors(collectors []collector.Collector,
-	handlers []handler.Handler,
-	collectorStatChans ...chan<- metric.CollectorEmission) {
+	handlers *handlerSet,
+	collectorStatChans ...chan<- metric.CollectorEmission) *sync.WaitGroup {
+	readers := new(sync.WaitGroup)
 	for i := range collectors {
-		go readFromCollector(collectors[i], handlers, collectorStatChans...)
+		statChans := []chan<- metric.CollectorEmission{}
+		for _, statChan := range collectorStatChans {
+			statChans = append(statChans, forwardCollectorStats(statChan))
+		}
+
+		readers.Add(1)
+		go func(c collector.Collector) {
+			defer readers.Done()
+			readFromCollector(c, handlers, statChans...)
+		}(collectors[i])
 	}
+	return readers
+}
+
+func forwardCollectorStats(collectorStatChan chan<- metric.CollectorEmission) chan<- metric.CollectorEmission {
+	forwarded := make(chan metric.CollectorEmission)
+	go func() {
+		for collectorMetric := range forwarded {
+			collectorStatChan <- collectorMetric
+		}
+	}()
+	return forwarded
 }
 
 func readFromCollector(collector collector.Collector,
-	handlers []handler.Handler,
+	handlers *handlerSet,
 	collectorStatChans ...chan<- metric.CollectorEmission) {
 	// In case of Diamond collectors, metric from multiple collectors are read
 	// from Single channel (owned by Go Diamond Collector) and hence we use a map
@@ -89,12 +258,16 @@ func readFromCollector(collector collector.Collector,
 	emissionCounter := map[string]uint64{}
 	lastEmission := time.Now()
 	statDuration := time.Duration(collector.Interval()) * time.Second
-	for m := range collector.Channel() {
-		var exists bool
+	cardinality := newCardinalityTracker(collector)
+	cardinalityWindow := time.Now()
+	defer removeCardinalityReport(collector.CanonicalName())
+	// looked up once rather than on every metric, they are
+	// nil for a collector that doesn't run on a schedule
+	schedule := collectorScheduleOf(collector)
+	health := collectorHealthOf(collector)
+
+	processMetric := func(m metric.Metric) {
 		c := collector.CanonicalName()
-		if _, exists = m.GetDimensionValue("collector"); !exists {
-			m.AddDimension("collector", collector.Name())
-		}
 		// We allow external collectors to provide us their collector's CanonicalName
 		// by sending it as a metric dimension. For example in the case of Diamond the
 		// individual python collectors can send their names this way.
@@ -102,12 +275,29 @@ func readFromCollector(collector collector.Collector,
 			c = val
 			m.RemoveDimension("collectorCanonicalName")
 		}
-		// check if the metric is blacklisted, if so skip it and
-		// process the next one
-		if stringInSlice(m.Name, collector.Blacklist()) {
-			continue
+		if schedule.isPaused() {
+			// silenced through the control API
+			return
+		}
+		if !prepareMetric(collector, &m) {
+			return
+		}
+
+		if time.Now().After(cardinalityWindow.Add(statDuration)) {
+			report, exceeded := cardinality.rollover(collector.CanonicalName())
+			setCardinalityReport(collector.CanonicalName(), report)
+			if exceeded != nil {
+				log.Warn(collector, " went over its limit of ", report.Limit, " series ",
+					exceeded.Value, " times, top metrics: ", report.TopMetrics)
+				handlers.writeToCollectorEndpoints(collector.CanonicalName(), *exceeded)
+			}
+			cardinalityWindow = time.Now()
+		}
+		if !cardinality.observe(&m) {
+			return
 		}
 		emissionCounter[c]++
+		health.countMetric()
 		// collectorStatChans is an optional parameter. In case of ad-hoc collector
 		// this parameter is not supplied at all. Using variadic arguments is pretty much
 		// only way of doing this in go.
@@ -120,13 +310,30 @@ func readFromCollector(collector collector.Collector,
 			}
 		}
 
-		if len(collector.Prefix()) > 0 {
-			m.Name = collector.Prefix() + m.Name
-		}
+		handlers.writeToCollectorEndpoints(c, m)
+	}
 
-		for i := range handlers {
-			if _, exists := handlers[i].CollectorEndpoints()[c]; exists {
-				handlers[i].CollectorEndpoints()[c].Channel <- m
+readLoop:
+	for {
+		select {
+		case m, ok := <-collector.Channel():
+			if !ok {
+				break readLoop
+			}
+			processMetric(m)
+		case <-collector.StopChannel():
+			// pass on whatever the collector is still trying to send
+			// so that handlers can flush it before we exit
+			for {
+				select {
+				case m, ok := <-collector.Channel():
+					if !ok {
+						break readLoop
+					}
+					processMetric(m)
+				default:
+					break readLoop
+				}
 			}
 		}
 	}
@@ -136,6 +343,29 @@ func readFromCollector(collector collector.Collector,
 	}
 }
 
+// prepareMetric applies the collector config to a metric it emitted, it
+// returns false when the metric is filtered out or dropped by a processor
+func prepareMetric(collector collector.Collector, m *metric.Metric) bool {
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+	if _, exists := m.GetDimensionValue("collector"); !exists {
+		m.AddDimension("collector", collector.Name())
+	}
+	// check if the metric is whitelisted and not blacklisted,
+	// if not skip it and process the next one
+	if !collector.MetricAllowed(m) {
+		return false
+	}
+
+	if len(collector.Prefix()) > 0 {
+		m.Name = collector.Prefix() + m.Name
+	}
+
+	// the processors see the metric as the handlers will
+	return collector.Processors().Process(m)
+}
+
 func emitCollectorStats(data map[string]uint64,
 	collectorStatChan chan<- metric.CollectorEmission) {
 	for collectorName, count := range data {
@@ -145,18 +375,9 @@ func emitCollectorStats(data map[string]uint64,
 
 func reportCollector(collector collector.Collector) {
 	log.Warn(fmt.Sprintf("%s collector took too long to run, reporting incident!", collector.Name()))
+	collectorHealthOf(collector).countTimeout()
 	metric := metric.New("fullerite.collection_time_exceeded")
 	metric.Value = 1
 	metric.AddDimension("interval", fmt.Sprintf("%d", collector.Interval()))
 	collector.Channel() <- metric
 }
-
-func stringInSlice(metricName string, list []string) bool {
-	var matched bool
-	for _, v := range list {
-		if matched, _ = regexp.MatchString(v, metricName); matched {
-			return true
-		}
-	}
-	return false
-}
@@ -75,6 +75,14 @@ func TestStartCollectorUnknownCollector(t *testing.T) {
 	assert.Nil(t, collector, "should NOT create a Collector")
 }
 
+func TestStartCollectorMisconfigured(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	c := map[string]interface{}{"interval": "often"}
+	collector := startCollector("Test", config.Config{}, c)
+
+	assert.Nil(t, collector, "should NOT start a misconfigured Collector")
+}
+
 func TestStartCollectorsMixedConfig(t *testing.T) {
 	logrus.SetLevel(logrus.ErrorLevel)
 	conf, _ := config.ReadConfig(tmpTestFakeFile)
@@ -90,6 +98,16 @@ func TestStartCollectorTooLong(t *testing.T) {
 	c := make(map[string]interface{})
 	c["interval"] = 1
 	collector := startCollector("Test", config.Config{}, c)
+	defer func() {
+		collector.Stop()
+		// runCollector is done once its schedule is gone
+		for i := 0; i < 100; i++ {
+			if _, running := scheduleStats()["Test"]; !running {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
 
 	select {
 	case m := <-collector.Channel():
@@ -133,7 +151,7 @@ func TestReadFromCollector(t *testing.T) {
 			collectorMetrics[collectorMetric.Name] = collectorMetric.EmissionCount
 		}
 	}()
-	readFromCollector(collector, []handler.Handler{}, collectorStatChannel)
+	readFromCollector(collector, newHandlerSet(nil), collectorStatChannel)
 	wg.Wait()
 	assert.Equal(t, uint64(1), collectorMetrics["Test"])
 	assert.Equal(t, uint64(2), collectorMetrics["Foobar"])
@@ -149,7 +167,7 @@ func TestCollectorPrefix(t *testing.T) {
 	collector.Configure(c)
 
 	collectorChannel := map[string]handler.CollectorEnd{
-		"Test": handler.CollectorEnd{make(chan metric.Metric), 1},
+		"Test": handler.CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 1},
 	}
 
 	testHandler := handler.New("Log")
@@ -167,7 +185,7 @@ func TestCollectorPrefix(t *testing.T) {
 		testMetric := <-collectorChannel["Test"].Channel
 		assert.Equal(t, "px.hello", testMetric.Name)
 	}()
-	readFromCollector(collector, []handler.Handler{testHandler})
+	readFromCollector(collector, newHandlerSet([]handler.Handler{testHandler}))
 	wg.Wait()
 }
 
@@ -201,8 +219,90 @@ func TestCollectorBlacklist(t *testing.T) {
 			collectorMetrics[collectorMetric.Name] = collectorMetric.EmissionCount
 		}
 	}()
-	readFromCollector(col, []handler.Handler{}, collectorStatChannel)
+	readFromCollector(col, newHandlerSet(nil), collectorStatChannel)
 	wg.Wait()
 
 	assert.Equal(t, uint64(1), collectorMetrics["Test"])
 }
+
+func TestCollectorProcessors(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	c := map[string]interface{}{
+		"processors": []interface{}{
+			map[string]interface{}{"type": "drop", "dimension": "env", "pattern": "^test$"},
+			map[string]interface{}{"type": "rename", "pattern": "^hello$", "replacement": "greeting"},
+			map[string]interface{}{"type": "add_dimension", "key": "source", "value": "%{collector}"},
+		},
+	}
+	col := collector.New("Test")
+	col.SetInterval(1)
+	col.Configure(c)
+
+	collectorChannel := map[string]handler.CollectorEnd{
+		"Test": handler.CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 1},
+	}
+	testHandler := handler.New("Log")
+	testHandler.SetCollectorEndpoints(collectorChannel)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		dropped := metric.New("hello")
+		dropped.AddDimension("env", "test")
+		col.Channel() <- dropped
+		col.Channel() <- metric.New("hello")
+		close(col.Channel())
+	}()
+	go func() {
+		defer wg.Done()
+		testMetric := <-collectorChannel["Test"].Channel
+		assert.Equal(t, "greeting", testMetric.Name)
+		assert.Equal(t, map[string]string{"collector": "Test", "source": "Test"}, testMetric.Dimensions)
+	}()
+	readFromCollector(col, newHandlerSet([]handler.Handler{testHandler}))
+	wg.Wait()
+}
+
+func TestRunCollectorStop(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := collector.New("Test")
+	col.SetInterval(1)
+
+	done := make(chan bool)
+	go func() {
+		runCollector(col)
+		done <- true
+	}()
+	col.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runCollector should return once the collector is stopped")
+	}
+}
+
+func TestReadFromCollectorStop(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := collector.New("Test")
+	col.SetInterval(1)
+
+	collectorChannel := map[string]handler.CollectorEnd{
+		"Test": handler.CollectorEnd{Channel: make(chan metric.Metric, 1), BufferSize: 1},
+	}
+	testHandler := handler.New("Log")
+	testHandler.SetCollectorEndpoints(collectorChannel)
+
+	readers := readFromCollectors([]collector.Collector{col}, newHandlerSet([]handler.Handler{testHandler}))
+	col.Channel() <- metric.New("hello")
+	col.Stop()
+	readers.Wait()
+
+	select {
+	case m := <-collectorChannel["Test"].Channel:
+		assert.Equal(t, "hello", m.Name)
+	default:
+		t.Fatal("metric read before stopping should reach the handler")
+	}
+}
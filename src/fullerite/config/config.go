@@ -2,7 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,58 +20,122 @@ type Config struct {
 	Prefix                string                            `json:"prefix"`
 	Interval              interface{}                       `json:"interval"`
 	CollectorsConfigPath  string                            `json:"collectorsConfigPath"`
+	HandlersConfigPath    string                            `json:"handlersConfigPath"`
 	DiamondCollectorsPath string                            `json:"diamondCollectorsPath"`
 	DiamondCollectors     []string                          `json:"diamondCollectors"`
 	Handlers              map[string]map[string]interface{} `json:"handlers"`
 	Collectors            []string                          `json:"collectors"`
 	DefaultDimensions     map[string]string                 `json:"defaultDimensions"`
+	Processors            []map[string]interface{}          `json:"processors"`
 	InternalServerConfig  map[string]interface{}            `json:"internalServer"`
 }
 
 // ReadConfig reads a fullerite configuration file
 func ReadConfig(configFile string) (c Config, e error) {
 	log.Info("Reading configuration file at ", configFile)
-	contents, e := ioutil.ReadFile(configFile)
+	raw, e := ReadFile(configFile)
 	if e != nil {
 		log.Error("Config file error: ", e)
 		return c, e
 	}
-	err := json.Unmarshal(contents, &c)
-	if err != nil {
-		log.Error("Invalid JSON in config: ", err)
-		return c, err
+	c, e = Parse(raw)
+	if e != nil {
+		log.Error("Invalid config in ", configFile, ": ", e)
+		return c, e
 	}
 	return c, nil
 }
 
+// Parse builds the configuration out of the contents of a configuration
+// file and adds the handlers defined in HandlersConfigPath
+func Parse(raw map[string]interface{}) (c Config, e error) {
+	if e = remarshal(raw, &c); e != nil {
+		return c, e
+	}
+	if c.HandlersConfigPath != "" {
+		e = c.includeHandlers()
+	}
+	return c, e
+}
+
+// includeHandlers adds the handlers defined by the files of HandlersConfigPath,
+// each file defines one or more handlers the way the handlers section of the
+// configuration does. A handler can only be defined once.
+func (conf *Config) includeHandlers() error {
+	files, err := ioutil.ReadDir(conf.HandlersConfigPath)
+	if err != nil {
+		return err
+	}
+	if conf.Handlers == nil {
+		conf.Handlers = make(map[string]map[string]interface{})
+	}
+	for _, file := range files {
+		if file.IsDir() || !isConfigFile(file.Name()) {
+			continue
+		}
+		path := filepath.Join(conf.HandlersConfigPath, file.Name())
+		raw, err := ReadFile(path)
+		if err != nil {
+			return err
+		}
+		handlers := map[string]map[string]interface{}{}
+		if err = remarshal(raw, &handlers); err != nil {
+			return fmt.Errorf("invalid handlers in %s: %s", path, err)
+		}
+		for name, handlerConfig := range handlers {
+			if _, exists := conf.Handlers[name]; exists {
+				return fmt.Errorf("handler %s of %s is already defined", name, path)
+			}
+			conf.Handlers[name] = handlerConfig
+		}
+	}
+	return nil
+}
+
+func isConfigFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, configExt := range configExtensions {
+		if ext == configExt {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadCollectorConfig reads a fullerite collector configuration file
 func ReadCollectorConfig(configFile string) (c map[string]interface{}, e error) {
 	log.Info("Reading collector configuration file at ", configFile)
-	contents, e := ioutil.ReadFile(configFile)
+	c, e = ReadFile(configFile)
 	if e != nil {
 		log.Error("Config file error: ", e)
 		return c, e
 	}
-	err := json.Unmarshal(contents, &c)
-	if err != nil {
-		log.Error("Invalid JSON in config: ", err)
-		return c, err
-	}
 	return c, nil
 }
 
 // GetCollectorConfig returns collector config. given a name
 func (conf Config) GetCollectorConfig(name string) (map[string]interface{}, error) {
-	configFile := strings.Join([]string{conf.CollectorsConfigPath, name}, "/") + ".conf"
+	configFile := strings.Join([]string{conf.CollectorsConfigPath, name}, "/")
 	// Since collector naems can be defined with a space in order to instantiate multiple
 	// instances of the same collector, we want their files
 	// will not have that space and needs to have it replaced with an underscore
 	// instead
 	configFile = strings.Replace(configFile, " ", "_", -1)
-	collectorConf, err := ReadCollectorConfig(configFile)
+	collectorConf, err := ReadCollectorConfig(findConfigFile(configFile))
 	return collectorConf, err
 }
 
+// findConfigFile returns the first of base.conf, base.yaml, base.yml and
+// base.toml that exists, base.conf when none of them does
+func findConfigFile(base string) string {
+	for _, ext := range []string{".conf", ".yaml", ".yml", ".toml"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return base + ".conf"
+}
+
 // GetAsFloat parses a string to a float or returns the float if float is passed in
 func GetAsFloat(value interface{}, defaultValue float64) (result float64) {
 	result = defaultValue
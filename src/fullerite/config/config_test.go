@@ -3,8 +3,10 @@ package config_test
 import (
 	"fullerite/config"
 
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"encoding/json"
@@ -172,3 +174,131 @@ func TestParseBadConfig(t *testing.T) {
 	_, err := config.ReadConfig(tmpTestBadFile)
 	assert.NotNil(t, err, "should fail")
 }
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeConfigFile(t, dir, "fullerite.yaml", `
+# comments are fine
+prefix: test.
+interval: 10
+collectors: [Test]
+defaultDimensions:
+  application: fullerite
+handlers:
+  Graphite:
+    server: 10.40.11.51
+    port: 2003
+    collectorBlackList: [TestCollector1]
+`)
+	c, err := config.ReadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "test.", c.Prefix)
+	assert.Equal(t, 10.0, c.Interval)
+	assert.Equal(t, []string{"Test"}, c.Collectors)
+	assert.Equal(t, map[string]string{"application": "fullerite"}, c.DefaultDimensions)
+	assert.Equal(t, map[string]interface{}{
+		"server":             "10.40.11.51",
+		"port":               2003.0,
+		"collectorBlackList": []interface{}{"TestCollector1"},
+	}, c.Handlers["Graphite"])
+}
+
+func TestParseTOMLConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeConfigFile(t, dir, "fullerite.toml", `
+prefix = "test."
+interval = 10
+collectors = ["Test"]
+
+[handlers.Graphite]
+server = "10.40.11.51"
+port = 2003
+`)
+	c, err := config.ReadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "test.", c.Prefix)
+	assert.Equal(t, 10.0, c.Interval)
+	assert.Equal(t, map[string]interface{}{"server": "10.40.11.51", "port": 2003.0}, c.Handlers["Graphite"])
+
+	path = writeConfigFile(t, dir, "broken.toml", `prefix = `)
+	_, err = config.ReadConfig(path)
+	assert.Contains(t, err.Error(), "invalid TOML in "+path)
+}
+
+func TestConfigEnvInterpolation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("FULLERITE_TEST_SERVER", "graphite.local")
+	defer os.Unsetenv("FULLERITE_TEST_SERVER")
+	os.Unsetenv("FULLERITE_TEST_MISSING")
+
+	raw, err := config.ReadFile(writeConfigFile(t, dir, "graphite.conf", `{
+		"server": "${FULLERITE_TEST_SERVER}",
+		"port": "${FULLERITE_TEST_MISSING:-2003}",
+		"prefix": "$${FULLERITE_TEST_SERVER}.",
+		"defaultDimensions": {"host": "web-${FULLERITE_TEST_SERVER}"}
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "graphite.local", raw["server"])
+	assert.Equal(t, "2003", raw["port"])
+	assert.Equal(t, "${FULLERITE_TEST_SERVER}.", raw["prefix"])
+	assert.Equal(t, map[string]interface{}{"host": "web-graphite.local"}, raw["defaultDimensions"])
+
+	_, err = config.ReadFile(writeConfigFile(t, dir, "missing.yaml", `server: ${FULLERITE_TEST_MISSING}`))
+	assert.Contains(t, err.Error(), "environment variables not set: FULLERITE_TEST_MISSING")
+}
+
+func TestIncludeHandlers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	handlersDir := filepath.Join(dir, "handlers.d")
+	assert.Nil(t, os.Mkdir(handlersDir, 0755))
+	writeConfigFile(t, handlersDir, "graphite.yaml", "Graphite:\n  server: localhost\n  port: 2003\n")
+	writeConfigFile(t, handlersDir, "signalfx.conf", `{"SignalFx": {"authToken": "token"}}`)
+	writeConfigFile(t, handlersDir, "README", "not a config")
+
+	path := writeConfigFile(t, dir, "fullerite.conf", fmt.Sprintf(`{
+		"handlersConfigPath": %q,
+		"handlers": {"Log": {}}
+	}`, handlersDir))
+	c, err := config.ReadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(c.Handlers))
+	assert.Equal(t, "localhost", c.Handlers["Graphite"]["server"])
+	assert.Equal(t, "token", c.Handlers["SignalFx"]["authToken"])
+
+	writeConfigFile(t, handlersDir, "log.toml", "[Log]\n")
+	_, err = config.ReadConfig(path)
+	assert.Contains(t, err.Error(), "handler Log of "+handlersDir+"/log.toml is already defined")
+}
+
+func TestGetCollectorConfigYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, dir, "Test_other.yml", "metricName: other\ninterval: 5\n")
+	c := config.Config{CollectorsConfigPath: dir}
+	conf, err := c.GetCollectorConfig("Test other")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"metricName": "other", "interval": 5.0}, conf)
+
+	_, err = c.GetCollectorConfig("Missing")
+	assert.Contains(t, err.Error(), dir+"/Missing.conf")
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envReference matches ${NAME} and ${NAME:-default}, a reference
+// written $${NAME} is left alone as ${NAME}
+var envReference = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the environment variable references in all the
+// string values of raw, the variables that are not set and have no default
+// are reported in the error
+func interpolate(raw map[string]interface{}) error {
+	missing := map[string]bool{}
+	for k, v := range raw {
+		raw[k] = expandEnv(v, missing)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	names := []string{}
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("environment variables not set: %s", strings.Join(names, ", "))
+}
+
+func expandEnv(value interface{}, missing map[string]bool) interface{} {
+	switch realValue := value.(type) {
+	case string:
+		return envReference.ReplaceAllStringFunc(realValue, func(reference string) string {
+			groups := envReference.FindStringSubmatch(reference)
+			if groups[1] != "" {
+				return reference[1:]
+			}
+			if env, exists := os.LookupEnv(groups[2]); exists {
+				return env
+			}
+			if groups[3] != "" {
+				return groups[4]
+			}
+			missing[groups[2]] = true
+			return ""
+		})
+	case map[string]interface{}:
+		for k, v := range realValue {
+			realValue[k] = expandEnv(v, missing)
+		}
+	case []interface{}:
+		for i, v := range realValue {
+			realValue[i] = expandEnv(v, missing)
+		}
+	}
+	return value
+}
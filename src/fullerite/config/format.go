@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// The formats a configuration file can be written in
+const (
+	JSONFormat = "JSON"
+	YAMLFormat = "YAML"
+	TOMLFormat = "TOML"
+)
+
+// configExtensions are the extensions of the files read from a conf.d
+// directory, .conf files are JSON
+var configExtensions = []string{".conf", ".json", ".yaml", ".yml", ".toml"}
+
+// FormatOf tells the format of a configuration file from its extension,
+// .yaml and .yml files are YAML, .toml files TOML and anything else JSON
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAMLFormat
+	case ".toml":
+		return TOMLFormat
+	}
+	return JSONFormat
+}
+
+// ReadFile reads a JSON, YAML or TOML configuration file and expands the
+// ${ENV_VAR} references of its values. The values are returned with the
+// types they would have in JSON whatever the format, numbers are float64
+// and nested objects map[string]interface{}
+func ReadFile(path string) (map[string]interface{}, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := FormatOf(path)
+	raw, err := decodeConfig(contents, format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %s", format, path, err)
+	}
+	if err = interpolate(raw); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return raw, nil
+}
+
+func decodeConfig(contents []byte, format string) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	switch format {
+	case YAMLFormat:
+		var doc map[interface{}]interface{}
+		if err := yaml.Unmarshal(contents, &doc); err != nil {
+			return nil, err
+		}
+		return raw, remarshal(stringKeys(doc), &raw)
+	case TOMLFormat:
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(contents), &doc); err != nil {
+			return nil, err
+		}
+		return raw, remarshal(doc, &raw)
+	}
+	return raw, json.Unmarshal(contents, &raw)
+}
+
+// remarshal converts value to target through JSON, which gives YAML and
+// TOML documents the types they would have if they had been written in JSON
+func remarshal(value interface{}, target interface{}) error {
+	contents, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(contents, target)
+}
+
+// stringKeys turns the map[interface{}]interface{} the YAML decoder
+// produces into map[string]interface{} so that they can be marshalled
+func stringKeys(value interface{}) interface{} {
+	switch realValue := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(realValue))
+		for k, v := range realValue {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(realValue))
+		for i, v := range realValue {
+			list[i] = stringKeys(v)
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types an option can be declared with. Numbers are also accepted
+// as strings, and lists and maps as JSON strings, the same way
+// GetAsInt, GetAsFloat, GetAsSlice and GetAsMap read them. Strings,
+// including the ones of string lists and string maps, can be secret
+// references, see resolveSecret.
+const (
+	StringOption     = "string"
+	IntOption        = "int"
+	FloatOption      = "float"
+	BoolOption       = "bool"
+	ListOption       = "list"
+	StringListOption = "string list"
+	MapOption        = "map"
+	StringMapOption  = "string map"
+)
+
+// Option declares a key a collector or a handler understands in its config.
+// The Default is used when the key is not set, options whose default
+// comes from somewhere else, like the global interval, leave it nil
+type Option struct {
+	Name        string
+	Type        string
+	Default     interface{}
+	Required    bool
+	Description string
+}
+
+// Options are all the keys a collector or a handler understands
+type Options []Option
+
+// Values are the decoded options of a config. The getters return the zero
+// value of their type for the options that are not set and have no default
+type Values map[string]interface{}
+
+// Decode converts the values of configMap to the declared types and fills in
+// the defaults. The values that could be decoded are returned even when some
+// could not, those fall back to their default, and the error lists every
+// missing required key and invalid value. Keys that are not declared are ignored.
+func (opts Options) Decode(configMap map[string]interface{}) (Values, error) {
+	values, problems := opts.decode(configMap)
+	if len(problems) > 0 {
+		return values, errors.New(strings.Join(problems, "; "))
+	}
+	return values, nil
+}
+
+// JoinErrors merges the errors that are not nil into one, it returns nil
+// when there are none
+func JoinErrors(errs ...error) error {
+	messages := []string{}
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// Check returns what is wrong with configMap: missing required keys,
+// values of the wrong type and keys that are not declared
+func (opts Options) Check(configMap map[string]interface{}) []string {
+	_, problems := opts.decode(configMap)
+
+	declared := make(map[string]bool, len(opts))
+	for _, opt := range opts {
+		declared[opt.Name] = true
+	}
+	unknown := []string{}
+	for key := range configMap {
+		if !declared[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown key %q", key))
+	}
+	return problems
+}
+
+func (opts Options) decode(configMap map[string]interface{}) (Values, []string) {
+	values := make(Values, len(opts))
+	problems := []string{}
+	for _, opt := range opts {
+		if value, exists := configMap[opt.Name]; exists {
+			decoded, err := decodeValue(value, opt.Type)
+			if err == nil {
+				values[opt.Name] = decoded
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%q %s", opt.Name, err))
+		} else if opt.Required {
+			problems = append(problems, fmt.Sprintf("missing required key %q", opt.Name))
+		}
+
+		if opt.Default != nil {
+			if decoded, err := decodeValue(opt.Default, opt.Type); err == nil {
+				values[opt.Name] = decoded
+			}
+		}
+	}
+	return values, problems
+}
+
+// HasType tells whether value can be read as an option of the given type
+func HasType(value interface{}, optionType string) bool {
+	_, err := decodeValue(value, optionType)
+	return err == nil
+}
+
+// decodeValue converts value to the Go type of the option type:
+// string, int, float64, bool, []interface{}, []string,
+// map[string]interface{} or map[string]string
+func decodeValue(value interface{}, optionType string) (interface{}, error) {
+	wrongType := fmt.Errorf("should be of type %s, got %s", optionType, describe(value))
+
+	switch optionType {
+	case StringOption:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		if secret, isSecret, err := resolveSecret(value); isSecret {
+			return secret, err
+		}
+	case IntOption:
+		switch realValue := value.(type) {
+		case int:
+			return realValue, nil
+		case int32:
+			return int(realValue), nil
+		case int64:
+			return int(realValue), nil
+		case float64:
+			if realValue == float64(int64(realValue)) {
+				return int(realValue), nil
+			}
+		case string:
+			if parsed, err := strconv.ParseInt(realValue, 10, 64); err == nil {
+				return int(parsed), nil
+			}
+		}
+	case FloatOption:
+		switch realValue := value.(type) {
+		case int:
+			return float64(realValue), nil
+		case int32:
+			return float64(realValue), nil
+		case int64:
+			return float64(realValue), nil
+		case float64:
+			return realValue, nil
+		case string:
+			if parsed, err := strconv.ParseFloat(realValue, 64); err == nil {
+				return parsed, nil
+			}
+		}
+	case BoolOption:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case ListOption:
+		switch realValue := value.(type) {
+		case []interface{}:
+			return realValue, nil
+		case []string:
+			list := make([]interface{}, len(realValue))
+			for i, item := range realValue {
+				list[i] = item
+			}
+			return list, nil
+		case string:
+			var list []interface{}
+			if json.Unmarshal([]byte(realValue), &list) == nil {
+				return list, nil
+			}
+		}
+	case StringListOption:
+		list, err := decodeValue(value, ListOption)
+		if err != nil {
+			return nil, wrongType
+		}
+		strs := make([]string, 0, len(list.([]interface{})))
+		for _, item := range list.([]interface{}) {
+			if secret, isSecret, err := resolveSecret(item); isSecret {
+				if err != nil {
+					return nil, err
+				}
+				item = secret
+			}
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("should be of type %s, got a %s in the list", optionType, describe(item))
+			}
+			strs = append(strs, str)
+		}
+		return strs, nil
+	case MapOption:
+		switch realValue := value.(type) {
+		case map[string]interface{}:
+			return realValue, nil
+		case map[string]string:
+			m := make(map[string]interface{}, len(realValue))
+			for k, v := range realValue {
+				m[k] = v
+			}
+			return m, nil
+		case string:
+			var m map[string]interface{}
+			if json.Unmarshal([]byte(realValue), &m) == nil {
+				return m, nil
+			}
+		}
+	case StringMapOption:
+		m, err := decodeValue(value, MapOption)
+		if err != nil {
+			return nil, wrongType
+		}
+		strs := make(map[string]string, len(m.(map[string]interface{})))
+		for k, v := range m.(map[string]interface{}) {
+			if secret, isSecret, err := resolveSecret(v); isSecret {
+				if err != nil {
+					return nil, fmt.Errorf("for %q %s", k, err)
+				}
+				v = secret
+			}
+			str, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("should be of type %s, got a %s for %q", optionType, describe(v), k)
+			}
+			strs[k] = str
+		}
+		return strs, nil
+	default:
+		return nil, fmt.Errorf("has an unknown type %s", optionType)
+	}
+	return nil, wrongType
+}
+
+func describe(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+// Has tells whether the option is set or has a default
+func (v Values) Has(name string) bool {
+	_, exists := v[name]
+	return exists
+}
+
+// String returns the value of a string option
+func (v Values) String(name string) string {
+	str, _ := v[name].(string)
+	return str
+}
+
+// Int returns the value of an int option
+func (v Values) Int(name string) int {
+	i, _ := v[name].(int)
+	return i
+}
+
+// Float returns the value of a float option
+func (v Values) Float(name string) float64 {
+	f, _ := v[name].(float64)
+	return f
+}
+
+// Bool returns the value of a bool option
+func (v Values) Bool(name string) bool {
+	b, _ := v[name].(bool)
+	return b
+}
+
+// List returns the value of a list option
+func (v Values) List(name string) []interface{} {
+	list, _ := v[name].([]interface{})
+	return list
+}
+
+// StringList returns the value of a string list option
+func (v Values) StringList(name string) []string {
+	list, _ := v[name].([]string)
+	return list
+}
+
+// Map returns the value of a map option
+func (v Values) Map(name string) map[string]interface{} {
+	m, _ := v[name].(map[string]interface{})
+	return m
+}
+
+// StringMap returns the value of a string map option
+func (v Values) StringMap(name string) map[string]string {
+	m, _ := v[name].(map[string]string)
+	return m
+}
+
+// diamondKeys are read from the fullerite configuration by the diamond server
+var diamondKeys = []string{"fulleritePort", "defaultConfig"}
+
+// UnknownKeys lists the keys of a fullerite configuration that don't
+// match any of the fields of Config nor any of the diamond server settings
+func UnknownKeys(raw map[string]interface{}) []string {
+	known := map[string]bool{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		known[name] = true
+	}
+	for _, name := range diamondKeys {
+		known[name] = true
+	}
+
+	unknown := []string{}
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,158 @@
+package config_test
+
+import (
+	"fullerite/config"
+
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsCheck(t *testing.T) {
+	opts := config.Options{
+		{Name: "server", Type: config.StringOption, Required: true},
+		{Name: "port", Type: config.IntOption, Required: true},
+		{Name: "timeout", Type: config.FloatOption},
+		{Name: "enabled", Type: config.BoolOption},
+		{Name: "hosts", Type: config.ListOption},
+		{Name: "dimensions", Type: config.MapOption},
+	}
+
+	assert.Equal(t, []string{}, opts.Check(map[string]interface{}{
+		"server":     "localhost",
+		"port":       "2003",
+		"timeout":    2.5,
+		"enabled":    true,
+		"hosts":      `["a", "b"]`,
+		"dimensions": map[string]interface{}{"env": "dev"},
+	}))
+
+	assert.Equal(t, []string{
+		`missing required key "server"`,
+		`"port" should be of type int, got float64`,
+		`"enabled" should be of type bool, got string`,
+		`"hosts" should be of type list, got map[string]interface {}`,
+		`unknown key "prot"`,
+		`unknown key "severs"`,
+	}, opts.Check(map[string]interface{}{
+		"port":    2003.5,
+		"enabled": "true",
+		"hosts":   map[string]interface{}{},
+		"severs":  "localhost",
+		"prot":    2003,
+	}))
+}
+
+func TestHasType(t *testing.T) {
+	assert.True(t, config.HasType(10.0, config.IntOption))
+	assert.True(t, config.HasType("10", config.IntOption))
+	assert.False(t, config.HasType("ten", config.IntOption))
+	assert.True(t, config.HasType(10, config.FloatOption))
+	assert.True(t, config.HasType("0.5", config.FloatOption))
+	assert.True(t, config.HasType([]string{"a"}, config.ListOption))
+	assert.False(t, config.HasType(`{"a": "b"}`, config.ListOption))
+	assert.True(t, config.HasType(`{"a": "b"}`, config.MapOption))
+	assert.False(t, config.HasType(nil, config.StringOption))
+	assert.False(t, config.HasType("a", "unknown"))
+}
+
+func TestUnknownKeys(t *testing.T) {
+	unknown := config.UnknownKeys(map[string]interface{}{
+		"prefix":        "test.",
+		"fulleritePort": 19191,
+		"colectors":     []interface{}{},
+		"handler":       map[string]interface{}{},
+	})
+	assert.Equal(t, []string{"colectors", "handler"}, unknown)
+}
+
+func TestOptionsDecode(t *testing.T) {
+	opts := config.Options{
+		{Name: "server", Type: config.StringOption, Required: true},
+		{Name: "port", Type: config.IntOption, Default: 2003},
+		{Name: "timeout", Type: config.FloatOption},
+		{Name: "hosts", Type: config.StringListOption},
+		{Name: "dimensions", Type: config.StringMapOption},
+	}
+
+	values, err := opts.Decode(map[string]interface{}{
+		"server":     "localhost",
+		"timeout":    "1.5",
+		"hosts":      []interface{}{"a", "b"},
+		"dimensions": `{"env": "dev"}`,
+		"unknown":    true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", values.String("server"))
+	assert.Equal(t, 2003, values.Int("port"))
+	assert.Equal(t, 1.5, values.Float("timeout"))
+	assert.Equal(t, []string{"a", "b"}, values.StringList("hosts"))
+	assert.Equal(t, map[string]string{"env": "dev"}, values.StringMap("dimensions"))
+	assert.False(t, values.Has("unknown"))
+
+	values, err = opts.Decode(map[string]interface{}{
+		"port":       "http",
+		"hosts":      []interface{}{"a", 1.0},
+		"dimensions": map[string]interface{}{"env": 1.0},
+	})
+	assert.Equal(t, `missing required key "server"; `+
+		`"port" should be of type int, got string; `+
+		`"hosts" should be of type string list, got a float64 in the list; `+
+		`"dimensions" should be of type string map, got a float64 for "env"`, err.Error())
+	assert.Equal(t, 2003, values.Int("port"), "invalid values fall back to their default")
+	assert.False(t, values.Has("server"))
+	assert.False(t, values.Has("hosts"))
+}
+
+func TestJoinErrors(t *testing.T) {
+	assert.Nil(t, config.JoinErrors())
+	assert.Nil(t, config.JoinErrors(nil, nil))
+	assert.Equal(t, "a; b", config.JoinErrors(errors.New("a"), nil, errors.New("b")).Error())
+}
+
+func TestSecretReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	secretFile := filepath.Join(dir, "token")
+	assert.Nil(t, ioutil.WriteFile(secretFile, []byte("file-secret\n"), 0600))
+	os.Setenv("FULLERITE_TEST_SECRET", "env-secret")
+	defer os.Unsetenv("FULLERITE_TEST_SECRET")
+	os.Unsetenv("FULLERITE_TEST_MISSING")
+
+	opts := config.Options{
+		{Name: "authToken", Type: config.StringOption},
+		{Name: "apiKey", Type: config.StringOption},
+		{Name: "tokens", Type: config.StringMapOption},
+		{Name: "keys", Type: config.StringListOption},
+	}
+	values, err := opts.Decode(map[string]interface{}{
+		"authToken": map[string]interface{}{"file": secretFile},
+		"apiKey":    map[string]interface{}{"env": "FULLERITE_TEST_SECRET"},
+		"tokens": map[string]interface{}{
+			"a": map[string]interface{}{"env": "FULLERITE_TEST_SECRET"},
+			"b": "plain",
+		},
+		"keys": []interface{}{map[string]interface{}{"file": secretFile}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "file-secret", values.String("authToken"))
+	assert.Equal(t, "env-secret", values.String("apiKey"))
+	assert.Equal(t, map[string]string{"a": "env-secret", "b": "plain"}, values.StringMap("tokens"))
+	assert.Equal(t, []string{"file-secret"}, values.StringList("keys"))
+
+	_, err = opts.Decode(map[string]interface{}{
+		"authToken": map[string]interface{}{"file": filepath.Join(dir, "missing")},
+		"apiKey":    map[string]interface{}{"env": "FULLERITE_TEST_MISSING"},
+		"tokens":    map[string]interface{}{"a": map[string]interface{}{"env": 1.0}},
+	})
+	assert.Equal(t, fmt.Sprintf(`"authToken" refers to a secret file that cannot be read: open %s/missing: no such file or directory; `, dir)+
+		`"apiKey" refers to the environment variable FULLERITE_TEST_MISSING which is not set; `+
+		`"tokens" for "a" should name the secret environment variable with a string, got float64`, err.Error())
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// The keys of a secret reference: {"file": "/etc/fullerite/token"} is replaced
+// by the contents of the file and {"env": "TOKEN"} by the value of the
+// environment variable, wherever a string option is expected
+const (
+	SecretFileKey = "file"
+	SecretEnvKey  = "env"
+)
+
+// resolveSecret reads the value a secret reference points to, isSecret tells
+// whether value is a reference at all. The errors never contain the secret.
+func resolveSecret(value interface{}) (secret string, isSecret bool, err error) {
+	reference, ok := value.(map[string]interface{})
+	if !ok || len(reference) != 1 {
+		return "", false, nil
+	}
+
+	if path, exists := reference[SecretFileKey]; exists {
+		name, ok := path.(string)
+		if !ok {
+			return "", true, fmt.Errorf("should name the secret file with a string, got %s", describe(path))
+		}
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", true, fmt.Errorf("refers to a secret file that cannot be read: %s", err)
+		}
+		// editors and config management like to end files with a newline
+		return strings.TrimSpace(string(contents)), true, nil
+	}
+
+	if env, exists := reference[SecretEnvKey]; exists {
+		name, ok := env.(string)
+		if !ok {
+			return "", true, fmt.Errorf("should name the secret environment variable with a string, got %s", describe(env))
+		}
+		secret, exists := os.LookupEnv(name)
+		if !exists {
+			return "", true, fmt.Errorf("refers to the environment variable %s which is not set", name)
+		}
+		return secret, true, nil
+	}
+	return "", false, nil
+}
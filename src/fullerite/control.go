@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fullerite/internalserver"
+
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// flushTimeout is how long a flush requested through the
+// control API waits for each channel of the handler
+const flushTimeout = 5 * time.Second
+
+// controller carries out the control API requests
+// on the running collectors and handlers
+type controller struct {
+	handlers *handlerSet
+	levels   *componentLevels
+}
+
+func (c controller) PauseCollector(name string) error {
+	return pauseCollector(name, true)
+}
+
+func (c controller) ResumeCollector(name string) error {
+	return pauseCollector(name, false)
+}
+
+func (c controller) Collect(name string) error {
+	return triggerCollection(name)
+}
+
+func (c controller) FlushHandler(name string) error {
+	return c.handlers.flushHandler(name, flushTimeout)
+}
+
+func (c controller) SetLogLevel(kind string, name string, level string, duration time.Duration) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("can't log at level %q", level)
+	}
+
+	running := false
+	switch kind {
+	case "collector":
+		_, running = scheduleStats()[name]
+	case "handler":
+		for _, h := range c.handlers.List() {
+			running = running || h.CanonicalName() == name
+		}
+	}
+	if !running {
+		return internalserver.ErrUnknownComponent
+	}
+	c.levels.set(kind, name, parsed, duration)
+	return nil
+}
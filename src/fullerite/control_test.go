@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/handler"
+	"fullerite/internalserver"
+	"fullerite/metric"
+	"fullerite/test_utils"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingCollector tells every time it collects
+type countingCollector struct {
+	collector.Collector
+	collected chan bool
+}
+
+func (c countingCollector) Collect(ctx context.Context) {
+	c.collected <- true
+}
+
+func TestControlCollectorOnDemand(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := collector.New("Test")
+	col.SetInterval(60)
+	counting := countingCollector{col, make(chan bool, 1)}
+	c := controller{newHandlerSet(nil), nil}
+
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.Collect("Test"))
+
+	// as startCollector does
+	addCollectorSchedule(counting)
+	done := make(chan bool)
+	go func() {
+		runCollector(counting)
+		done <- true
+	}()
+	for i := 0; i < 100 && c.Collect("Test") != nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-counting.collected:
+	case <-time.After(time.Second):
+		t.Error("the collector should collect on demand")
+	}
+
+	assert.Nil(t, c.PauseCollector("Test"))
+	assert.True(t, collectorPaused("Test"))
+	assert.Equal(t, 1.0, scheduleStats()["Test"].Gauges["fullerite.collector_paused"])
+	assert.Nil(t, c.ResumeCollector("Test"))
+	assert.False(t, collectorPaused("Test"))
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.PauseCollector("Missing"))
+
+	col.Stop()
+	<-done
+}
+
+func TestControlFlushHandler(t *testing.T) {
+	h := handler.NewTest(make(chan metric.Metric), 10, 10, time.Second, test_utils.BuildLogger())
+	h.SetCanonicalName("Test other")
+	c := controller{newHandlerSet([]handler.Handler{h}), nil}
+
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.FlushHandler("Missing"))
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.FlushHandler("Test"), "handlers are named as configured")
+
+	received := make(chan metric.Metric, 1)
+	go func() { received <- <-h.Channel() }()
+	assert.Nil(t, c.FlushHandler("Test other"))
+	m := <-received
+	assert.True(t, m.Sentinel(), "the handler should be sent a sentinel")
+
+	// nobody reads the channel anymore
+	err := c.handlers.flushHandler("Test other", 10*time.Millisecond)
+	assert.NotNil(t, err)
+}
+
+func TestControlSetLogLevel(t *testing.T) {
+	h := handler.NewTest(make(chan metric.Metric), 10, 10, time.Second, test_utils.BuildLogger())
+	h.SetCanonicalName("Test other")
+	logger := logrus.New()
+	c := controller{newHandlerSet([]handler.Handler{h}), newComponentLevels(logger)}
+
+	assert.NotNil(t, c.SetLogLevel("handler", "Test other", "chatty", time.Minute))
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.SetLogLevel("collector", "Missing", "debug", time.Minute))
+	assert.Equal(t, internalserver.ErrUnknownComponent, c.SetLogLevel("handler", "Test", "debug", time.Minute))
+	assert.Nil(t, c.SetLogLevel("handler", "Test other", "debug", time.Minute))
+	assert.Equal(t, logrus.DebugLevel, logger.Level)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/config"
+	"fullerite/handler"
+	"fullerite/metric"
+
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// daemon keeps track of the collectors and handlers that are running
+// so that a new configuration can be applied without restarting fullerite.
+// Only what changed between two configurations is stopped or started.
+type daemon struct {
+	mu         sync.Mutex
+	configFile string
+	config     config.Config
+
+	collectors        map[string]*runningCollector
+	handlers          map[string]handler.Handler
+	handlerSet        *handlerSet
+	collectorStatChan chan<- metric.CollectorEmission
+
+	// set to 1 once the collectors and handlers of the
+	// configuration started, until they are stopped
+	started int32
+}
+
+type runningCollector struct {
+	collector collector.Collector
+	config    map[string]interface{}
+
+	// closed once the collector's reader passed on its last metric
+	done chan struct{}
+}
+
+func newDaemon(configFile string, collectorStatChan chan<- metric.CollectorEmission) *daemon {
+	return &daemon{
+		configFile:        configFile,
+		collectors:        make(map[string]*runningCollector),
+		handlers:          make(map[string]handler.Handler),
+		handlerSet:        newHandlerSet(nil),
+		collectorStatChan: collectorStatChan,
+	}
+}
+
+// reload reads the configuration file again and applies it
+func (d *daemon) reload() error {
+	log.Info("Reloading configuration from ", d.configFile)
+	c, err := config.ReadConfig(d.configFile)
+	if err != nil {
+		return err
+	}
+	d.apply(c)
+	return nil
+}
+
+// apply brings the running collectors and handlers in line with the config.
+// Collectors that are gone or changed are stopped first, their readers pass on
+// what they have left before the handlers are swapped and their collector
+// endpoints rewired, then the new collectors are started.
+func (d *daemon) apply(c config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	collectorConfigs := make(map[string]map[string]interface{})
+	for _, name := range c.Collectors {
+		conf, err := c.GetCollectorConfig(name)
+		if err != nil {
+			log.Error("Collector config failed to load for: ", name)
+			// leave it alone rather than stopping it over a broken file
+			if running, exists := d.collectors[name]; exists {
+				collectorConfigs[name] = running.config
+			}
+			continue
+		}
+		collectorConfigs[name] = conf
+	}
+
+	paused := d.stopCollectors(c, collectorConfigs)
+	d.swapHandlers(c)
+	d.startCollectors(c, collectorConfigs, paused)
+	d.config = c
+	atomic.StoreInt32(&d.started, 1)
+}
+
+// ready tells whether the collectors and handlers have started
+func (d *daemon) ready() bool {
+	return atomic.LoadInt32(&d.started) == 1
+}
+
+// stopCollectors stops the collectors that are gone or changed and returns
+// the names of those that were paused through the control API
+func (d *daemon) stopCollectors(c config.Config, collectorConfigs map[string]map[string]interface{}) map[string]bool {
+	globalsChanged := config.GetAsInt(d.config.Interval, collector.DefaultCollectionInterval) !=
+		config.GetAsInt(c.Interval, collector.DefaultCollectionInterval) ||
+		!reflect.DeepEqual(d.config.Processors, c.Processors)
+
+	stopped := []*runningCollector{}
+	paused := make(map[string]bool)
+	for name, running := range d.collectors {
+		conf, exists := collectorConfigs[name]
+		if exists && !globalsChanged && reflect.DeepEqual(conf, running.config) {
+			continue
+		}
+		log.Info("Stopping collector ", name)
+		if collectorScheduleOf(running.collector).isPaused() {
+			paused[name] = true
+		}
+		running.collector.Stop()
+		stopped = append(stopped, running)
+		delete(d.collectors, name)
+	}
+	for _, running := range stopped {
+		<-running.done
+	}
+	return paused
+}
+
+// swapHandlers stops the handlers that are gone or changed before it creates
+// their replacements, so that a replacement doesn't share the spool of the
+// handler it replaces while that one flushes. The collectors don't write to
+// a changed handler until its replacement starts.
+func (d *daemon) swapHandlers(c config.Config) {
+	globalsChanged := config.GetAsInt(d.config.Interval, handler.DefaultInterval) !=
+		config.GetAsInt(c.Interval, handler.DefaultInterval) ||
+		d.config.Prefix != c.Prefix ||
+		!reflect.DeepEqual(d.config.DefaultDimensions, c.DefaultDimensions)
+
+	stopped := []handler.Handler{}
+	d.handlerSet.Lock()
+	for name, h := range d.handlers {
+		conf, exists := c.Handlers[name]
+		if exists && !globalsChanged && reflect.DeepEqual(conf, d.config.Handlers[name]) {
+			h.ReloadListeners(c)
+			continue
+		}
+		log.Info("Stopping handler ", name)
+		stopped = append(stopped, h)
+		delete(d.handlers, name)
+	}
+	d.setHandlers()
+	d.handlerSet.Unlock()
+
+	// the writers still holding an endpoint of the handlers that were
+	// taken out give up once they stop
+	stopHandlers(stopped)
+
+	d.handlerSet.Lock()
+	defer d.handlerSet.Unlock()
+	for name, conf := range c.Handlers {
+		if _, exists := d.handlers[name]; exists {
+			continue
+		}
+		log.Info("Starting handler ", name)
+		if h := createHandler(name, c, conf); h != nil {
+			go h.Run()
+			d.handlers[name] = h
+		}
+	}
+	d.setHandlers()
+}
+
+// setHandlers has the collectors write to the running handlers,
+// it is called holding the lock of the handler set
+func (d *daemon) setHandlers() {
+	names := []string{}
+	for name := range d.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	d.handlerSet.handlers = nil
+	for _, name := range names {
+		d.handlerSet.handlers = append(d.handlerSet.handlers, d.handlers[name])
+	}
+}
+
+// startCollectors starts the collectors that aren't running, those
+// replacing a paused collector start paused
+func (d *daemon) startCollectors(c config.Config, collectorConfigs map[string]map[string]interface{}, paused map[string]bool) {
+	for _, name := range c.Collectors {
+		conf, exists := collectorConfigs[name]
+		if !exists {
+			continue
+		}
+		if _, running := d.collectors[name]; running {
+			continue
+		}
+
+		collectorInst := startCollector(name, c, conf)
+		if collectorInst == nil {
+			continue
+		}
+		if paused[name] {
+			collectorScheduleOf(collectorInst).pause(true)
+		}
+		running := &runningCollector{collectorInst, conf, make(chan struct{})}
+		d.collectors[name] = running
+
+		statChan := forwardCollectorStats(d.collectorStatChan)
+		go func() {
+			defer close(running.done)
+			readFromCollector(running.collector, d.handlerSet, statChan)
+		}()
+	}
+}
+
+// stop stops the collectors, waits for the metrics they produced
+// to reach the handlers and then has the handlers flush their buffers.
+func (d *daemon) stop() {
+	atomic.StoreInt32(&d.started, 0)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	collectors := []collector.Collector{}
+	for _, running := range d.collectors {
+		collectors = append(collectors, running.collector)
+	}
+	stopCollectors(collectors)
+	for _, running := range d.collectors {
+		<-running.done
+	}
+	stopHandlers(d.handlerSet.List())
+	log.Info("fullerite stopped")
+}
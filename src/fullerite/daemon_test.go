@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/config"
+	"fullerite/handler"
+	"fullerite/metric"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCollectorConfig(t *testing.T, dir, name, contents string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name+".conf"), []byte(contents), 0644)
+	assert.Nil(t, err)
+}
+
+func drainCollectorStats() chan<- metric.CollectorEmission {
+	collectorStatChan := make(chan metric.CollectorEmission)
+	go func() {
+		for range collectorStatChan {
+		}
+	}()
+	return collectorStatChan
+}
+
+func TestDaemonApplyConfigChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "first"}`)
+	writeCollectorConfig(t, dir, "Test_other", `{"metricName": "other"}`)
+	writeCollectorConfig(t, dir, "Test_new", `{"metricName": "new"}`)
+
+	c := config.Config{
+		Interval:             10,
+		CollectorsConfigPath: dir,
+		Collectors:           []string{"Test", "Test other"},
+		Handlers: map[string]map[string]interface{}{
+			"Log": map[string]interface{}{},
+		},
+	}
+	d := newDaemon("", drainCollectorStats())
+	d.apply(c)
+	defer d.stop()
+
+	assert.Equal(t, 2, len(d.collectors))
+	changed := d.collectors["Test"].collector
+	removed := d.collectors["Test other"].collector
+	untouched := d.handlers["Log"]
+	assert.Equal(t, 2, len(untouched.CollectorEndpoints()))
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "second"}`)
+	c.Collectors = []string{"Test", "Test new"}
+	c.Handlers = map[string]map[string]interface{}{
+		"Log":       map[string]interface{}{},
+		"Log added": map[string]interface{}{},
+	}
+	d.apply(c)
+
+	assert.Equal(t, 2, len(d.collectors))
+	assert.NotEqual(t, changed, d.collectors["Test"].collector)
+	assert.Contains(t, d.collectors, "Test new")
+	assert.NotContains(t, d.collectors, "Test other")
+	for _, stopped := range []collector.Collector{changed, removed} {
+		select {
+		case <-stopped.StopChannel():
+		default:
+			t.Error("Expected ", stopped, " to be stopped")
+		}
+	}
+
+	assert.True(t, untouched == d.handlers["Log"], "an unchanged handler should keep running")
+	assert.Equal(t, 2, len(d.handlerSet.List()))
+	endpoints := untouched.CollectorEndpoints()
+	assert.Equal(t, 2, len(endpoints))
+	assert.Contains(t, endpoints, "Test")
+	assert.Contains(t, endpoints, "Test new")
+}
+
+func TestDaemonReloadKeepsEntriesOfReplacedCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "first"}`)
+	c := config.Config{
+		CollectorsConfigPath: dir,
+		Collectors:           []string{"Test"},
+	}
+	d := newDaemon("", drainCollectorStats())
+	d.apply(c)
+	defer d.stop()
+	assert.Nil(t, pauseCollector("Test", true))
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "second"}`)
+	d.apply(c)
+	replacement := d.collectors["Test"].collector
+	// the collector that was replaced is done with its entries by then
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NotNil(t, collectorScheduleOf(replacement))
+	assert.NotNil(t, collectorHealthOf(replacement))
+	assert.Contains(t, scheduleStats(), "Test")
+	assert.Contains(t, healthStats(), "Test")
+	assert.Nil(t, triggerCollection("Test"), "the replacement should collect on demand")
+	assert.True(t, collectorPaused("Test"), "the replacement of a paused collector should be paused")
+}
+
+// countedHandler keeps count of the instances of the handler that run
+type countedHandler struct {
+	handler.Handler
+	running *int32
+}
+
+func (h countedHandler) Stop() {
+	h.Handler.Stop()
+	atomic.AddInt32(h.running, -1)
+}
+
+func TestDaemonStopsChangedHandlerBeforeCreatingItsReplacement(t *testing.T) {
+	var running, overlaps int32
+	handler.RegisterHandler("Counted", func(channel chan metric.Metric, interval, bufferSize int,
+		timeout time.Duration, log *l.Entry) handler.Handler {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		return countedHandler{handler.NewTest(channel, interval, bufferSize, timeout, log), &running}
+	})
+
+	c := config.Config{Handlers: map[string]map[string]interface{}{
+		"Counted": map[string]interface{}{"max_buffer_size": 10},
+	}}
+	d := newDaemon("", drainCollectorStats())
+	d.apply(c)
+	defer d.stop()
+
+	c.Handlers = map[string]map[string]interface{}{
+		"Counted": map[string]interface{}{"max_buffer_size": 20},
+	}
+	d.apply(c)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&running))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlaps), "the replacement should be created once the handler it replaces stopped")
+}
+
+func TestDaemonKeepsCollectorWithBrokenConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": "first"}`)
+	c := config.Config{
+		CollectorsConfigPath: dir,
+		Collectors:           []string{"Test"},
+	}
+	d := newDaemon("", drainCollectorStats())
+	d.apply(c)
+	defer d.stop()
+	running := d.collectors["Test"].collector
+
+	writeCollectorConfig(t, dir, "Test", `{"metricName": `)
+	d.apply(c)
+	assert.True(t, running == d.collectors["Test"].collector)
+}
+
+func TestDaemonReloadMissingConfig(t *testing.T) {
+	d := newDaemon("/tmp/fullerite-does-not-exist.conf", drainCollectorStats())
+	assert.NotNil(t, d.reload())
+	assert.Equal(t, 0, len(d.collectors))
+}
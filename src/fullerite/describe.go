@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/config"
+	"fullerite/handler"
+
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+)
+
+func list(ctx *cli.Context) {
+	listComponents(os.Stdout)
+}
+
+func describe(ctx *cli.Context) {
+	if len(ctx.Args()) == 0 {
+		fmt.Fprintln(os.Stderr, "You need to name a collector or a handler, see 'fullerite list'")
+		os.Exit(1)
+	}
+	for i, name := range ctx.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		if !describeComponent(os.Stdout, name) {
+			fmt.Fprintf(os.Stderr, "%s is neither a collector nor a handler, see 'fullerite list'\n", name)
+			os.Exit(1)
+		}
+	}
+}
+
+// listComponents prints the registered collectors along with their type,
+// listener or collector, and the registered handlers
+func listComponents(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Collectors:")
+	for _, name := range collector.Names() {
+		fmt.Fprintf(w, "  %s\t%s\n", name, collector.New(name).CollectorType())
+	}
+	fmt.Fprintln(w, "Handlers:")
+	for _, name := range handler.Names() {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+	w.Flush()
+}
+
+// describeComponent prints the options of the collectors and handlers called
+// name, and the metrics of the collector. It returns false when there are none.
+func describeComponent(out io.Writer, name string) bool {
+	found := false
+	if inst := findCollector(name); inst != nil {
+		found = true
+		fmt.Fprintf(out, "collector %s (%s type)\n\n", name, inst.CollectorType())
+		printOptions(out, inst.Options())
+		fmt.Fprintln(out)
+		printMetrics(out, inst.Metrics())
+	}
+	if inst := findHandler(name); inst != nil {
+		if found {
+			fmt.Fprintln(out)
+		}
+		found = true
+		fmt.Fprintf(out, "handler %s\n\n", name)
+		printOptions(out, inst.Options())
+	}
+	return found
+}
+
+// findCollector only creates registered collectors, collector.New
+// complains about the others
+func findCollector(name string) collector.Collector {
+	for _, registered := range collector.Names() {
+		if registered == name {
+			return collector.New(name)
+		}
+	}
+	return nil
+}
+
+func findHandler(name string) handler.Handler {
+	for _, registered := range handler.Names() {
+		if registered == name {
+			return handler.New(name)
+		}
+	}
+	return nil
+}
+
+func printOptions(out io.Writer, options config.Options) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Options:")
+	fmt.Fprintln(w, "  NAME\tTYPE\tDEFAULT\tREQUIRED\tDESCRIPTION")
+	for _, opt := range options {
+		defaultValue := ""
+		if opt.Default != nil {
+			defaultValue = fmt.Sprint(opt.Default)
+		}
+		required := ""
+		if opt.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", opt.Name, opt.Type, defaultValue, required, opt.Description)
+	}
+	w.Flush()
+}
+
+func printMetrics(out io.Writer, metrics []collector.MetricDescription) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Metrics:")
+	fmt.Fprintln(w, "  NAME\tTYPE\tDIMENSIONS\tDESCRIPTION")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", m.Name, m.Type, strings.Join(m.Dimensions, ", "), m.Description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListComponents(t *testing.T) {
+	out := new(bytes.Buffer)
+	listComponents(out)
+
+	assert.Regexp(t, `(?m)^Collectors:\n`, out.String())
+	assert.Regexp(t, `(?m)^  Diamond +listener$`, out.String())
+	assert.Regexp(t, `(?m)^  DockerStats +collector$`, out.String())
+	assert.Regexp(t, `(?m)^Handlers:\n(  \w+\n)*  Graphite\n`, out.String())
+}
+
+func TestDescribeComponent(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.True(t, describeComponent(out, "Test"))
+	assert.Regexp(t, `^collector Test \(collector type\)\n`, out.String())
+	assert.Regexp(t, `(?m)^  metricName +string +TestMetric +name of the random metric$`, out.String())
+	assert.Regexp(t, `(?m)^  <metricName> +gauge +testing +a random value$`, out.String())
+
+	out.Reset()
+	assert.True(t, describeComponent(out, "Graphite"))
+	assert.Regexp(t, `^handler Graphite\n`, out.String())
+	assert.Regexp(t, `(?m)^  server +string +yes +host of the Graphite server$`, out.String())
+	assert.NotContains(t, out.String(), "Metrics:")
+
+	out.Reset()
+	assert.False(t, describeComponent(out, "Graphite other"))
+	assert.Equal(t, "", out.String())
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"fullerite/config"
+	"fullerite/metric"
+
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The functions an aggregation can emit, each one is
+// emitted as the metric name followed by the function
+const (
+	AggregateSum   = "sum"
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+)
+
+// aggregation rolls up the metrics whose name matches into one series per
+// combination of the groupBy dimensions
+type aggregation struct {
+	only      *regexp.Regexp
+	groupBy   []string
+	functions []string
+	dropRaw   bool
+}
+
+// aggregationOptions are the keys of an aggregation
+var aggregationOptions = config.Options{
+	{Name: "metric", Type: config.StringOption},
+	{Name: "groupBy", Type: config.StringListOption},
+	{Name: "functions", Type: config.StringListOption, Default: []string{AggregateSum}},
+	{Name: "dropRaw", Type: config.BoolOption},
+}
+
+// newAggregation builds an aggregation from its config, e.g.
+// {"metric": "^DockerCpu", "groupBy": ["service"], "functions": ["sum"], "dropRaw": true}
+func newAggregation(c map[string]interface{}) (aggregation, error) {
+	values, err := aggregationOptions.Decode(c)
+	agg := aggregation{
+		only:      regexp.MustCompile(""),
+		groupBy:   values.StringList("groupBy"),
+		functions: values.StringList("functions"),
+		dropRaw:   values.Bool("dropRaw"),
+	}
+	if err != nil {
+		return agg, err
+	}
+
+	if values.Has("metric") {
+		only, err := regexp.Compile(values.String("metric"))
+		if err != nil {
+			return agg, fmt.Errorf("metric: %s", err)
+		}
+		agg.only = only
+	}
+
+	if agg.groupBy == nil {
+		agg.groupBy = []string{}
+	}
+	sort.Strings(agg.groupBy)
+
+	if len(agg.functions) == 0 {
+		return agg, fmt.Errorf("functions should list at least one function")
+	}
+	for _, f := range agg.functions {
+		switch f {
+		case AggregateSum, AggregateAvg, AggregateMin, AggregateMax, AggregateCount:
+		default:
+			return agg, fmt.Errorf("unknown function %q", f)
+		}
+	}
+	return agg, nil
+}
+
+// configureAggregations parses the "aggregations" list of a handler config,
+// the aggregations that are misconfigured are left out and reported in the error
+func (base *BaseHandler) configureAggregations(list []interface{}) error {
+	errs := []error{}
+	base.aggregations = []aggregation{}
+	for i, item := range list {
+		c, _ := item.(map[string]interface{})
+		agg, err := newAggregation(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid aggregation %d: %s", i, err))
+			continue
+		}
+		base.aggregations = append(base.aggregations, agg)
+	}
+	return config.JoinErrors(errs...)
+}
+
+// aggregator keeps the series being aggregated by a handler listener
+// until the next flush
+type aggregator struct {
+	aggregations []aggregation
+	groups       map[string]*aggregateGroup
+	order        []string
+}
+
+type aggregateGroup struct {
+	name       string
+	dimensions map[string]string
+	functions  []string
+	sum        float64
+	min        float64
+	max        float64
+	count      int
+}
+
+func newAggregator(aggregations []aggregation) *aggregator {
+	if len(aggregations) == 0 {
+		return nil
+	}
+	return &aggregator{
+		aggregations: aggregations,
+		groups:       make(map[string]*aggregateGroup),
+	}
+}
+
+// add feeds the metric to the aggregations it matches,
+// it returns false when the raw metric should be dropped
+func (a *aggregator) add(m metric.Metric) bool {
+	if math.IsNaN(m.Value) {
+		return true
+	}
+
+	keep := true
+	for i, agg := range a.aggregations {
+		if !agg.only.MatchString(m.Name) {
+			continue
+		}
+		if agg.dropRaw {
+			keep = false
+		}
+
+		dimensions := make(map[string]string, len(agg.groupBy))
+		key := []string{fmt.Sprint(i), m.Name}
+		for _, d := range agg.groupBy {
+			if value, ok := m.GetDimensionValue(d); ok {
+				dimensions[d] = value
+				key = append(key, d+"="+value)
+			}
+		}
+
+		groupKey := strings.Join(key, "\x00")
+		group, exists := a.groups[groupKey]
+		if !exists {
+			group = &aggregateGroup{
+				name:       m.Name,
+				dimensions: dimensions,
+				functions:  agg.functions,
+				min:        m.Value,
+				max:        m.Value,
+			}
+			a.groups[groupKey] = group
+			a.order = append(a.order, groupKey)
+		}
+		group.sum += m.Value
+		group.min = math.Min(group.min, m.Value)
+		group.max = math.Max(group.max, m.Value)
+		group.count++
+	}
+	return keep
+}
+
+// flush returns the aggregated metrics and starts over
+func (a *aggregator) flush() []metric.Metric {
+	now := time.Now()
+	metrics := []metric.Metric{}
+	for _, key := range a.order {
+		group := a.groups[key]
+		for _, f := range group.functions {
+			var value float64
+			switch f {
+			case AggregateSum:
+				value = group.sum
+			case AggregateAvg:
+				value = group.sum / float64(group.count)
+			case AggregateMin:
+				value = group.min
+			case AggregateMax:
+				value = group.max
+			case AggregateCount:
+				value = float64(group.count)
+			}
+
+			m := metric.WithValue(group.name+"."+f, value)
+			m.AddDimensions(group.dimensions)
+			m.Timestamp = now
+			metrics = append(metrics, m)
+		}
+	}
+
+	a.groups = make(map[string]*aggregateGroup)
+	a.order = nil
+	return metrics
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"sync"
+	"testing"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildAggregationTestMetric(name string, value float64, dimensions map[string]string) metric.Metric {
+	m := metric.WithValue(name, value)
+	m.AddDimensions(dimensions)
+	return m
+}
+
+func TestAggregator(t *testing.T) {
+	agg, err := newAggregation(map[string]interface{}{
+		"metric":    "^cpu",
+		"groupBy":   []interface{}{"service"},
+		"functions": []interface{}{"sum", "avg", "min", "max", "count"},
+	})
+	assert.Nil(t, err)
+	a := newAggregator([]aggregation{agg})
+
+	assert.True(t, a.add(buildAggregationTestMetric("cpu", 1, map[string]string{"service": "web", "pid": "1"})))
+	assert.True(t, a.add(buildAggregationTestMetric("cpu", 3, map[string]string{"service": "web", "pid": "2"})))
+	assert.True(t, a.add(buildAggregationTestMetric("cpu", 5, map[string]string{"service": "db", "pid": "3"})))
+	assert.True(t, a.add(buildAggregationTestMetric("memory", 5, map[string]string{"service": "db"})))
+
+	values := map[string]float64{}
+	for _, m := range a.flush() {
+		assert.Equal(t, 1, len(m.Dimensions))
+		values[m.Dimensions["service"]+":"+m.Name] = m.Value
+	}
+	assert.Equal(t, map[string]float64{
+		"web:cpu.sum":   4,
+		"web:cpu.avg":   2,
+		"web:cpu.min":   1,
+		"web:cpu.max":   3,
+		"web:cpu.count": 2,
+		"db:cpu.sum":    5,
+		"db:cpu.avg":    5,
+		"db:cpu.min":    5,
+		"db:cpu.max":    5,
+		"db:cpu.count":  1,
+	}, values)
+
+	// every flush starts over
+	assert.Equal(t, 0, len(a.flush()))
+}
+
+func TestAggregatorDropRaw(t *testing.T) {
+	agg, err := newAggregation(map[string]interface{}{"metric": "^cpu$", "dropRaw": true})
+	assert.Nil(t, err)
+	a := newAggregator([]aggregation{agg})
+
+	assert.False(t, a.add(metric.WithValue("cpu", 1)))
+	assert.True(t, a.add(metric.WithValue("cpu.idle", 1)))
+
+	aggregates := a.flush()
+	assert.Equal(t, 1, len(aggregates))
+	assert.Equal(t, "cpu.sum", aggregates[0].Name)
+}
+
+func TestInvalidAggregations(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_aggregations")
+	base.configureCommonParams(map[string]interface{}{
+		"aggregations": []interface{}{
+			map[string]interface{}{"metric": "("},
+			map[string]interface{}{"functions": []interface{}{"median"}},
+			map[string]interface{}{"functions": []interface{}{}},
+			map[string]interface{}{"functions": []interface{}{"max"}},
+		},
+	})
+	assert.Equal(t, 1, len(base.aggregations))
+	assert.Nil(t, newAggregator(nil))
+}
+
+func TestHandlerAggregatesOnFlush(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_aggregations")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 100, done: make(chan struct{})},
+	}
+	base.configureCommonParams(map[string]interface{}{
+		"aggregations": []interface{}{
+			map[string]interface{}{
+				"metric":    "^requests$",
+				"groupBy":   []interface{}{"service"},
+				"functions": []interface{}{"sum"},
+				"dropRaw":   true,
+			},
+		},
+	})
+
+	var lock sync.Mutex
+	emitted := []metric.Metric{}
+	emitFunc := func(metrics []metric.Metric) error {
+		lock.Lock()
+		defer lock.Unlock()
+		emitted = append(emitted, metrics...)
+		return nil
+	}
+
+	base.run(emitFunc)
+	for _, worker := range []string{"1", "2", "3"} {
+		m := buildAggregationTestMetric("requests", 10, map[string]string{"service": "web", "worker": worker})
+		base.CollectorEndpoints()["collector1"].Channel <- m
+	}
+	base.CollectorEndpoints()["collector1"].Channel <- metric.New("other")
+	base.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 2, len(emitted))
+	assert.Equal(t, "other", emitted[0].Name)
+	assert.Equal(t, "requests.sum", emitted[1].Name)
+	assert.Equal(t, 30.0, emitted[1].Value)
+	assert.Equal(t, map[string]string{"service": "web"}, emitted[1].Dimensions)
+}
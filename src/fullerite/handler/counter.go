@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"time"
+)
+
+// How a handler converts cumulative counters for backends that don't understand them
+const (
+	// CounterRate emits the per second rate of the counter as a gauge
+	CounterRate = "rate"
+	// CounterDelta emits how much the counter grew since the last sample as a counter
+	CounterDelta = "delta"
+)
+
+// DefaultCounterStaleAfterSec is how long a counter is remembered without a new sample
+const DefaultCounterStaleAfterSec = 600
+
+// counterConverter remembers the last sample of every cumulative counter
+// series a handler listener has seen, to emit the difference with the next one
+type counterConverter struct {
+	mode       string
+	staleAfter time.Duration
+	series     map[string]counterSample
+}
+
+type counterSample struct {
+	value     float64
+	timestamp time.Time
+	seen      time.Time
+}
+
+func newCounterConverter(mode string, staleAfter time.Duration) *counterConverter {
+	if mode == "" {
+		return nil
+	}
+	if staleAfter <= 0 {
+		staleAfter = DefaultCounterStaleAfterSec * time.Second
+	}
+	return &counterConverter{
+		mode:       mode,
+		staleAfter: staleAfter,
+		series:     make(map[string]counterSample),
+	}
+}
+
+// convert turns a cumulative counter into a rate or a delta, the other
+// metrics are left alone. It returns false when there is nothing to emit yet:
+// on the first sample of a series and when the counter was reset.
+func (c *counterConverter) convert(m *metric.Metric) bool {
+	if m.MetricType != metric.CumulativeCounter {
+		return true
+	}
+
+	key := m.SeriesKey()
+	current := counterSample{
+		value:     m.Value,
+		timestamp: m.GetTimestamp(),
+		seen:      time.Now(),
+	}
+	previous, exists := c.series[key]
+	c.series[key] = current
+	if !exists {
+		return false
+	}
+
+	delta := current.value - previous.value
+	if delta < 0 {
+		// the process behind the counter restarted, start over from here
+		defaultLog.Debug("Counter ", m.Name, " was reset from ", previous.value, " to ", current.value)
+		return false
+	}
+
+	if c.mode == CounterDelta {
+		m.Value = delta
+		m.MetricType = metric.Counter
+		return true
+	}
+
+	elapsed := current.timestamp.Sub(previous.timestamp).Seconds()
+	if elapsed <= 0 {
+		return false
+	}
+	m.Value = delta / elapsed
+	m.MetricType = metric.Gauge
+	return true
+}
+
+// expire forgets the series that didn't get a sample in a while
+func (c *counterConverter) expire(now time.Time) {
+	for key, sample := range c.series {
+		if now.Sub(sample.seen) > c.staleAfter {
+			delete(c.series, key)
+		}
+	}
+}
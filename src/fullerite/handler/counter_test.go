@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"sync"
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildCounterTestMetric(value float64, timestamp time.Time) metric.Metric {
+	m := metric.WithValue("requests", value)
+	m.MetricType = metric.CumulativeCounter
+	m.AddDimension("service", "web")
+	m.Timestamp = timestamp
+	return m
+}
+
+func TestCounterRate(t *testing.T) {
+	c := newCounterConverter(CounterRate, time.Minute)
+	start := time.Unix(1476748800, 0)
+
+	m := buildCounterTestMetric(100, start)
+	assert.False(t, c.convert(&m), "the first sample has nothing to compare to")
+
+	m = buildCounterTestMetric(150, start.Add(10*time.Second))
+	assert.True(t, c.convert(&m))
+	assert.Equal(t, 5.0, m.Value)
+	assert.Equal(t, metric.Gauge, m.MetricType)
+
+	gauge := metric.WithValue("requests", 3)
+	assert.True(t, c.convert(&gauge))
+	assert.Equal(t, 3.0, gauge.Value)
+}
+
+func TestCounterDelta(t *testing.T) {
+	c := newCounterConverter(CounterDelta, time.Minute)
+	start := time.Unix(1476748800, 0)
+
+	m := buildCounterTestMetric(100, start)
+	c.convert(&m)
+	m = buildCounterTestMetric(150, start.Add(10*time.Second))
+	assert.True(t, c.convert(&m))
+	assert.Equal(t, 50.0, m.Value)
+	assert.Equal(t, metric.Counter, m.MetricType)
+
+	// other dimensions make another series
+	other := buildCounterTestMetric(500, start.Add(10*time.Second))
+	other.AddDimension("service", "db")
+	assert.False(t, c.convert(&other))
+}
+
+func TestCounterReset(t *testing.T) {
+	c := newCounterConverter(CounterDelta, time.Minute)
+	start := time.Unix(1476748800, 0)
+
+	m := buildCounterTestMetric(100, start)
+	c.convert(&m)
+	m = buildCounterTestMetric(5, start.Add(10*time.Second))
+	assert.False(t, c.convert(&m), "nothing is emitted when the counter went backwards")
+
+	m = buildCounterTestMetric(25, start.Add(20*time.Second))
+	assert.True(t, c.convert(&m))
+	assert.Equal(t, 20.0, m.Value)
+}
+
+func TestCounterExpire(t *testing.T) {
+	c := newCounterConverter(CounterDelta, time.Minute)
+	m := buildCounterTestMetric(100, time.Now())
+	c.convert(&m)
+
+	c.expire(time.Now())
+	assert.Equal(t, 1, len(c.series))
+	c.expire(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 0, len(c.series))
+}
+
+func TestHandlerConvertsCounters(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_counters")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 100, done: make(chan struct{})},
+	}
+	base.configureCommonParams(map[string]interface{}{
+		"convertCumulativeCounters": "delta",
+	})
+
+	var lock sync.Mutex
+	emitted := []metric.Metric{}
+	emitFunc := func(metrics []metric.Metric) error {
+		lock.Lock()
+		defer lock.Unlock()
+		emitted = append(emitted, metrics...)
+		return nil
+	}
+
+	base.run(emitFunc)
+	start := time.Unix(1476748800, 0)
+	base.CollectorEndpoints()["collector1"].Channel <- buildCounterTestMetric(10, start)
+	base.CollectorEndpoints()["collector1"].Channel <- buildCounterTestMetric(12, start.Add(time.Second))
+	base.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, len(emitted))
+	assert.Equal(t, 2.0, emitted[0].Value)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
 
 	"bytes"
@@ -58,19 +59,24 @@ func newDatadog(
 	return inst
 }
 
+var datadogOptions = config.Options{
+	{Name: "apiKey", Type: config.StringOption, Required: true,
+		Description: "key of the Datadog API"},
+	{Name: "endpoint", Type: config.StringOption, Required: true,
+		Description: "URL of the Datadog API"},
+}
+
 // Configure the Datadog handler
-func (d *Datadog) Configure(configMap map[string]interface{}) {
-	if apiKey, exists := configMap["apiKey"]; exists {
-		d.apiKey = apiKey.(string)
-	} else {
-		d.log.Error("There was no API key specified for the Datadog handler, there won't be any emissions")
-	}
-	if endpoint, exists := configMap["endpoint"]; exists {
-		d.endpoint = endpoint.(string)
-	} else {
-		d.log.Error("There was no endpoint specified for the Datadog Handler, there won't be any emissions")
-	}
-	d.configureCommonParams(configMap)
+func (d *Datadog) Configure(configMap map[string]interface{}) error {
+	values, err := datadogOptions.Decode(configMap)
+	d.apiKey = values.String("apiKey")
+	d.endpoint = values.String("endpoint")
+	return config.JoinErrors(err, d.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the Datadog handler
+func (d *Datadog) Options() config.Options {
+	return append(datadogOptions, commonOptions...)
 }
 
 // Endpoint returns the Datadog API endpoint
@@ -102,12 +108,12 @@ func (d *Datadog) convertToDatadog(incomingMetric metric.Metric) (datapoint data
 	return *dog
 }
 
-func (d *Datadog) emitMetrics(metrics []metric.Metric) bool {
+func (d *Datadog) emitMetrics(metrics []metric.Metric) error {
 	d.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if len(metrics) == 0 {
 		d.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	series := make([]datadogMetric, 0, len(metrics))
@@ -120,14 +126,14 @@ func (d *Datadog) emitMetrics(metrics []metric.Metric) bool {
 	if err != nil {
 		d.log.Error("Failed marshaling datapoints to Datadog format")
 		d.log.Error("Dropping Datadog datapoints ", series)
-		return false
+		return err
 	}
 
 	apiURL := fmt.Sprintf("%s/series?api_key=%s", d.endpoint, d.apiKey)
 	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
 	if err != nil {
 		d.log.Error("Failed to create a request to endpoint ", d.endpoint)
-		return false
+		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
@@ -139,14 +145,15 @@ func (d *Datadog) emitMetrics(metrics []metric.Metric) bool {
 	}
 	rsp, err := client.Do(req)
 	if err != nil {
+		// the backend could not be reached
 		d.log.Error("Failed to complete POST ", err)
-		return false
+		return retryable(err)
 	}
 
 	defer rsp.Body.Close()
 	if (rsp.StatusCode == http.StatusOK) || (rsp.StatusCode == http.StatusAccepted) {
 		d.log.Info("Successfully sent ", len(series), " datapoints to Datadog")
-		return true
+		return nil
 	}
 
 	body, _ := ioutil.ReadAll(rsp.Body)
@@ -154,7 +161,7 @@ func (d *Datadog) emitMetrics(metrics []metric.Metric) bool {
 		" status was ", rsp.Status,
 		" rsp body was ", string(body),
 		" payload was ", string(payload))
-	return false
+	return statusError("Datadog", rsp.StatusCode)
 }
 
 func (d Datadog) dialTimeout(network, addr string) (net.Conn, error) {
@@ -169,6 +176,6 @@ func (d Datadog) serializedDimensions(m metric.Metric) (dimensions []string) {
 }
 
 func makeDatadogPoints(m metric.Metric) []datadogPoint {
-	point := datadogPoint{float64(time.Now().Unix()), m.Value}
+	point := datadogPoint{float64(m.GetTimestamp().Unix()), m.Value}
 	return []datadogPoint{point}
 }
@@ -3,6 +3,8 @@ package handler
 import (
 	"fullerite/metric"
 
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -43,3 +45,23 @@ func TestDatadogConfigure(t *testing.T) {
 	assert.Equal(t, 100, d.MaxBufferSize())
 	assert.Equal(t, "datadog.server", d.Endpoint())
 }
+
+func TestDatadogSendsTheKey(t *testing.T) {
+	var requests []*http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	d := getTestDataDogHandler(12, 13, 14)
+	d.Configure(map[string]interface{}{
+		"apiKey":   "secret",
+		"endpoint": ts.URL,
+	})
+
+	assert.Nil(t, d.emitMetrics([]metric.Metric{metric.New("Test")}))
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "/series", requests[0].URL.Path)
+	assert.Equal(t, "secret", requests[0].URL.Query().Get("api_key"))
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fullerite/config"
+	"fullerite/metric"
+
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// filterRule matches metrics on their name, dimensions and type,
+// every condition that is set has to match
+type filterRule struct {
+	name       string
+	metric     *regexp.Regexp
+	dimensions map[string]*regexp.Regexp
+	metricType string
+
+	// for tracking
+	matched uint64
+}
+
+// filters decide which of the metrics a handler receives it emits:
+// when there are include rules a metric has to match one of them,
+// and it must not match any of the exclude rules
+type filters struct {
+	include  []*filterRule
+	exclude  []*filterRule
+	filtered uint64
+}
+
+// newFilterRule builds a rule from its config, e.g.
+// {"name": "api_latency", "metric": "latency", "dimensions": {"service": "^api"}, "type": "gauge"}
+func newFilterRule(c map[string]interface{}, defaultName string) (*filterRule, error) {
+	rule := &filterRule{
+		name:       defaultName,
+		dimensions: make(map[string]*regexp.Regexp),
+	}
+
+	if asInterface, exists := c["name"]; exists {
+		name, ok := asInterface.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("name should be a string")
+		}
+		rule.name = name
+	}
+
+	if asInterface, exists := c["metric"]; exists {
+		pattern, ok := asInterface.(string)
+		if !ok {
+			return nil, fmt.Errorf("metric should be a pattern, got %v", asInterface)
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("metric: %s", err)
+		}
+		rule.metric = compiled
+	}
+
+	if asInterface, exists := c["dimensions"]; exists {
+		dimensions, ok := asInterface.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("dimensions should map dimension names to patterns")
+		}
+		for key, value := range dimensions {
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("dimension %s should be a pattern, got %v", key, value)
+			}
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("dimension %s: %s", key, err)
+			}
+			rule.dimensions[key] = compiled
+		}
+	}
+
+	if asInterface, exists := c["type"]; exists {
+		switch metricType, _ := asInterface.(string); metricType {
+		case metric.Gauge, metric.Counter, metric.CumulativeCounter:
+			rule.metricType = metricType
+		default:
+			return nil, fmt.Errorf("unknown type %v", asInterface)
+		}
+	}
+	return rule, nil
+}
+
+func (rule *filterRule) matches(m *metric.Metric) bool {
+	if rule.metric != nil && !rule.metric.MatchString(m.Name) {
+		return false
+	}
+	if rule.metricType != "" && rule.metricType != m.MetricType {
+		return false
+	}
+	for key, pattern := range rule.dimensions {
+		value, ok := m.GetDimensionValue(key)
+		if !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	atomic.AddUint64(&rule.matched, 1)
+	return true
+}
+
+// configureFilters parses the "include" and "exclude" rules of a handler config,
+// the rules that are misconfigured are left out and reported in the error
+func (base *BaseHandler) configureFilters(values config.Values) error {
+	f := new(filters)
+	errs := []error{}
+	for _, kind := range []string{"include", "exclude"} {
+		for i, item := range values.List(kind) {
+			c, ok := item.(map[string]interface{})
+			if !ok {
+				errs = append(errs, fmt.Errorf("invalid %s rule %d: should be an object, got %v", kind, i, item))
+				continue
+			}
+			rule, err := newFilterRule(c, fmt.Sprintf("%s%d", kind, i))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s rule %d: %s", kind, i, err))
+				continue
+			}
+			if kind == "include" {
+				f.include = append(f.include, rule)
+			} else {
+				f.exclude = append(f.exclude, rule)
+			}
+		}
+	}
+
+	if len(f.include) > 0 || len(f.exclude) > 0 {
+		base.filters = f
+	}
+	return config.JoinErrors(errs...)
+}
+
+// accept tells whether the handler should emit the metric,
+// every matching rule is counted
+func (f *filters) accept(m *metric.Metric) bool {
+	accepted := len(f.include) == 0
+	for _, rule := range f.include {
+		if rule.matches(m) {
+			accepted = true
+		}
+	}
+	for _, rule := range f.exclude {
+		if rule.matches(m) {
+			accepted = false
+		}
+	}
+
+	if !accepted {
+		atomic.AddUint64(&f.filtered, 1)
+	}
+	return accepted
+}
+
+// stats adds the filter counters to the handler's internal metrics
+func (f *filters) stats(counters map[string]float64) {
+	counters["metricsFiltered"] = float64(atomic.LoadUint64(&f.filtered))
+	for _, rules := range [][]*filterRule{f.include, f.exclude} {
+		for _, rule := range rules {
+			counters["filterMatches."+rule.name] = float64(atomic.LoadUint64(&rule.matched))
+		}
+	}
+}
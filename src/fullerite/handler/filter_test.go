@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"sync"
+	"testing"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildFilterTestHandler(configMap map[string]interface{}) *BaseHandler {
+	base := new(BaseHandler)
+	base.log = l.WithField("testing", "basehandler_filters")
+	base.configureCommonParams(configMap)
+	return base
+}
+
+func buildFilterTestMetric(name, metricType string, dimensions map[string]string) metric.Metric {
+	m := metric.New(name)
+	m.MetricType = metricType
+	m.AddDimensions(dimensions)
+	return m
+}
+
+func TestFilters(t *testing.T) {
+	base := buildFilterTestHandler(map[string]interface{}{
+		"include": []interface{}{
+			map[string]interface{}{
+				"name":       "api_latency",
+				"metric":     "latency",
+				"dimensions": map[string]interface{}{"service": "^api"},
+			},
+			map[string]interface{}{"type": "counter"},
+		},
+		"exclude": []interface{}{
+			map[string]interface{}{"dimensions": map[string]interface{}{"canary": ""}},
+		},
+	})
+	assert.NotNil(t, base.filters)
+
+	for _, test := range []struct {
+		m        metric.Metric
+		accepted bool
+	}{
+		{buildFilterTestMetric("request.latency", "gauge", map[string]string{"service": "api_v1"}), true},
+		{buildFilterTestMetric("request.latency", "gauge", map[string]string{"service": "web"}), false},
+		{buildFilterTestMetric("request.latency", "gauge", map[string]string{}), false},
+		{buildFilterTestMetric("request.count", "counter", map[string]string{}), true},
+		{buildFilterTestMetric("request.count", "counter", map[string]string{"canary": "true"}), false},
+	} {
+		assert.Equal(t, test.accepted, base.filters.accept(&test.m), test.m)
+	}
+
+	counters := base.InternalMetrics().Counters
+	assert.Equal(t, 3.0, counters["metricsFiltered"])
+	assert.Equal(t, 1.0, counters["filterMatches.api_latency"])
+	assert.Equal(t, 2.0, counters["filterMatches.include1"])
+	assert.Equal(t, 1.0, counters["filterMatches.exclude0"])
+}
+
+func TestExcludeOnly(t *testing.T) {
+	base := buildFilterTestHandler(map[string]interface{}{
+		"exclude": []interface{}{
+			map[string]interface{}{"metric": "^debug\\."},
+		},
+	})
+
+	m := metric.New("debug.queue")
+	assert.False(t, base.filters.accept(&m))
+	m = metric.New("queue")
+	assert.True(t, base.filters.accept(&m))
+}
+
+func TestInvalidFilters(t *testing.T) {
+	base := buildFilterTestHandler(map[string]interface{}{
+		"include": []interface{}{
+			map[string]interface{}{"metric": "("},
+			map[string]interface{}{"type": "histogram"},
+			map[string]interface{}{"dimensions": "service"},
+			map[string]interface{}{"metric": "valid"},
+		},
+		"exclude": "not a list",
+	})
+	assert.Equal(t, 1, len(base.filters.include))
+	assert.Equal(t, 0, len(base.filters.exclude))
+
+	assert.Nil(t, buildFilterTestHandler(map[string]interface{}{}).filters)
+}
+
+func TestFilterRulesShouldBeObjectsOfPatterns(t *testing.T) {
+	for _, exclude := range []interface{}{
+		[]interface{}{"^foo"},
+		[]interface{}{map[string]interface{}{"metric": []interface{}{"foo"}}},
+		[]interface{}{map[string]interface{}{"dimensions": map[string]interface{}{"service": 1}}},
+	} {
+		base := new(BaseHandler)
+		base.log = l.WithField("testing", "basehandler_filters")
+		err := base.configureCommonParams(map[string]interface{}{"exclude": exclude})
+		if assert.NotNil(t, err, "%v should be rejected", exclude) {
+			assert.Contains(t, err.Error(), "invalid exclude rule 0")
+		}
+		assert.Nil(t, base.filters, "a broken rule should not exclude every metric")
+	}
+}
+
+func TestHandlerFiltersBeforeBuffering(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_filters")
+	base.interval = 100
+	base.maxBufferSize = 1
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 1, done: make(chan struct{})},
+	}
+	base.configureCommonParams(map[string]interface{}{
+		"include": []interface{}{
+			map[string]interface{}{"metric": "latency"},
+		},
+	})
+
+	var lock sync.Mutex
+	emitted := []string{}
+	emitFunc := func(metrics []metric.Metric) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, m := range metrics {
+			emitted = append(emitted, m.Name)
+		}
+		return nil
+	}
+
+	base.run(emitFunc)
+	for _, name := range []string{"heap.used", "p99.latency", "threads"} {
+		base.CollectorEndpoints()["collector1"].Channel <- metric.New(name)
+	}
+	base.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"p99.latency"}, emitted)
+	assert.Equal(t, 2.0, base.InternalMetrics().Counters["metricsFiltered"])
+}
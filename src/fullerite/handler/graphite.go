@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"fullerite/config"
 	"fullerite/metric"
 	"fullerite/util"
 	"net"
@@ -55,20 +56,26 @@ func (g Graphite) Port() string {
 	return g.port
 }
 
+var graphiteOptions = config.Options{
+	{Name: "server", Type: config.StringOption, Required: true,
+		Description: "host of the Graphite server"},
+	{Name: "port", Type: config.IntOption, Required: true,
+		Description: "port of the Graphite plaintext protocol"},
+}
+
 // Configure accepts the different configuration options for the Graphite handler
-func (g *Graphite) Configure(configMap map[string]interface{}) {
-	if server, exists := configMap["server"]; exists {
-		g.server = server.(string)
-	} else {
-		g.log.Error("There was no server specified for the Graphite Handler, there won't be any emissions")
+func (g *Graphite) Configure(configMap map[string]interface{}) error {
+	values, err := graphiteOptions.Decode(configMap)
+	g.server = values.String("server")
+	if values.Has("port") {
+		g.port = fmt.Sprint(values.Int("port"))
 	}
+	return config.JoinErrors(err, g.configureCommonParams(configMap))
+}
 
-	if port, exists := configMap["port"]; exists {
-		g.port = fmt.Sprint(port)
-	} else {
-		g.log.Error("There was no port specified for the Graphite Handler, there won't be any emissions")
-	}
-	g.configureCommonParams(configMap)
+// Options : the keys understood by the Graphite handler
+func (g *Graphite) Options() config.Options {
+	return append(graphiteOptions, commonOptions...)
 }
 
 // Run runs the handler main loop
@@ -77,50 +84,61 @@ func (g *Graphite) Run() {
 }
 
 func (g Graphite) convertToGraphite(incomingMetric metric.Metric) (datapoint string) {
+	return FormatGraphite(g.Prefix(), g.DefaultDimensions(), incomingMetric)
+}
+
+// FormatGraphite renders a metric as a line of the Graphite plaintext protocol,
+// the sanitized dimensions are appended to the name in order
+func FormatGraphite(prefix string, defaultDimensions map[string]string, incomingMetric metric.Metric) (datapoint string) {
 	//orders dimensions so datapoint keeps consistent name
 	var keys []string
-	dimensions := g.getSanitizedDimensions(incomingMetric)
+	dimensions := graphiteDimensions(incomingMetric, defaultDimensions)
 	for k := range dimensions {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	datapoint = g.Prefix() + graphiteSanitize(incomingMetric.Name)
+	datapoint = prefix + graphiteSanitize(incomingMetric.Name)
 	for _, key := range keys {
 		datapoint = fmt.Sprintf("%s.%s.%s", datapoint, key, dimensions[key])
 	}
-	datapoint = fmt.Sprintf("%s %f %d\n", datapoint, incomingMetric.Value, time.Now().Unix())
+	datapoint = fmt.Sprintf("%s %f %d\n", datapoint, incomingMetric.Value, incomingMetric.GetTimestamp().Unix())
 	return datapoint
 }
 
-func (g Graphite) getSanitizedDimensions(incomingMetric metric.Metric) map[string]string {
+func graphiteDimensions(incomingMetric metric.Metric, defaultDimensions map[string]string) map[string]string {
 	dimSanitized := make(map[string]string)
-	dimensions := incomingMetric.GetDimensions(g.DefaultDimensions())
+	dimensions := incomingMetric.GetDimensions(defaultDimensions)
 	for key, value := range dimensions {
 		dimSanitized[graphiteSanitize(key)] = graphiteSanitize(value)
 	}
 	return dimSanitized
 }
 
-func (g *Graphite) emitMetrics(metrics []metric.Metric) bool {
+func (g *Graphite) emitMetrics(metrics []metric.Metric) error {
 	g.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if len(metrics) == 0 {
 		g.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	addr := fmt.Sprintf("%s:%s", g.server, g.port)
 	conn, err := net.DialTimeout("tcp", addr, g.timeout)
 	if err != nil {
 		g.log.Error("Failed to connect ", addr)
-		return false
+		return err
 	}
 
+	defer conn.Close()
+
 	for _, m := range metrics {
-		fmt.Fprintf(conn, g.convertToGraphite(m))
+		if _, err := fmt.Fprint(conn, g.convertToGraphite(m)); err != nil {
+			g.log.Error("Failed to write to ", addr, ": ", err)
+			return retryable(err)
+		}
 	}
-	return true
+	return nil
 }
 
 func graphiteSanitize(value string) string {
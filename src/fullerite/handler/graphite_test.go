@@ -3,6 +3,7 @@ package handler
 import (
 	"fullerite/metric"
 
+	"net"
 	"strings"
 	"testing"
 	"time"
@@ -90,3 +91,41 @@ func TestGraphiteSanitation(t *testing.T) {
 
 	assert.Equal(t, strings.Split(datapoint1, " ")[0], datapoint2, "the two metrics should be the same")
 }
+
+func TestGraphiteUsesMetricTimestamp(t *testing.T) {
+	g := getTestGraphiteHandler(12, 12, 12)
+
+	m := metric.WithValue("Test", 1.0)
+	m.Timestamp = time.Unix(1476748800, 0)
+	datapoint := g.convertToGraphite(m)
+
+	assert.Equal(t, "Test 1.000000 1476748800\n", datapoint)
+}
+
+func TestGraphiteWriteErrorIsRetryable(t *testing.T) {
+	// the server resets the connection as soon as it is made
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.(*net.TCPConn).SetLinger(0)
+		conn.Close()
+	}()
+
+	g := getTestGraphiteHandler(10, 10, 1)
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	g.Configure(map[string]interface{}{"server": host, "port": port})
+
+	metrics := make([]metric.Metric, 100000)
+	for i := range metrics {
+		metrics[i] = metric.New("graphite.write.error.test")
+	}
+	err = g.emitMetrics(metrics)
+	if assert.NotNil(t, err, "the failed writes should be reported") {
+		assert.True(t, isRetryable(err))
+	}
+}
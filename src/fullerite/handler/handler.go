@@ -3,11 +3,15 @@ package handler
 import (
 	"fullerite/config"
 	"fullerite/metric"
+	"fullerite/processor"
+	"fullerite/util"
 	"sync"
 	"sync/atomic"
 
 	"container/list"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,6 +25,20 @@ const (
 	DefaultTimeoutSec                = 2
 	DefaultMaxIdleConnectionsPerHost = 2
 	DefaultKeepAliveInterval         = 30
+	DefaultStopTimeoutSec            = 10
+	DefaultMaxConcurrentEmissions    = 10
+	DefaultMaxPendingBatches         = 10
+	DefaultCollectorSendTimeoutSec   = 5
+)
+
+// What a handler does with a batch when all its emissions are in flight
+const (
+	// OverflowBlock waits for an emission to finish, which holds up the collectors
+	OverflowBlock = "block"
+	// OverflowDropOldest drops the oldest batch waiting to be emitted
+	OverflowDropOldest = "drop_oldest"
+	// OverflowDropNewest drops the batch that was just flushed
+	OverflowDropNewest = "drop_newest"
 )
 
 var defaultLog = l.WithFields(l.Fields{"app": "fullerite", "pkg": "handler"})
@@ -41,6 +59,74 @@ func RegisterHandler(name string, f func(chan metric.Metric, int, int, time.Dura
 type CollectorEnd struct {
 	Channel    chan metric.Metric
 	BufferSize int
+
+	// closed once the handler stops listening on the endpoint,
+	// the channel itself is never closed so writing to it is safe
+	done chan struct{}
+
+	// how long a writer waits for the handler to take a metric before
+	// dropping it, 0 waits until the handler stops listening, and the
+	// counter of the handler the dropped metrics are added to
+	sendTimeout time.Duration
+	dropped     *uint64
+}
+
+// NewCollectorEnd creates an endpoint buffering up to bufferSize metrics
+// before emitting them
+func NewCollectorEnd(bufferSize int) CollectorEnd {
+	return CollectorEnd{
+		Channel:    make(chan metric.Metric, 1),
+		BufferSize: bufferSize,
+		done:       make(chan struct{}),
+	}
+}
+
+// Send writes the metric to the endpoint, it gives up and returns false
+// once the handler stopped listening on it, or drops the metric when the
+// handler didn't take it within the send timeout of the endpoint so that
+// a slow handler doesn't hold up the collector and the other handlers
+func (c CollectorEnd) Send(m metric.Metric) bool {
+	select {
+	case c.Channel <- m:
+		return true
+	case <-c.done:
+		return false
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if c.sendTimeout > 0 {
+		timer := time.NewTimer(c.sendTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.Channel <- m:
+		return true
+	case <-c.done:
+		return false
+	case <-timeout:
+		if c.dropped != nil {
+			atomic.AddUint64(c.dropped, 1)
+		}
+		return false
+	}
+}
+
+// stop tells the listener and the writers of the endpoint
+// that the handler is done with it
+func (c CollectorEnd) stop() {
+	close(c.done)
+}
+
+// Names lists the registered handlers in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(handlerConstructs))
+	for name := range handlerConstructs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // New creates a new Handler based on the requested handler name.
@@ -55,7 +141,9 @@ func New(name string) Handler {
 	realName := strings.Split(name, " ")[0]
 
 	if f, exists := handlerConstructs[realName]; exists {
-		return f(channel, DefaultInterval, DefaultBufferSize, timeout, handlerLog)
+		handler := f(channel, DefaultInterval, DefaultBufferSize, timeout, handlerLog)
+		handler.SetCanonicalName(name)
+		return handler
 	}
 
 	defaultLog.Error("Cannot create handler ", realName)
@@ -65,15 +153,29 @@ func New(name string) Handler {
 // Handler defines the interface of a generic handler.
 type Handler interface {
 	Run()
-	Configure(map[string]interface{})
+	// Configure applies the settings of the handler config, the
+	// handler should not be started when it returns an error
+	Configure(map[string]interface{}) error
+	// Options declares the keys Configure understands
+	Options() config.Options
 	InitListeners(config.Config)
 
+	// ReloadListeners rewires the collector endpoints of a running
+	// handler to match a reloaded config
+	ReloadListeners(config.Config)
+
+	// Stop flushes whatever the handler has buffered and waits,
+	// for a bounded amount of time, for the emissions to finish
+	Stop()
+
 	// InternalMetrics is to publish a set of values
 	// that are relevant to the handler itself.
 	InternalMetrics() metric.InternalMetrics
 
 	// taken care of by the base
 	Name() string
+	CanonicalName() string
+	SetCanonicalName(string)
 	String() string
 	Channel() chan metric.Metric
 
@@ -127,6 +229,7 @@ type BaseHandler struct {
 	channel            chan metric.Metric
 	collectorEndpoints map[string]CollectorEnd
 	name               string
+	canonicalName      string
 	prefix             string
 	defaultDimensions  map[string]string
 	log                *l.Entry
@@ -134,6 +237,7 @@ type BaseHandler struct {
 	interval      int
 	maxBufferSize int
 	timeout       time.Duration
+	stopTimeout   time.Duration
 
 	// for keepalive
 	maxIdleConnectionsPerHost int
@@ -157,6 +261,9 @@ type BaseHandler struct {
 	totalEmissions uint64
 	metricsSent    uint64
 	metricsDropped uint64
+	// the failed metrics that made it to the spool, they are
+	// not dropped but counted as replayed once emitted
+	metricsDroppedToSpool uint64
 
 	// List of blacklisted collectors
 	// the handler won't accept metrics from
@@ -165,6 +272,57 @@ type BaseHandler struct {
 	// List of whitelisted collectors
 	// the handler will accept metrics from
 	whiteListedCollectors map[string]bool
+
+	// Closed when the handler is asked to stop
+	stopChannel chan struct{}
+
+	// Tracks the listeners and the emissions in flight,
+	// so that Stop can wait for the buffers to be flushed
+	inFlight *sync.WaitGroup
+
+	// Include and exclude rules, checked before anything else
+	filters *filters
+
+	// The metrics go through this chain before being buffered
+	processors processor.Chain
+
+	// Cumulative counters are converted to rates or deltas when set
+	counterMode       string
+	counterStaleAfter time.Duration
+
+	// Rolled up by every listener and emitted on each flush interval
+	aggregations []aggregation
+
+	// Set by run, listeners started on a reload emit through it
+	emitFunc func([]metric.Metric) error
+
+	// Optional on-disk queue for the batches that failed to be emitted
+	spool *spool
+
+	// Retry policy for the emissions that failed with a retryable error
+	retryMaxAttempts int
+	retryBackoff     time.Duration
+	retryMaxBackoff  time.Duration
+	emissionRetries  uint64
+	retriesExhausted uint64
+	breaker          *circuitBreaker
+
+	// Emissions are held back for a while after a panic,
+	// and for good once the handler panicked too many times in a row
+	panicBackoff *util.PanicBackoff
+	panics       uint64
+
+	// Caps the emissions in flight, the overflow policy decides what
+	// happens to the batches flushed while all of them are busy
+	maxConcurrentEmissions int
+	maxPendingBatches      int
+	overflowPolicy         string
+	collectorSendTimeout   time.Duration
+	emissionSlots          chan struct{}
+	emissionsInFlight      int64
+	pendingBatches         int64
+	batchesOverflowed      uint64
+	metricsOverflowed      uint64
 }
 
 // SetMaxBufferSize : set the buffer size
@@ -224,6 +382,17 @@ func (base *BaseHandler) Name() string {
 	return base.name
 }
 
+// SetCanonicalName : the name of the handler in the configuration, which
+// tells apart the handlers of the same type
+func (base *BaseHandler) SetCanonicalName(name string) {
+	base.canonicalName = name
+}
+
+// CanonicalName : handler canonical name
+func (base *BaseHandler) CanonicalName() string {
+	return base.canonicalName
+}
+
 // MaxBufferSize : the maximum number of metrics that should be buffered before sending
 func (base *BaseHandler) MaxBufferSize() int {
 	return base.maxBufferSize
@@ -299,6 +468,50 @@ func (base *BaseHandler) MaxIdleConnectionsPerHost() int {
 
 // InitListeners - initiate listener channels for collectors
 func (base *BaseHandler) InitListeners(globalConfig config.Config) {
+	collectorEndpoints := base.collectorEndpointsFor(globalConfig)
+	base.log.Debug("Listening for metrics from ", len(collectorEndpoints), " collectors")
+	base.SetCollectorEndpoints(collectorEndpoints)
+}
+
+// ReloadListeners : keep the endpoints of the collectors that are still configured,
+// stop listening on the ones of the collectors that are gone and start listening
+// for the new ones. It must not run concurrently with CollectorEndpoints, the
+// writers that already got hold of an endpoint that is gone give up on it.
+func (base *BaseHandler) ReloadListeners(globalConfig config.Config) {
+	stopChannel, inFlight := base.lifecycle()
+	mu.Lock()
+	defer mu.Unlock()
+	select {
+	case <-stopChannel:
+		return
+	default:
+	}
+
+	collectorEndpoints := base.collectorEndpointsFor(globalConfig)
+	for name, collectorEnd := range base.collectorEndpoints {
+		if wanted, exists := collectorEndpoints[name]; exists && wanted.BufferSize == collectorEnd.BufferSize {
+			collectorEndpoints[name] = collectorEnd
+			continue
+		}
+		// the listener flushes what it has buffered once it is done
+		collectorEnd.stop()
+	}
+
+	// the listeners are started by run if the handler is not running yet
+	if base.emitFunc != nil {
+		for name, collectorEnd := range collectorEndpoints {
+			if current, exists := base.collectorEndpoints[name]; exists && current == collectorEnd {
+				continue
+			}
+			base.log.Info("Listening for metrics from ", name)
+			inFlight.Add(1)
+			go base.listenForMetrics(base.emitFunc, collectorEnd, name)
+		}
+	}
+	base.collectorEndpoints = collectorEndpoints
+}
+
+func (base *BaseHandler) collectorEndpointsFor(globalConfig config.Config) map[string]CollectorEnd {
 	collectorEndpoints := make(map[string]CollectorEnd)
 	for _, c := range append(globalConfig.Collectors, globalConfig.DiamondCollectors...) {
 
@@ -320,13 +533,12 @@ func (base *BaseHandler) InitListeners(globalConfig config.Config) {
 				continue
 			}
 		}
-		collectorEndpoints[c] = CollectorEnd{
-			make(chan metric.Metric, 1),
-			getCollectorBatchSize(c, globalConfig, base.MaxBufferSize()),
-		}
+		collectorEnd := NewCollectorEnd(getCollectorBatchSize(c, globalConfig, base.MaxBufferSize()))
+		collectorEnd.sendTimeout = base.collectorSendTimeout
+		collectorEnd.dropped = &base.metricsOverflowed
+		collectorEndpoints[c] = collectorEnd
 	}
-	fmt.Println(collectorEndpoints)
-	base.SetCollectorEndpoints(collectorEndpoints)
+	return collectorEndpoints
 }
 
 // GetEmissionTimesLen returns base.emissionTimes.Len thread-safe
@@ -365,14 +577,25 @@ func (base *BaseHandler) String() string {
 func (base *BaseHandler) InternalMetrics() metric.InternalMetrics {
 	mu.Lock()
 	defer mu.Unlock()
+	// a failed metric is counted as dropped before it is spooled,
+	// loading the spooled ones first keeps the difference positive
+	spooled := atomic.LoadUint64(&base.metricsDroppedToSpool)
+	dropped := atomic.LoadUint64(&base.metricsDropped)
 	counters := map[string]float64{
-		"totalEmissions": float64(base.totalEmissions),
-		"metricsDropped": float64(base.metricsDropped),
-		"metricsSent":    float64(base.metricsSent),
+		"totalEmissions":           float64(atomic.LoadUint64(&base.totalEmissions)),
+		"metricsDropped":           float64(dropped - spooled),
+		"metricsSent":              float64(atomic.LoadUint64(&base.metricsSent)),
+		"emissionRetries":          float64(atomic.LoadUint64(&base.emissionRetries)),
+		"retriesExhausted":         float64(atomic.LoadUint64(&base.retriesExhausted)),
+		"batchesDroppedOnOverflow": float64(atomic.LoadUint64(&base.batchesOverflowed)),
+		"metricsDroppedOnOverflow": float64(atomic.LoadUint64(&base.metricsOverflowed)),
+		"fullerite.handler_panics": float64(atomic.LoadUint64(&base.panics)),
 	}
 	gauges := map[string]float64{
 		"intervalLength":    float64(base.interval),
 		"emissionsInWindow": float64(base.emissionTimes.Len()),
+		"emissionsInFlight": float64(atomic.LoadInt64(&base.emissionsInFlight)),
+		"pendingBatches":    float64(atomic.LoadInt64(&base.pendingBatches)),
 	}
 
 	// now we calculate the average emission seconds for
@@ -393,122 +616,520 @@ func (base *BaseHandler) InternalMetrics() metric.InternalMetrics {
 		gauges["maxEmissionTiming"] = max
 	}
 
+	if base.spool != nil {
+		base.spool.stats(counters, gauges)
+	}
+
+	if base.filters != nil {
+		base.filters.stats(counters)
+	}
+
+	breaker := base.breaker
+	if breaker == nil {
+		breaker = new(circuitBreaker)
+	}
+	breaker.stats(counters, gauges)
+
+	gauges["handlerDisabled"] = 0
+	if base.panicBackoff != nil && base.panicBackoff.Disabled() {
+		gauges["handlerDisabled"] = 1
+	}
+
 	return metric.InternalMetrics{
 		Counters: counters,
 		Gauges:   gauges,
 	}
 }
 
-// configureCommonParams will extract the common parameters that are used and set them in the handler
-func (base *BaseHandler) configureCommonParams(configMap map[string]interface{}) {
-	if asInterface, exists := configMap["timeout"]; exists {
-		timeout := config.GetAsFloat(asInterface, DefaultTimeoutSec)
-		base.timeout = time.Duration(timeout) * time.Second
+// commonOptions are the keys every handler understands
+var commonOptions = config.Options{
+	{Name: "timeout", Type: config.FloatOption,
+		Description: "seconds an emission can take"},
+	{Name: "max_buffer_size", Type: config.IntOption,
+		Description: "metrics buffered before they are emitted"},
+	{Name: "interval", Type: config.IntOption,
+		Description: "seconds between two flushes of the buffer, the global interval by default"},
+	{Name: "defaultDimensions", Type: config.StringMapOption,
+		Description: "dimensions added to every metric, replacing the global ones"},
+	{Name: "keepAliveInterval", Type: config.IntOption,
+		Description: "seconds the HTTP connections are kept alive"},
+	{Name: "maxIdleConnectionsPerHost", Type: config.IntOption,
+		Description: "idle HTTP connections kept per host"},
+	{Name: "collectorBlackList", Type: config.StringListOption,
+		Description: "collectors whose metrics are not emitted"},
+	{Name: "collectorWhiteList", Type: config.StringListOption,
+		Description: "only the metrics of these collectors are emitted"},
+	{Name: "stopTimeout", Type: config.FloatOption, Default: DefaultStopTimeoutSec,
+		Description: "seconds given to flush the buffers on shutdown"},
+	{Name: "include", Type: config.ListOption,
+		Description: "rules the metrics have to match one of to be emitted"},
+	{Name: "exclude", Type: config.ListOption,
+		Description: "rules matching the metrics not to emit"},
+	{Name: "processors", Type: config.ListOption,
+		Description: "transformations applied to the metrics, in order"},
+	{Name: "convertCumulativeCounters", Type: config.StringOption,
+		Description: "emit the cumulative counters as a rate or a delta"},
+	{Name: "counterStaleAfter", Type: config.FloatOption, Default: DefaultCounterStaleAfterSec,
+		Description: "seconds after which a counter without samples is forgotten"},
+	{Name: "aggregations", Type: config.ListOption,
+		Description: "series rolled up on every flush"},
+	{Name: "spoolDir", Type: config.StringOption,
+		Description: "directory the batches that failed to be emitted are kept in"},
+	{Name: "spoolMaxSizeMB", Type: config.FloatOption, Default: DefaultSpoolMaxSizeMB,
+		Description: "size of the spool past which the oldest batches are dropped"},
+	{Name: "spoolMaxAge", Type: config.FloatOption, Default: DefaultSpoolMaxAgeSec,
+		Description: "seconds after which a spooled batch is dropped"},
+	{Name: "retryMaxAttempts", Type: config.IntOption, Default: DefaultRetryMaxAttempts,
+		Description: "attempts at emitting a batch"},
+	{Name: "retryBackoff", Type: config.FloatOption, Default: DefaultRetryBackoffSec,
+		Description: "seconds before the first retry, doubled on every retry"},
+	{Name: "retryMaxBackoff", Type: config.FloatOption, Default: DefaultRetryMaxBackoffSec,
+		Description: "seconds between two retries at most"},
+	{Name: "maxConcurrentEmissions", Type: config.IntOption, Default: DefaultMaxConcurrentEmissions,
+		Description: "emissions in flight at most"},
+	{Name: "maxPendingBatches", Type: config.IntOption, Default: DefaultMaxPendingBatches,
+		Description: "batches waiting for an emission before they are dropped"},
+	{Name: "overflowPolicy", Type: config.StringOption, Default: OverflowBlock,
+		Description: "block, drop_oldest or drop_newest when all the emissions are in flight"},
+	{Name: "collectorSendTimeout", Type: config.FloatOption, Default: DefaultCollectorSendTimeoutSec,
+		Description: "seconds a collector waits for the handler to take a metric before it is dropped, 0 waits for ever"},
+	{Name: "circuitBreakerThreshold", Type: config.IntOption, Default: DefaultCircuitBreakerThreshold,
+		Description: "consecutive failures that stop the emissions, 0 disables the breaker"},
+	{Name: "circuitBreakerCooldown", Type: config.FloatOption, Default: DefaultCircuitBreakerCooldownSec,
+		Description: "seconds the emissions are stopped for"},
+}
+
+// Options : the keys understood by the handlers that have no settings of their own
+func (base *BaseHandler) Options() config.Options {
+	return commonOptions
+}
+
+// configureCommonParams will extract the common parameters that are used and set them in the handler,
+// the ones that are invalid are left out and reported in the error
+func (base *BaseHandler) configureCommonParams(configMap map[string]interface{}) error {
+	values, err := commonOptions.Decode(configMap)
+	errs := []error{err}
+
+	if values.Has("timeout") {
+		base.timeout = time.Duration(values.Float("timeout")) * time.Second
 	}
 
-	if asInterface, exists := configMap["max_buffer_size"]; exists {
-		base.maxBufferSize = config.GetAsInt(asInterface, DefaultBufferSize)
+	if values.Has("max_buffer_size") {
+		base.maxBufferSize = values.Int("max_buffer_size")
 	}
 
-	if asInterface, exists := configMap["interval"]; exists {
-		base.interval = config.GetAsInt(asInterface, DefaultInterval)
+	if values.Has("interval") {
+		base.interval = values.Int("interval")
 	}
 
 	// Default dimensions can be extended or overridden on a per handler basis.
-	if asInterface, exists := configMap["defaultDimensions"]; exists {
-		handlerLevelDimensions := config.GetAsMap(asInterface)
-		base.SetDefaultDimensions(handlerLevelDimensions)
+	if values.Has("defaultDimensions") {
+		base.SetDefaultDimensions(values.StringMap("defaultDimensions"))
+	}
+
+	if values.Has("keepAliveInterval") {
+		base.SetKeepAliveInterval(values.Int("keepAliveInterval"))
+	}
+
+	if values.Has("maxIdleConnectionsPerHost") {
+		base.SetMaxIdleConnectionsPerHost(values.Int("maxIdleConnectionsPerHost"))
+	}
+
+	if values.Has("collectorBlackList") {
+		base.SetCollectorBlackList(values.StringList("collectorBlackList"))
+	}
+
+	if values.Has("collectorWhiteList") {
+		base.SetCollectorWhiteList(values.StringList("collectorWhiteList"))
+	}
+
+	base.stopTimeout = time.Duration(values.Float("stopTimeout")) * time.Second
+
+	errs = append(errs, base.configureFilters(values))
+
+	if values.Has("processors") {
+		chain, err := processor.FromConfig(values.List("processors"))
+		errs = append(errs, err)
+		base.processors = chain
+	}
+
+	if values.Has("convertCumulativeCounters") {
+		switch mode := values.String("convertCumulativeCounters"); mode {
+		case CounterRate, CounterDelta:
+			base.counterMode = mode
+		default:
+			errs = append(errs, fmt.Errorf("unknown convertCumulativeCounters %s, should be %s or %s",
+				mode, CounterRate, CounterDelta))
+		}
+	}
+
+	base.counterStaleAfter = time.Duration(values.Float("counterStaleAfter") * float64(time.Second))
+
+	if values.Has("aggregations") {
+		errs = append(errs, base.configureAggregations(values.List("aggregations")))
+	}
+
+	if values.Has("spoolDir") {
+		errs = append(errs, base.configureSpool(values))
+	}
+
+	base.retryMaxAttempts = values.Int("retryMaxAttempts")
+	base.retryBackoff = time.Duration(values.Float("retryBackoff") * float64(time.Second))
+	base.retryMaxBackoff = time.Duration(values.Float("retryMaxBackoff") * float64(time.Second))
+
+	base.maxConcurrentEmissions = values.Int("maxConcurrentEmissions")
+	if base.maxConcurrentEmissions < 1 {
+		errs = append(errs, fmt.Errorf("maxConcurrentEmissions should be at least 1, got %d", base.maxConcurrentEmissions))
+	}
+	base.maxPendingBatches = values.Int("maxPendingBatches")
+	if base.maxPendingBatches < 1 {
+		errs = append(errs, fmt.Errorf("maxPendingBatches should be at least 1, got %d", base.maxPendingBatches))
+	}
+
+	switch policy := values.String("overflowPolicy"); policy {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		base.overflowPolicy = policy
+	default:
+		errs = append(errs, fmt.Errorf("unknown overflowPolicy %s", policy))
+	}
+
+	base.collectorSendTimeout = time.Duration(values.Float("collectorSendTimeout") * float64(time.Second))
+
+	breaker := base.circuit()
+	breaker.threshold = values.Int("circuitBreakerThreshold")
+	breaker.cooldown = time.Duration(values.Float("circuitBreakerCooldown") * float64(time.Second))
+
+	return config.JoinErrors(errs...)
+}
+
+// circuit lazily sets up the circuit breaker of the handler
+func (base *BaseHandler) circuit() *circuitBreaker {
+	mu.Lock()
+	defer mu.Unlock()
+	if base.breaker == nil {
+		base.breaker = &circuitBreaker{
+			threshold: DefaultCircuitBreakerThreshold,
+			cooldown:  DefaultCircuitBreakerCooldownSec * time.Second,
+		}
+	}
+	return base.breaker
+}
+
+// panicked lazily sets up the backoff of the emissions after a panic
+func (base *BaseHandler) panicked() *util.PanicBackoff {
+	mu.Lock()
+	defer mu.Unlock()
+	if base.panicBackoff == nil {
+		base.panicBackoff = new(util.PanicBackoff)
+	}
+	return base.panicBackoff
+}
+
+// configureSpool sets up the on-disk queue the failed batches are written to,
+// each handler gets its own directory under spoolDir, named after its
+// canonical name with underscores for spaces
+func (base *BaseHandler) configureSpool(values config.Values) error {
+	dir := values.String("spoolDir")
+	if dir == "" {
+		return fmt.Errorf("spoolDir should be a path")
 	}
 
-	if asInterface, exists := configMap["keepAliveInterval"]; exists {
-		keepAliveInterval := config.GetAsInt(asInterface, DefaultKeepAliveInterval)
-		base.SetKeepAliveInterval(keepAliveInterval)
+	maxSizeMB := values.Float("spoolMaxSizeMB")
+	maxAge := values.Float("spoolMaxAge")
+
+	instance := base.CanonicalName()
+	if instance == "" {
+		instance = base.name
 	}
+	dir = filepath.Join(dir, strings.Replace(instance, " ", "_", -1))
+	s, err := newSpool(dir,
+		int64(maxSizeMB*1024*1024),
+		time.Duration(maxAge*float64(time.Second)),
+		base.log.WithField("spool", dir))
+	if err != nil {
+		return fmt.Errorf("failed to set up the spool in %s: %s", dir, err)
+	}
+	base.spool = s
+	return nil
+}
 
-	if asInterface, exists := configMap["maxIdleConnectionsPerHost"]; exists {
-		maxIdleConnectionsPerHost := config.GetAsInt(asInterface,
-			DefaultMaxIdleConnectionsPerHost)
-		base.SetMaxIdleConnectionsPerHost(maxIdleConnectionsPerHost)
+// lifecycle lazily sets up what is needed to stop the handler,
+// since Stop can be called before Run had a chance to do it
+func (base *BaseHandler) lifecycle() (chan struct{}, *sync.WaitGroup) {
+	mu.Lock()
+	defer mu.Unlock()
+	if base.stopChannel == nil {
+		base.stopChannel = make(chan struct{})
+		base.inFlight = new(sync.WaitGroup)
 	}
+	return base.stopChannel, base.inFlight
+}
 
-	if asInterface, exists := configMap["collectorBlackList"]; exists {
-		blackList := config.GetAsSlice(asInterface)
-		base.SetCollectorBlackList(blackList)
+// slots lazily sets up the semaphore capping the emissions in flight,
+// an emission holds a slot by sending to the channel
+func (base *BaseHandler) slots() chan struct{} {
+	mu.Lock()
+	defer mu.Unlock()
+	if base.emissionSlots == nil {
+		maxConcurrentEmissions := base.maxConcurrentEmissions
+		if maxConcurrentEmissions <= 0 {
+			maxConcurrentEmissions = DefaultMaxConcurrentEmissions
+		}
+		base.emissionSlots = make(chan struct{}, maxConcurrentEmissions)
+	}
+	return base.emissionSlots
+}
+
+// Stop : stop listening on the collector endpoints, flush the buffered
+// metrics and wait for the emissions in flight. The writers still blocked
+// on an endpoint give up.
+func (base *BaseHandler) Stop() {
+	stopChannel, inFlight := base.lifecycle()
+	mu.Lock()
+	select {
+	case <-stopChannel:
+		mu.Unlock()
+		return
+	default:
 	}
 
-	if asInterface, exists := configMap["collectorWhiteList"]; exists {
-		whiteList := config.GetAsSlice(asInterface)
-		base.SetCollectorWhiteList(whiteList)
+	base.log.Info("Stopping ", base.String(), ", flushing buffered metrics")
+	close(stopChannel)
+	for _, collectorEnd := range base.collectorEndpoints {
+		collectorEnd.stop()
+	}
+	mu.Unlock()
+
+	stopTimeout := base.stopTimeout
+	if stopTimeout == 0 {
+		stopTimeout = DefaultStopTimeoutSec * time.Second
+	}
+
+	done := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		base.log.Info("Stopped ", base.String())
+	case <-time.After(stopTimeout):
+		base.log.Warn("Timed out after ", stopTimeout, " waiting for ", base.String(), " to flush")
 	}
 }
 
-func (base *BaseHandler) run(emitFunc func([]metric.Metric) bool) {
+func (base *BaseHandler) run(emitFunc func([]metric.Metric) error) {
+	stopChannel, inFlight := base.lifecycle()
+	mu.Lock()
+	defer mu.Unlock()
+	select {
+	case <-stopChannel:
+		base.log.Warn(base.String(), " was stopped before it started running")
+		return
+	default:
+	}
+
 	// Initiliaze channel and start listening to
 	// emissionTimings on the same
 	base.emissionTimingChannel = make(chan emissionTiming)
 	go base.recordEmissions()
 
-	defaultCollectorEnd := CollectorEnd{base.Channel(), base.MaxBufferSize()}
+	// the handler channel is listened on until the handler stops
+	defaultCollectorEnd := CollectorEnd{Channel: base.Channel(), BufferSize: base.MaxBufferSize(), done: stopChannel}
 
+	base.emitFunc = emitFunc
+	inFlight.Add(1 + len(base.collectorEndpoints))
 	go base.listenForMetrics(emitFunc, defaultCollectorEnd, "")
-	for k := range base.CollectorEndpoints() {
-		go base.listenForMetrics(emitFunc, base.CollectorEndpoints()[k], k)
+	for k := range base.collectorEndpoints {
+		go base.listenForMetrics(emitFunc, base.collectorEndpoints[k], k)
 	}
 }
 
 func (base *BaseHandler) listenForMetrics(
-	emitFunc func([]metric.Metric) bool,
+	emitFunc func([]metric.Metric) error,
 	collectorEnd CollectorEnd,
 	collectorName string) {
 
+	stopChannel, inFlight := base.lifecycle()
+	defer inFlight.Done()
+
 	metrics := make([]metric.Metric, 0, collectorEnd.BufferSize)
 	currentBufferSize := 0
+	counters := newCounterConverter(base.counterMode, base.counterStaleAfter)
+	aggregator := newAggregator(base.aggregations)
 
 	ticker := time.NewTicker(time.Duration(base.Interval()) * time.Second)
 	flusher := ticker.C
 
+	// batches waiting for an emission slot, only used when
+	// the overflow policy drops batches instead of blocking
+	slots := base.slots()
+	pending := [][]metric.Metric{}
+
+	// must be called holding a slot, the emission gives it back
+	dispatch := func(batch []metric.Metric) {
+		inFlight.Add(1)
+		atomic.AddInt64(&base.emissionsInFlight, 1)
+		go func() {
+			defer inFlight.Done()
+			defer func() { <-slots }()
+			defer atomic.AddInt64(&base.emissionsInFlight, -1)
+			base.emitAndTime(batch, emitFunc)
+		}()
+	}
+
+	queue := func(batch []metric.Metric) {
+		if len(pending) >= base.maxPendingBatches {
+			if base.overflowPolicy == OverflowDropNewest || len(pending) == 0 {
+				base.overflow(batch, collectorName)
+				return
+			}
+			base.overflow(pending[0], collectorName)
+			pending = pending[1:]
+			atomic.AddInt64(&base.pendingBatches, -1)
+		}
+		pending = append(pending, batch)
+		atomic.AddInt64(&base.pendingBatches, 1)
+	}
+
 	flushFunction := func() {
-		go base.emitAndTime(metrics, emitFunc)
+		batch := metrics
+		if base.overflowPolicy == "" || base.overflowPolicy == OverflowBlock {
+			slots <- struct{}{}
+			dispatch(batch)
+		} else if len(pending) > 0 {
+			queue(batch)
+		} else {
+			select {
+			case slots <- struct{}{}:
+				dispatch(batch)
+			default:
+				queue(batch)
+			}
+		}
 
-		// will get copied into this call, meaning it's ok to clear it
+		// handed over above, meaning it's ok to clear it
 		metrics = make([]metric.Metric, 0, collectorEnd.BufferSize)
 		currentBufferSize = 0
 	}
 
+	// the aggregates are added to the buffer on the flush interval,
+	// when forced to flush and when stopping
+	addAggregates := func() {
+		if aggregator == nil {
+			return
+		}
+		aggregates := aggregator.flush()
+		metrics = append(metrics, aggregates...)
+		currentBufferSize += len(aggregates)
+	}
+
+	// receive buffers a metric read from the endpoint,
+	// it returns false when asked to stop reading
+	receive := func(incomingMetric metric.Metric) bool {
+		if incomingMetric.ZeroValue() {
+			// a zero metric value means, either channel has been closed or
+			// we have been asked to stop reading.
+			return false
+		}
+		if incomingMetric.Sentinel() {
+			base.log.Info("Sentinel :", currentBufferSize, " col: ", collectorName)
+			addAggregates()
+			if currentBufferSize > 0 {
+				flushFunction()
+			}
+			return true
+		}
+
+		if base.filters != nil && !base.filters.accept(&incomingMetric) {
+			return true
+		}
+		if !base.processors.Process(&incomingMetric) {
+			return true
+		}
+		if counters != nil && !counters.convert(&incomingMetric) {
+			return true
+		}
+		if aggregator != nil && !aggregator.add(incomingMetric) {
+			return true
+		}
+
+		base.log.Debug(base.Name(), " metric: ", incomingMetric)
+		metrics = append(metrics, incomingMetric)
+		currentBufferSize++
+
+		if int(currentBufferSize) >= collectorEnd.BufferSize {
+			base.log.Debug("Full: ", currentBufferSize, " col: ", collectorName)
+			flushFunction()
+		}
+		return true
+	}
+
 stopReading:
 	for {
+		// wait for a free slot only when there is something to emit
+		var acquire chan struct{}
+		if len(pending) > 0 {
+			acquire = slots
+		}
+
 		select {
+		case acquire <- struct{}{}:
+			dispatch(pending[0])
+			pending = pending[1:]
+			atomic.AddInt64(&base.pendingBatches, -1)
 		case incomingMetric := <-collectorEnd.Channel:
-			if incomingMetric.ZeroValue() {
-				// a zero metric value means, either channel has been closed or
-				// we have been asked to stop reading.
+			if !receive(incomingMetric) {
 				break stopReading
 			}
-			if incomingMetric.Sentinel() {
-				base.log.Info("Sentinel :", currentBufferSize, " col: ", collectorName)
-				if currentBufferSize > 0 {
-					flushFunction()
-				}
-				continue
-			}
-
-			base.log.Debug(base.Name(), " metric: ", incomingMetric)
-			metrics = append(metrics, incomingMetric)
-			currentBufferSize++
-
-			if int(currentBufferSize) >= collectorEnd.BufferSize {
-				base.log.Debug("Full: ", currentBufferSize, " col: ", collectorName)
-				flushFunction()
+		case now := <-flusher:
+			if counters != nil {
+				counters.expire(now)
 			}
-		case <-flusher:
+			addAggregates()
 			if currentBufferSize > 0 {
 				base.log.Debug("Time: ", currentBufferSize, " col: ", collectorName)
 				flushFunction()
 			}
+		case <-collectorEnd.done:
+			break stopReading
+		case <-stopChannel:
+			break stopReading
+		}
+	}
+
+	// take in what was written before the handler stopped listening
+drain:
+	for {
+		select {
+		case incomingMetric := <-collectorEnd.Channel:
+			if !receive(incomingMetric) {
+				break drain
+			}
+		default:
+			break drain
 		}
 	}
 	ticker.Stop()
 
+	// flush what is left, Stop waits for the emission to finish
+	addAggregates()
+	if currentBufferSize > 0 {
+		base.log.Debug("Stop: ", currentBufferSize, " col: ", collectorName)
+		flushFunction()
+	}
+	for _, batch := range pending {
+		slots <- struct{}{}
+		dispatch(batch)
+		atomic.AddInt64(&base.pendingBatches, -1)
+	}
+}
+
+// overflow drops a batch that could not be emitted in time
+func (base *BaseHandler) overflow(batch []metric.Metric, collectorName string) {
+	base.log.Warn("Too many emissions in flight, dropping ", len(batch), " metrics col: ", collectorName)
+	atomic.AddUint64(&base.batchesOverflowed, 1)
+	atomic.AddUint64(&base.metricsOverflowed, uint64(len(batch)))
 }
 
 // manages the rolling window of emissions
@@ -555,10 +1176,29 @@ func (base *BaseHandler) reportEmissionMetrics(emissionResult bool, timing emiss
 	}
 }
 
-func (base *BaseHandler) emitAndTime(metrics []metric.Metric, emitFunc func([]metric.Metric) bool) {
+// safeEmit turns a panic of emitFunc into an error that isn't worth retrying,
+// the panic is logged with its stack trace and recorded in the backoff
+func (base *BaseHandler) safeEmit(metrics []metric.Metric, emitFunc func([]metric.Metric) error,
+	panicBackoff *util.PanicBackoff) (err error) {
+	defer util.Recover(base.log, func(recovered interface{}) {
+		atomic.AddUint64(&base.panics, 1)
+		if delay, disabled := panicBackoff.Panicked(time.Now()); disabled {
+			base.log.Error("Handler keeps panicking, disabling it")
+		} else {
+			base.log.Warn("Handler panicked, emitting again in ", delay)
+		}
+		err = fmt.Errorf("emission panicked: %v", recovered)
+	})
+	err = emitFunc(metrics)
+	panicBackoff.Succeeded()
+	return err
+}
+
+func (base *BaseHandler) emitAndTime(metrics []metric.Metric, emitFunc func([]metric.Metric) error) {
 	start := time.Now()
-	result := emitFunc(metrics)
+	err := base.emitWithRetry(metrics, emitFunc)
 	elapsed := time.Since(start)
+	result := err == nil
 	if !base.useCustomEmissionMetricsReporter {
 		timing := emissionTiming{
 			timestamp:   time.Now(),
@@ -567,4 +1207,74 @@ func (base *BaseHandler) emitAndTime(metrics []metric.Metric, emitFunc func([]me
 		}
 		base.reportEmissionMetrics(result, timing)
 	}
+
+	if base.spool == nil {
+		return
+	}
+	if !result {
+		if failed := unsent(metrics, err); len(failed) > 0 {
+			if err := base.spool.add(failed); err != nil {
+				base.log.Error("Failed to spool ", len(failed), " metrics: ", err)
+			} else {
+				atomic.AddUint64(&base.metricsDroppedToSpool, uint64(len(failed)))
+			}
+		}
+		return
+	}
+
+	// the backend takes writes again, catch up on what it missed. The replay
+	// keeps the emission slot of this batch, Stop waits for it and it stops
+	// with the handler, the next run replays what is left.
+	stopChannel, _ := base.lifecycle()
+	base.spool.replay(stopChannel, func(metrics []metric.Metric) error {
+		return base.emitWithRetry(metrics, emitFunc)
+	})
+}
+
+// emitWithRetry retries the emissions that failed with a retryable error,
+// backing off exponentially in between. Nothing is emitted while the
+// circuit breaker is open or while the handler backs off after a panic.
+func (base *BaseHandler) emitWithRetry(metrics []metric.Metric, emitFunc func([]metric.Metric) error) error {
+	breaker := base.circuit()
+	panicBackoff := base.panicked()
+
+	maxAttempts := base.retryMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultRetryMaxAttempts
+	}
+	initialBackoff := base.retryBackoff
+	if initialBackoff == 0 {
+		initialBackoff = time.Duration(DefaultRetryBackoffSec * float64(time.Second))
+	}
+	maxBackoff := base.retryMaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultRetryMaxBackoffSec * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		if panicBackoff.Disabled() {
+			return errDisabled
+		}
+		if !panicBackoff.Ready(time.Now()) {
+			return errPanicBackoff
+		}
+		if !breaker.allow() {
+			return errCircuitOpen
+		}
+		err := base.safeEmit(metrics, emitFunc, panicBackoff)
+		breaker.record(err)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if attempt >= maxAttempts {
+			atomic.AddUint64(&base.retriesExhausted, 1)
+			return err
+		}
+		metrics = unsent(metrics, err)
+
+		delay := backoff(attempt, initialBackoff, maxBackoff)
+		base.log.Warn("Emission of ", len(metrics), " metrics failed: ", err, ", retrying in ", delay)
+		atomic.AddUint64(&base.emissionRetries, 1)
+		time.Sleep(delay)
+	}
 }
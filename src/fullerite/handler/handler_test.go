@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
 
 	"fmt"
@@ -96,9 +97,9 @@ func TestCommonKeepAliveConfig(t *testing.T) {
 func TestEmissionAndRecord(t *testing.T) {
 	emitCalled := false
 
-	emitFunc := func([]metric.Metric) bool {
+	emitFunc := func([]metric.Metric) error {
 		emitCalled = true
-		return true
+		return nil
 	}
 	metrics := []metric.Metric{metric.New("example")}
 
@@ -159,7 +160,7 @@ func TestHandlerRunFlushInterval(t *testing.T) {
 	emitCalledOnce := false
 	emitCalledTwice := false
 	emitCalledThrice := false
-	emitFunc := func(metrics []metric.Metric) bool {
+	emitFunc := func(metrics []metric.Metric) error {
 		mu.Lock()
 		defer mu.Unlock()
 		if emitCalledOnce && !emitCalledTwice {
@@ -169,7 +170,7 @@ func TestHandlerRunFlushInterval(t *testing.T) {
 			assert.Equal(t, 2, len(metrics))
 			emitCalledOnce = true
 		}
-		return true
+		return nil
 	}
 
 	// now we are waiting for some metrics
@@ -198,12 +199,12 @@ func TestHandlerBufferSize(t *testing.T) {
 	base.maxBufferSize = 100
 	base.channel = make(chan metric.Metric)
 	base.collectorEndpoints = map[string]CollectorEnd{
-		"collector1": CollectorEnd{make(chan metric.Metric), 3},
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 3, done: make(chan struct{})},
 	}
 
-	emitFunc := func(metrics []metric.Metric) bool {
+	emitFunc := func(metrics []metric.Metric) error {
 		assert.Equal(t, 3, len(metrics))
-		return true
+		return nil
 	}
 
 	go base.run(emitFunc)
@@ -226,12 +227,12 @@ func TestSentinelFlushing(t *testing.T) {
 	base.maxBufferSize = 100
 	base.channel = make(chan metric.Metric)
 	base.collectorEndpoints = map[string]CollectorEnd{
-		"collector1": CollectorEnd{make(chan metric.Metric), 100},
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 100, done: make(chan struct{})},
 	}
 
-	emitFunc := func(metrics []metric.Metric) bool {
+	emitFunc := func(metrics []metric.Metric) error {
 		assert.Equal(t, 2, len(metrics))
-		return true
+		return nil
 	}
 
 	go base.run(emitFunc)
@@ -267,12 +268,12 @@ func TestHandlerRun(t *testing.T) {
 	base.channel = make(chan metric.Metric)
 
 	emitCalled := false
-	emitFunc := func(metrics []metric.Metric) bool {
+	emitFunc := func(metrics []metric.Metric) error {
 		assert.Equal(t, 1, len(metrics))
 		mu.Lock()
 		defer mu.Unlock()
 		emitCalled = true
-		return true
+		return nil
 	}
 
 	// now we are waiting for some metrics
@@ -307,15 +308,28 @@ func TestInternalMetrics(t *testing.T) {
 	results := base.InternalMetrics()
 	expected := metric.InternalMetrics{
 		Counters: map[string]float64{
-			"metricsDropped": 100,
-			"metricsSent":    2,
-			"totalEmissions": 10,
+			"metricsDropped":       100,
+			"metricsSent":          2,
+			"totalEmissions":       10,
+			"emissionRetries":      0,
+			"retriesExhausted":     0,
+			"circuitBreakerOpened": 0,
+			"emissionsRejected":    0,
+
+			"batchesDroppedOnOverflow": 0,
+			"metricsDroppedOnOverflow": 0,
+			"fullerite.handler_panics": 0,
 		},
 		Gauges: map[string]float64{
 			"averageEmissionTiming": 7,
 			"emissionsInWindow":     3,
 			"intervalLength":        4,
 			"maxEmissionTiming":     10,
+			"circuitBreakerState":   0,
+			"consecutiveFailures":   0,
+			"emissionsInFlight":     0,
+			"pendingBatches":        0,
+			"handlerDisabled":       0,
 		},
 	}
 	assert.Equal(t, expected, results)
@@ -326,15 +340,28 @@ func TestInternalMetricsWithNan(t *testing.T) {
 
 	expected := metric.InternalMetrics{
 		Counters: map[string]float64{
-			"metricsDropped": 0,
-			"metricsSent":    0,
-			"totalEmissions": 0,
+			"metricsDropped":       0,
+			"metricsSent":          0,
+			"totalEmissions":       0,
+			"emissionRetries":      0,
+			"retriesExhausted":     0,
+			"circuitBreakerOpened": 0,
+			"emissionsRejected":    0,
+
+			"batchesDroppedOnOverflow": 0,
+			"metricsDroppedOnOverflow": 0,
+			"fullerite.handler_panics": 0,
 		},
 		// specifically missing the averageEmissionTiming
 		// because we have no emissions yet
 		Gauges: map[string]float64{
-			"emissionsInWindow": 0,
-			"intervalLength":    0,
+			"emissionsInWindow":   0,
+			"intervalLength":      0,
+			"circuitBreakerState": 0,
+			"consecutiveFailures": 0,
+			"emissionsInFlight":   0,
+			"pendingBatches":      0,
+			"handlerDisabled":     0,
 		},
 	}
 	im := base.InternalMetrics()
@@ -347,3 +374,259 @@ func TestKeepAliveConfig(t *testing.T) {
 	assert.Equal(t, 0, base.KeepAliveInterval())
 	assert.Equal(t, 0, base.MaxIdleConnectionsPerHost())
 }
+
+func TestHandlerStopFlushesBuffers(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_stop")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 100, done: make(chan struct{})},
+	}
+
+	emitFunc := func(metrics []metric.Metric) error {
+		return nil
+	}
+
+	base.run(emitFunc)
+	base.channel <- metric.New("testMetric")
+	base.CollectorEndpoints()["collector1"].Channel <- metric.New("testMetric1")
+	base.CollectorEndpoints()["collector1"].Channel <- metric.New("testMetric2")
+
+	base.Stop()
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&base.metricsSent))
+
+	// stopping twice is harmless
+	base.Stop()
+}
+
+func TestHandlerStopTimeout(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_stop")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.configureCommonParams(map[string]interface{}{"stopTimeout": "1"})
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+	emitFunc := func(metrics []metric.Metric) error {
+		<-blocked
+		return nil
+	}
+
+	base.run(emitFunc)
+	base.channel <- metric.New("testMetric")
+
+	start := time.Now()
+	base.Stop()
+	assert.True(t, time.Since(start) < 3*time.Second, "Stop should give up after the stop timeout")
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&base.metricsSent))
+}
+
+func TestReloadListeners(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_reload")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.InitListeners(config.Config{Collectors: []string{"collector1", "collector2"}})
+
+	emitFunc := func(metrics []metric.Metric) error {
+		return nil
+	}
+
+	base.run(emitFunc)
+	kept := base.CollectorEndpoints()["collector1"]
+	removed := base.CollectorEndpoints()["collector2"]
+	kept.Channel <- metric.New("testMetric1")
+	removed.Channel <- metric.New("testMetric2")
+
+	base.ReloadListeners(config.Config{Collectors: []string{"collector1", "collector3"}})
+
+	endpoints := base.CollectorEndpoints()
+	assert.Equal(t, 2, len(endpoints))
+	assert.Equal(t, kept, endpoints["collector1"])
+	assert.Contains(t, endpoints, "collector3")
+
+	endpoints["collector3"].Channel <- metric.New("testMetric3")
+
+	// the metric buffered for the removed collector was flushed too
+	base.Stop()
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&base.metricsSent))
+}
+
+func TestReloadListenersAfterStop(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_reload")
+	base.InitListeners(config.Config{Collectors: []string{"collector1"}})
+	base.Stop()
+
+	base.ReloadListeners(config.Config{Collectors: []string{"collector2"}})
+	assert.Contains(t, base.CollectorEndpoints(), "collector1")
+	assert.NotContains(t, base.CollectorEndpoints(), "collector2")
+}
+
+func runOverflowTest(t *testing.T, policy string) ([]string, metric.InternalMetrics) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_overflow")
+	base.interval = 100
+	base.maxBufferSize = 1
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 1, done: make(chan struct{})},
+	}
+	base.configureCommonParams(map[string]interface{}{
+		"maxConcurrentEmissions": 1,
+		"maxPendingBatches":      1,
+		"overflowPolicy":         policy,
+	})
+
+	var lock sync.Mutex
+	emitted := []string{}
+	blocked := make(chan bool)
+	emitFunc := func(metrics []metric.Metric) error {
+		<-blocked
+		lock.Lock()
+		defer lock.Unlock()
+		for _, m := range metrics {
+			emitted = append(emitted, m.Name)
+		}
+		return nil
+	}
+
+	base.run(emitFunc)
+	for _, name := range []string{"first", "second", "third", "fourth"} {
+		base.CollectorEndpoints()["collector1"].Channel <- metric.New(name)
+	}
+	// the handler keeps reading while its only emission is stuck
+	base.CollectorEndpoints()["collector1"].Channel <- metric.Sentinel()
+	internalMetrics := base.InternalMetrics()
+
+	close(blocked)
+	base.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	return emitted, internalMetrics
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	emitted, internalMetrics := runOverflowTest(t, OverflowDropNewest)
+	assert.Equal(t, []string{"first", "second"}, emitted)
+	assert.Equal(t, 2.0, internalMetrics.Counters["batchesDroppedOnOverflow"])
+	assert.Equal(t, 2.0, internalMetrics.Counters["metricsDroppedOnOverflow"])
+	assert.Equal(t, 1.0, internalMetrics.Gauges["emissionsInFlight"])
+	assert.Equal(t, 1.0, internalMetrics.Gauges["pendingBatches"])
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	emitted, internalMetrics := runOverflowTest(t, OverflowDropOldest)
+	assert.Equal(t, []string{"first", "fourth"}, emitted)
+	assert.Equal(t, 2.0, internalMetrics.Counters["batchesDroppedOnOverflow"])
+}
+
+func TestCollectorSendTimeout(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_send_timeout")
+	base.configureCommonParams(map[string]interface{}{"collectorSendTimeout": 0.05})
+	base.InitListeners(config.Config{Collectors: []string{"collector1"}})
+
+	// the handler isn't running, only the buffer of the endpoint takes metrics
+	collectorEnd := base.CollectorEndpoints()["collector1"]
+	assert.True(t, collectorEnd.Send(metric.New("buffered")))
+	assert.False(t, collectorEnd.Send(metric.New("dropped")), "a metric the handler doesn't take in time should be dropped")
+	assert.Equal(t, 1.0, base.InternalMetrics().Counters["metricsDroppedOnOverflow"])
+}
+
+func TestEmissionLimitsShouldBePositive(t *testing.T) {
+	b := new(BaseHandler)
+	err := b.configureCommonParams(map[string]interface{}{
+		"maxConcurrentEmissions": 0,
+		"maxPendingBatches":      0,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "maxConcurrentEmissions should be at least 1, got 0")
+	assert.Contains(t, err.Error(), "maxPendingBatches should be at least 1, got 0")
+
+	assert.Nil(t, b.configureCommonParams(map[string]interface{}{}))
+	assert.Equal(t, DefaultMaxConcurrentEmissions, b.maxConcurrentEmissions)
+	assert.Equal(t, DefaultMaxPendingBatches, b.maxPendingBatches)
+	assert.Equal(t, DefaultCollectorSendTimeoutSec*time.Second, b.collectorSendTimeout)
+}
+
+func TestMaxConcurrentEmissions(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_overflow")
+	base.interval = 100
+	base.maxBufferSize = 1
+	base.channel = make(chan metric.Metric)
+	base.configureCommonParams(map[string]interface{}{
+		"maxConcurrentEmissions": 2,
+	})
+
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	emitFunc := func(metrics []metric.Metric) error {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return nil
+	}
+
+	base.run(emitFunc)
+	for i := 0; i < 10; i++ {
+		base.channel <- metric.New("testMetric")
+	}
+	base.Stop()
+
+	assert.Equal(t, 2, maxInFlight)
+	assert.Equal(t, uint64(10), atomic.LoadUint64(&base.metricsSent))
+}
+
+func TestHandlerProcessors(t *testing.T) {
+	base := BaseHandler{}
+	base.log = l.WithField("testing", "basehandler_processors")
+	base.interval = 100
+	base.maxBufferSize = 100
+	base.channel = make(chan metric.Metric)
+	base.collectorEndpoints = map[string]CollectorEnd{
+		"collector1": CollectorEnd{Channel: make(chan metric.Metric), BufferSize: 100, done: make(chan struct{})},
+	}
+	base.configureCommonParams(map[string]interface{}{
+		"processors": []interface{}{
+			map[string]interface{}{"type": "drop", "pattern": "^noisy\\."},
+			map[string]interface{}{"type": "set_type", "metricType": "counter"},
+		},
+	})
+
+	var lock sync.Mutex
+	emitted := []metric.Metric{}
+	emitFunc := func(metrics []metric.Metric) error {
+		lock.Lock()
+		defer lock.Unlock()
+		emitted = append(emitted, metrics...)
+		return nil
+	}
+
+	base.run(emitFunc)
+	base.CollectorEndpoints()["collector1"].Channel <- metric.New("noisy.metric")
+	base.CollectorEndpoints()["collector1"].Channel <- metric.New("useful.metric")
+	base.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, len(emitted))
+	assert.Equal(t, "useful.metric", emitted[0].Name)
+	assert.Equal(t, metric.Counter, emitted[0].MetricType)
+}
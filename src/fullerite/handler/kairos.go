@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fullerite/config"
 	"fullerite/metric"
 	"fullerite/util"
 
@@ -59,20 +60,26 @@ func newKairos(
 	return inst
 }
 
+var kairosOptions = config.Options{
+	{Name: "server", Type: config.StringOption, Required: true,
+		Description: "host of the KairosDB server"},
+	{Name: "port", Type: config.IntOption, Required: true,
+		Description: "port of the KairosDB REST API"},
+}
+
 // Configure the Kairos handler
-func (k *Kairos) Configure(configMap map[string]interface{}) {
-	if server, exists := configMap["server"]; exists {
-		k.server = server.(string)
-	} else {
-		k.log.Error("There was no server specified for the Kairos Handler, there won't be any emissions")
+func (k *Kairos) Configure(configMap map[string]interface{}) error {
+	values, err := kairosOptions.Decode(configMap)
+	k.server = values.String("server")
+	if values.Has("port") {
+		k.port = fmt.Sprint(values.Int("port"))
 	}
+	return config.JoinErrors(err, k.configureCommonParams(configMap))
+}
 
-	if port, exists := configMap["port"]; exists {
-		k.port = fmt.Sprint(port)
-	} else {
-		k.log.Error("There was no port specified for the Kairos Handler, there won't be any emissions")
-	}
-	k.configureCommonParams(configMap)
+// Options : the keys understood by the Kairos handler
+func (k *Kairos) Options() config.Options {
+	return append(kairosOptions, commonOptions...)
 }
 
 // Server returns the Kairos server's hostname or IP address
@@ -95,7 +102,7 @@ func (k Kairos) convertToKairos(incomingMetric metric.Metric) (datapoint KairosM
 	km.Name = k.Prefix() + kairosSanitize(incomingMetric.Name)
 	km.Value = incomingMetric.Value
 	km.MetricType = "double"
-	km.Timestamp = time.Now().Unix() * 1000 // Kairos require timestamps to be milliseconds
+	km.Timestamp = incomingMetric.GetTimestamp().Unix() * 1000 // Kairos require timestamps to be milliseconds
 	km.Tags = make(map[string]string)
 	for key, value := range incomingMetric.GetDimensions(k.DefaultDimensions()) {
 		km.Tags[kairosSanitize(key)] = kairosSanitize(value)
@@ -103,12 +110,12 @@ func (k Kairos) convertToKairos(incomingMetric metric.Metric) (datapoint KairosM
 	return *km
 }
 
-func (k *Kairos) emitMetrics(metrics []metric.Metric) bool {
+func (k *Kairos) emitMetrics(metrics []metric.Metric) error {
 	k.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if len(metrics) == 0 {
 		k.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	series := make([]KairosMetric, 0, len(metrics))
@@ -120,14 +127,14 @@ func (k *Kairos) emitMetrics(metrics []metric.Metric) bool {
 	if err != nil {
 		k.log.Error("Failed marshaling datapoints to Kairos format")
 		k.log.Error("Dropping Kairos datapoints ", series)
-		return false
+		return err
 	}
 
 	apiURL := fmt.Sprintf("http://%s:%s/api/v1/datapoints", k.server, k.port)
 	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
 	if err != nil {
 		k.log.Error("Failed to create a request to API url ", apiURL)
-		return false
+		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
@@ -139,14 +146,15 @@ func (k *Kairos) emitMetrics(metrics []metric.Metric) bool {
 	}
 	rsp, err := client.Do(req)
 	if err != nil {
+		// the backend could not be reached
 		k.log.Error("Failed to complete POST ", err)
-		return false
+		return retryable(err)
 	}
 
 	defer rsp.Body.Close()
 	if rsp.StatusCode == http.StatusNoContent {
 		k.log.Info("Successfully sent ", len(series), " datapoints to Kairos")
-		return true
+		return nil
 	}
 
 	body, _ := ioutil.ReadAll(rsp.Body)
@@ -161,7 +169,7 @@ func (k *Kairos) emitMetrics(metrics []metric.Metric) bool {
 			" rsp body was ", string(body))
 	}
 
-	return false
+	return statusError("Kairos", rsp.StatusCode)
 }
 
 func (k Kairos) dialTimeout(network, addr string) (net.Conn, error) {
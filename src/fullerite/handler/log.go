@@ -39,8 +39,8 @@ func newLog(
 }
 
 // Configure accepts the different configuration options for the Log handler
-func (h *Log) Configure(configMap map[string]interface{}) {
-	h.configureCommonParams(configMap)
+func (h *Log) Configure(configMap map[string]interface{}) error {
+	return h.configureCommonParams(configMap)
 }
 
 // Run runs the handler main loop
@@ -53,12 +53,12 @@ func (h Log) convertToLog(incomingMetric metric.Metric) (string, error) {
 	return string(jsonOut), err
 }
 
-func (h *Log) emitMetrics(metrics []metric.Metric) bool {
+func (h *Log) emitMetrics(metrics []metric.Metric) error {
 	h.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if len(metrics) == 0 {
 		h.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	for _, m := range metrics {
@@ -69,5 +69,5 @@ func (h *Log) emitMetrics(metrics []metric.Metric) bool {
 			h.log.Info(dpString)
 		}
 	}
-	return true
+	return nil
 }
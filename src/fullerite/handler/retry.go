@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for retrying failed emissions and for the circuit breaker
+const (
+	DefaultRetryMaxAttempts          = 3
+	DefaultRetryBackoffSec           = 0.5
+	DefaultRetryMaxBackoffSec        = 10
+	DefaultCircuitBreakerThreshold   = 5
+	DefaultCircuitBreakerCooldownSec = 30
+)
+
+// The states of the circuit breaker, as reported in the internal metrics
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+var (
+	errCircuitOpen  = errors.New("circuit breaker is open, not emitting")
+	errPanicBackoff = errors.New("backing off after a panic, not emitting")
+	errDisabled     = errors.New("disabled after panicking too many times, not emitting")
+	errEmptyPayload = errors.New("empty payload")
+)
+
+// emissionError carries whether a failed emission is worth retrying
+type emissionError struct {
+	err       error
+	retryable bool
+}
+
+func (e emissionError) Error() string {
+	return e.err.Error()
+}
+
+// retryable marks err as a failure that may go away when retried
+func retryable(err error) error {
+	return emissionError{err, true}
+}
+
+// partialError is returned by an emission that sent only some of the
+// metrics, the ones that failed are retried and spooled on their own
+// so that those which went through aren't sent twice
+type partialError struct {
+	emissionError
+	failed []metric.Metric
+}
+
+// unsent returns the metrics an emission that returned err didn't send
+func unsent(metrics []metric.Metric, err error) []metric.Metric {
+	if e, ok := err.(partialError); ok {
+		return e.failed
+	}
+	return metrics
+}
+
+// statusError describes an unexpected response from a backend,
+// only a 5xx or a 429 is worth retrying
+func statusError(backend string, status int) error {
+	err := fmt.Errorf("%s responded with status %d", backend, status)
+	if status == http.StatusTooManyRequests || status/100 == 5 {
+		return retryable(err)
+	}
+	return err
+}
+
+// isRetryable : connection errors and the errors marked as retryable
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case emissionError:
+		return e.retryable
+	case partialError:
+		return e.retryable
+	case net.Error:
+		return true
+	}
+	return false
+}
+
+// backoff returns how long to wait before the given retry, the delay doubles
+// with every attempt up to maxBackoff and half of it is random jitter
+func backoff(retry int, initial, maxBackoff time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < retry && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// circuitBreaker stops the emissions to a backend after too many consecutive
+// failures. Once the cooldown passed a single emission is let through,
+// the breaker closes again if it succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     int
+	openedAt  time.Time
+	trial     bool
+
+	// for tracking
+	opened   uint64
+	rejected uint64
+}
+
+// allow : whether an emission can go through
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			cb.rejected++
+			return false
+		}
+		cb.state = circuitHalfOpen
+		cb.trial = true
+		return true
+	case circuitHalfOpen:
+		if cb.trial {
+			cb.rejected++
+			return false
+		}
+		cb.trial = true
+		return true
+	}
+	return true
+}
+
+// record the outcome of an emission that was allowed through
+func (cb *circuitBreaker) record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.trial = false
+	if err == nil {
+		cb.failures = 0
+		cb.state = circuitClosed
+		return
+	}
+	if !isRetryable(err) {
+		// the backend is up, it didn't like what we sent
+		return
+	}
+
+	cb.failures++
+	if cb.threshold > 0 && (cb.state == circuitHalfOpen || cb.failures >= cb.threshold) {
+		if cb.state != circuitOpen {
+			cb.opened++
+		}
+		cb.state = circuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// stats adds the breaker counters and gauges to the handler's internal metrics
+func (cb *circuitBreaker) stats(counters, gauges map[string]float64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	counters["circuitBreakerOpened"] = float64(cb.opened)
+	counters["emissionsRejected"] = float64(cb.rejected)
+	gauges["circuitBreakerState"] = float64(cb.state)
+	gauges["consecutiveFailures"] = float64(cb.failures)
+}
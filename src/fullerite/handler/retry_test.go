@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"fullerite/metric"
+	"fullerite/util"
+
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildRetryTestHandler(configMap map[string]interface{}) *BaseHandler {
+	base := new(BaseHandler)
+	base.log = l.WithField("testing", "basehandler_retry")
+	base.configureCommonParams(configMap)
+	return base
+}
+
+func TestIsRetryable(t *testing.T) {
+	_, err := net.DialTimeout("tcp", "127.0.0.1:0", time.Second)
+	assert.NotNil(t, err)
+	assert.True(t, isRetryable(err), "connection errors should be retried")
+
+	assert.True(t, isRetryable(statusError("Test", 503)))
+	assert.True(t, isRetryable(statusError("Test", 429)))
+	assert.False(t, isRetryable(statusError("Test", 400)))
+	assert.False(t, isRetryable(errors.New("bad payload")))
+	assert.True(t, isRetryable(retryable(errors.New("try again"))))
+	assert.True(t, isRetryable(partialError{emissionError{errors.New("try again"), true}, nil}))
+	assert.False(t, isRetryable(partialError{emissionError{errors.New("bad payload"), false}, nil}))
+}
+
+func TestBackoff(t *testing.T) {
+	initial := 100 * time.Millisecond
+	max := time.Second
+
+	for retry, expected := range map[int]time.Duration{1: initial, 2: 2 * initial, 3: 4 * initial, 10: max} {
+		delay := backoff(retry, initial, max)
+		assert.True(t, delay >= expected/2, "delay of retry ", retry, " is too short: ", delay)
+		assert.True(t, delay <= expected, "delay of retry ", retry, " is too long: ", delay)
+	}
+}
+
+func TestEmitRetriesRetryableErrors(t *testing.T) {
+	base := buildRetryTestHandler(map[string]interface{}{
+		"retryMaxAttempts": 3,
+		"retryBackoff":     "0.001",
+	})
+
+	attempts := 0
+	err := base.emitWithRetry([]metric.Metric{metric.New("test")}, func([]metric.Metric) error {
+		attempts++
+		if attempts < 3 {
+			return statusError("Test", 503)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2.0, base.InternalMetrics().Counters["emissionRetries"])
+
+	attempts = 0
+	err = base.emitWithRetry([]metric.Metric{metric.New("test")}, func([]metric.Metric) error {
+		attempts++
+		return statusError("Test", 503)
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1.0, base.InternalMetrics().Counters["retriesExhausted"])
+}
+
+func TestEmitRetriesOnlyTheUnsentMetrics(t *testing.T) {
+	base := buildRetryTestHandler(map[string]interface{}{
+		"retryBackoff": "0.001",
+	})
+
+	sent := []int{}
+	err := base.emitWithRetry([]metric.Metric{metric.New("a"), metric.New("b")}, func(metrics []metric.Metric) error {
+		sent = append(sent, len(metrics))
+		if len(metrics) == 2 {
+			return partialError{emissionError{errors.New("b failed"), true}, metrics[1:]}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 1}, sent)
+}
+
+func TestEmitDoesNotRetryOtherErrors(t *testing.T) {
+	base := buildRetryTestHandler(map[string]interface{}{
+		"retryBackoff": "0.001",
+	})
+
+	attempts := 0
+	err := base.emitWithRetry([]metric.Metric{metric.New("test")}, func([]metric.Metric) error {
+		attempts++
+		return statusError("Test", 400)
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 0.0, base.InternalMetrics().Counters["emissionRetries"])
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	base := buildRetryTestHandler(map[string]interface{}{
+		"retryMaxAttempts":        1,
+		"circuitBreakerThreshold": 2,
+		"circuitBreakerCooldown":  "0.05",
+	})
+
+	attempts := 0
+	failing := func([]metric.Metric) error {
+		attempts++
+		return retryable(errors.New("connection refused"))
+	}
+	metrics := []metric.Metric{metric.New("test")}
+
+	base.emitWithRetry(metrics, failing)
+	base.emitWithRetry(metrics, failing)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, float64(circuitOpen), base.InternalMetrics().Gauges["circuitBreakerState"])
+
+	// the backend is left alone while the breaker is open
+	assert.Equal(t, errCircuitOpen, base.emitWithRetry(metrics, failing))
+	assert.Equal(t, 2, attempts)
+
+	internalMetrics := base.InternalMetrics()
+	assert.Equal(t, 1.0, internalMetrics.Counters["circuitBreakerOpened"])
+	assert.Equal(t, 1.0, internalMetrics.Counters["emissionsRejected"])
+
+	// once the cooldown passed a trial emission closes it again
+	time.Sleep(60 * time.Millisecond)
+	err := base.emitWithRetry(metrics, func([]metric.Metric) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(circuitClosed), base.InternalMetrics().Gauges["circuitBreakerState"])
+}
+
+func TestCircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 1, cooldown: time.Millisecond}
+	assert.True(t, breaker.allow())
+	breaker.record(retryable(errors.New("timeout")))
+	assert.Equal(t, circuitOpen, breaker.state)
+
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, breaker.allow())
+	assert.Equal(t, circuitHalfOpen, breaker.state)
+
+	// only one trial at a time
+	assert.False(t, breaker.allow())
+
+	breaker.record(retryable(errors.New("timeout")))
+	assert.Equal(t, circuitOpen, breaker.state)
+	assert.Equal(t, uint64(2), breaker.opened)
+}
+
+func TestEmitRecoversPanics(t *testing.T) {
+	base := buildRetryTestHandler(map[string]interface{}{
+		"retryBackoff": "0.001",
+	})
+	base.panicBackoff = &util.PanicBackoff{Initial: 10 * time.Millisecond, MaxPanics: 2}
+
+	attempts := 0
+	panicking := func([]metric.Metric) error {
+		attempts++
+		panic("boom")
+	}
+
+	err := base.emitWithRetry([]metric.Metric{metric.New("test")}, panicking)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts, "a panic should not be retried")
+	assert.Equal(t, 1.0, base.InternalMetrics().Counters["fullerite.handler_panics"])
+
+	err = base.emitWithRetry([]metric.Metric{metric.New("test")}, panicking)
+	assert.Equal(t, errPanicBackoff, err)
+	assert.Equal(t, 1, attempts)
+
+	time.Sleep(20 * time.Millisecond)
+	err = base.emitWithRetry([]metric.Metric{metric.New("test")}, panicking)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 1.0, base.InternalMetrics().Gauges["handlerDisabled"])
+
+	err = base.emitWithRetry([]metric.Metric{metric.New("test")}, func([]metric.Metric) error { return nil })
+	assert.Equal(t, errDisabled, err)
+	assert.Equal(t, 2.0, base.InternalMetrics().Counters["fullerite.handler_panics"])
+}
@@ -5,6 +5,7 @@ import (
 	"fullerite/metric"
 
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"time"
@@ -69,21 +70,28 @@ func newScribe(
 	return inst
 }
 
-// Configure accepts the different configuration options for the Scribe handler
-func (s *Scribe) Configure(configMap map[string]interface{}) {
-	if endpoint, exists := configMap["endpoint"]; exists {
-		s.endpoint = endpoint.(string)
-	}
+var scribeOptions = config.Options{
+	{Name: "endpoint", Type: config.StringOption, Default: defaultScribeEndpoint,
+		Description: "host of the Scribe server"},
+	{Name: "port", Type: config.IntOption, Default: defaultScribePort,
+		Description: "port of the Scribe server"},
+	{Name: "streamName", Type: config.StringOption, Default: defaultScribeStreamName,
+		Description: "category the metrics are logged to"},
+}
 
-	if port, exists := configMap["port"]; exists {
-		s.port = config.GetAsInt(port, defaultScribePort)
-	}
+// Configure accepts the different configuration options for the Scribe handler
+func (s *Scribe) Configure(configMap map[string]interface{}) error {
+	values, err := scribeOptions.Decode(configMap)
+	s.endpoint = values.String("endpoint")
+	s.port = values.Int("port")
+	s.streamName = values.String("streamName")
 
-	if stream, exists := configMap["streamName"]; exists {
-		s.streamName = stream.(string)
-	}
+	return config.JoinErrors(err, s.configureCommonParams(configMap))
+}
 
-	s.configureCommonParams(configMap)
+// Options : the keys understood by the Scribe handler
+func (s *Scribe) Options() config.Options {
+	return append(scribeOptions, commonOptions...)
 }
 
 func (s *Scribe) connectToScribe() {
@@ -107,18 +115,18 @@ func (s *Scribe) Run() {
 	s.run(s.emitMetrics)
 }
 
-func (s *Scribe) emitMetrics(metrics []metric.Metric) bool {
+func (s *Scribe) emitMetrics(metrics []metric.Metric) error {
 	s.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if s.scribeClient == nil {
 		s.log.Warn("Cannot connect to scribe server. Skipping send.")
 		s.connectToScribe()
-		return false
+		return retryable(errors.New("not connected to scribe"))
 	}
 
 	if len(metrics) == 0 {
 		s.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	var encodedMetrics []*scribe.LogEntry
@@ -137,12 +145,12 @@ func (s *Scribe) emitMetrics(metrics []metric.Metric) bool {
 		if err != nil {
 			s.log.Errorf("Failed to write to scribe. Error: %s", err.Error())
 			s.connectToScribe()
-			return false
+			return retryable(err)
 		}
 	}
 
 	s.log.Info("Successfully written ", len(encodedMetrics), " datapoints to Scribe")
-	return true
+	return nil
 }
 
 func (s Scribe) createScribeMetric(m metric.Metric) scribeMetric {
@@ -150,7 +158,7 @@ func (s Scribe) createScribeMetric(m metric.Metric) scribeMetric {
 		Name:       m.Name,
 		Value:      m.Value,
 		MetricType: m.MetricType,
-		Timestamp:  time.Now().Unix(),
+		Timestamp:  m.GetTimestamp().Unix(),
 		Dimensions: m.GetDimensions(s.DefaultDimensions()),
 	}
 
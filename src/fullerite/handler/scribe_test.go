@@ -69,7 +69,8 @@ func TestScribeEmitMetricsNoClient(t *testing.T) {
 
 	m := metric.Metric{}
 	res := s.emitMetrics([]metric.Metric{m})
-	assert.False(t, res, "Should not emit metrics if the scribeClient is nil")
+	assert.NotNil(t, res, "Should not emit metrics if the scribeClient is nil")
+	assert.True(t, isRetryable(res))
 }
 
 func TestScribeEmitMetricsZeroMetrics(t *testing.T) {
@@ -77,7 +78,7 @@ func TestScribeEmitMetricsZeroMetrics(t *testing.T) {
 	s.scribeClient = &MockScribeClient{}
 
 	res := s.emitMetrics([]metric.Metric{})
-	assert.False(t, res, "Should not emit anything if there are not metrics")
+	assert.NotNil(t, res, "Should not emit anything if there are not metrics")
 }
 
 func TestScribeEmitMetrics(t *testing.T) {
@@ -102,7 +103,7 @@ func TestScribeEmitMetrics(t *testing.T) {
 	}
 
 	res := s.emitMetrics(metrics)
-	assert.True(t, res)
+	assert.Nil(t, res)
 
 	assert.Equal(t, "my_stream", m.msg[0].Category)
 	matched, _ := regexp.MatchString(
@@ -6,6 +6,8 @@ import (
 	"fullerite/util"
 
 	"bytes"
+	"errors"
+	"sync"
 	"time"
 
 	l "github.com/Sirupsen/logrus"
@@ -57,26 +59,30 @@ func newSignalFx(
 	return inst
 }
 
+var signalFxOptions = config.Options{
+	{Name: "authToken", Type: config.StringOption, Required: true,
+		Description: "token of the SignalFx ingest API"},
+	{Name: "endpoint", Type: config.StringOption, Required: true,
+		Description: "URL of the SignalFx ingest API"},
+	{Name: "batchByDimension", Type: config.StringOption,
+		Description: "metrics with this dimension are emitted in separate batches per value"},
+	{Name: "perBatchAuthToken", Type: config.StringMapOption,
+		Description: "token to emit each batch with, by batchByDimension value"},
+}
+
 // Configure accepts the different configuration options for the signalfx handler
-func (s *SignalFx) Configure(configMap map[string]interface{}) {
-	if authToken, exists := configMap["authToken"]; exists {
-		s.authToken = authToken.(string)
-	} else {
-		s.log.Error("There was no auth key specified for the SignalFx Handler, there won't be any emissions")
-	}
-	if endpoint, exists := configMap["endpoint"]; exists {
-		s.endpoint = endpoint.(string)
-	} else {
-		s.log.Error("There was no endpoint specified for the SignalFx Handler, there won't be any emissions")
-	}
+func (s *SignalFx) Configure(configMap map[string]interface{}) error {
+	values, err := signalFxOptions.Decode(configMap)
+	s.authToken = values.String("authToken")
+	s.endpoint = values.String("endpoint")
 
-	if batchByDimension, exists := configMap["batchByDimension"]; exists {
-		s.batchByDimension = batchByDimension.(string)
+	if values.Has("batchByDimension") {
+		s.batchByDimension = values.String("batchByDimension")
 		s.log.Info("Batching metrics by dimension: ", s.batchByDimension)
 
 		// Checking if authtoken for batches are specified
-		if perBatchAuthToken, exists := configMap["perBatchAuthToken"]; exists {
-			s.perBatchAuthToken = config.GetAsMap(perBatchAuthToken)
+		if values.Has("perBatchAuthToken") {
+			s.perBatchAuthToken = values.StringMap("perBatchAuthToken")
 			s.log.Info("Loaded authkeys for batches")
 		} else {
 			s.log.Info("Using default authToken for all batches")
@@ -87,7 +93,12 @@ func (s *SignalFx) Configure(configMap map[string]interface{}) {
 		s.OverrideBaseEmissionMetricsReporter()
 	}
 
-	s.configureCommonParams(configMap)
+	return config.JoinErrors(err, s.configureCommonParams(configMap))
+}
+
+// Options : the keys understood by the SignalFx handler
+func (s *SignalFx) Options() config.Options {
+	return append(signalFxOptions, commonOptions...)
 }
 
 // Endpoint returns SignalFx' API endpoint
@@ -119,9 +130,9 @@ func (s SignalFx) convertToProto(incomingMetric metric.Metric) *DataPoint {
 	outname := s.Prefix() + signalFxValueSanitize(incomingMetric.Name)
 	value := incomingMetric.Value
 
-	now := time.Now().UnixNano() / int64(time.Millisecond)
+	timestamp := incomingMetric.GetTimestamp().UnixNano() / int64(time.Millisecond)
 	datapoint := new(DataPoint)
-	datapoint.Timestamp = &now
+	datapoint.Timestamp = &timestamp
 	datapoint.Metric = &outname
 	datapoint.Value = &Datum{
 		DoubleValue: &value,
@@ -191,7 +202,7 @@ func (s *SignalFx) makeBatches(metrics []metric.Metric) map[string][]metric.Metr
 	return m
 }
 
-func (s *SignalFx) emitBatch(batchName string, metrics []metric.Metric) bool {
+func (s *SignalFx) emitBatch(batchName string, metrics []metric.Metric) error {
 	s.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	datapoints := make([]*DataPoint, 0, len(metrics))
@@ -207,14 +218,14 @@ func (s *SignalFx) emitBatch(batchName string, metrics []metric.Metric) bool {
 	if authToken == "" || s.endpoint == "" {
 		s.log.Warn("Skipping emission because we're missing the auth token ",
 			"or the endpoint, payload would have been ", payload)
-		return false
+		return errors.New("missing the auth token or the endpoint")
 	}
 
 	// Serialize the payload
 	serialized, err := proto.Marshal(payload)
 	if err != nil {
 		s.log.Error("Failed to serailize payload ", payload)
-		return false
+		return err
 	}
 
 	customHeader := map[string]string{
@@ -231,7 +242,7 @@ func (s *SignalFx) emitBatch(batchName string, metrics []metric.Metric) bool {
 	if err != nil {
 		s.log.Error("Failed to make request ", err,
 			" to endpoint ", s.endpoint)
-		return false
+		return retryable(err)
 	}
 
 	if rsp.StatusCode != 200 {
@@ -239,16 +250,16 @@ func (s *SignalFx) emitBatch(batchName string, metrics []metric.Metric) bool {
 			" status was ", rsp.StatusCode,
 			" rsp body was ", string(rsp.Body),
 			" payload was ", payload)
-		return false
+		return statusError("SignalFx", rsp.StatusCode)
 	}
 
 	s.log.Info("Successfully sent ", len(datapoints), " datapoints to SignalFx")
-	return true
+	return nil
 }
 
-func (s *SignalFx) emitAndTime(batchName string, metrics []metric.Metric) bool {
+func (s *SignalFx) emitAndTime(batchName string, metrics []metric.Metric) error {
 	start := time.Now()
-	emissionResult := s.emitBatch(batchName, metrics)
+	err := s.emitBatch(batchName, metrics)
 	elapsed := time.Since(start)
 
 	// Report emission metrics if emission tracker is disabled in base handler
@@ -258,17 +269,17 @@ func (s *SignalFx) emitAndTime(batchName string, metrics []metric.Metric) bool {
 			duration:    elapsed,
 			metricsSent: len(metrics),
 		}
-		s.reportEmissionMetrics(emissionResult, timing)
+		s.reportEmissionMetrics(err == nil, timing)
 	}
 
-	return emissionResult
+	return err
 }
 
-func (s *SignalFx) emitMetrics(metrics []metric.Metric) bool {
+func (s *SignalFx) emitMetrics(metrics []metric.Metric) error {
 
 	if len(metrics) == 0 {
 		s.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
 	if s.batchByDimension == "" {
@@ -280,8 +291,32 @@ func (s *SignalFx) emitMetrics(metrics []metric.Metric) bool {
 	// If batchByDimension key is defined,
 	// then divide the list of metrics into batches,
 	// emit them concurrently (or parallely, if GOMAXPROCS is > 1)
+	// and wait for all of them, so that a flush on stop is complete.
+	// Only the batches that failed are retried.
+	var (
+		batches sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		failed  []metric.Metric
+		retry   bool
+	)
 	for batchName, metricBatch := range s.makeBatches(metrics) {
-		go s.emitAndTime(batchName, metricBatch)
+		batches.Add(1)
+		go func(batchName string, metricBatch []metric.Metric) {
+			defer batches.Done()
+			if err := s.emitAndTime(batchName, metricBatch); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+				failed = append(failed, metricBatch...)
+				retry = retry || isRetryable(err)
+			}
+		}(batchName, metricBatch)
+	}
+	batches.Wait()
+
+	if len(errs) == 0 {
+		return nil
 	}
-	return true
+	return partialError{emissionError{config.JoinErrors(errs...), retry}, failed}
 }
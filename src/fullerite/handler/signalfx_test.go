@@ -2,10 +2,12 @@ package handler
 
 import (
 	"fullerite/metric"
+	"fullerite/util"
 
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -135,3 +137,46 @@ func TestSignalFxSanitation(t *testing.T) {
 		}
 	}
 }
+
+func TestSignalFxRetriesOnlyFailedBatches(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Sf-Token")
+		mu.Lock()
+		requests[token]++
+		first := requests[token] == 1
+		mu.Unlock()
+		if token == "tokenB" && first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	s := getTestSignalfxHandler(12, 12, 12)
+	s.Configure(map[string]interface{}{
+		"authToken":         "secret",
+		"endpoint":          ts.URL,
+		"batchByDimension":  "service",
+		"perBatchAuthToken": map[string]interface{}{"a": "tokenA", "b": "tokenB"},
+		"retryBackoff":      "0.001",
+	})
+
+	s.httpClient = new(util.HTTPAlive)
+	s.httpClient.Configure(time.Second, time.Second, 1)
+	s.emissionTimingChannel = make(chan emissionTiming, 10)
+
+	metrics := []metric.Metric{metric.New("test"), metric.New("test")}
+	metrics[0].AddDimension("service", "a")
+	metrics[1].AddDimension("service", "b")
+
+	// the batch that went through isn't sent again
+	assert.Nil(t, s.emitWithRetry(metrics, s.emitMetrics))
+	assert.Equal(t, map[string]int{"tokenA": 1, "tokenB": 2}, requests)
+
+	requests = map[string]int{}
+	err := s.emitMetrics(metrics)
+	assert.NotNil(t, err)
+	assert.True(t, isRetryable(err))
+	assert.Equal(t, metrics[1:], unsent(metrics, err))
+}
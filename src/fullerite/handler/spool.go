@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+)
+
+// Defaults for the on-disk spool of a handler
+const (
+	DefaultSpoolMaxSizeMB = 100
+	DefaultSpoolMaxAgeSec = 3600
+)
+
+const spoolFileSuffix = ".batch"
+
+// spool is an on-disk queue of the batches a handler failed to emit.
+// Every batch is written to its own file named after the time it was
+// spooled, so sorting the file names gives back the order to replay in.
+type spool struct {
+	mu        sync.Mutex
+	dir       string
+	maxBytes  int64
+	maxAge    time.Duration
+	log       *l.Entry
+	seq       uint64
+	replaying bool
+
+	// for tracking
+	batches  int
+	bytes    int64
+	spooled  uint64
+	replayed uint64
+	expired  uint64
+	evicted  uint64
+}
+
+type spoolFile struct {
+	name    string
+	size    int64
+	created time.Time
+	metrics int
+}
+
+func newSpool(dir string, maxBytes int64, maxAge time.Duration, log *l.Entry) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		log:      log,
+	}
+
+	// pick up what a previous run left behind
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	s.enforceLimits(files)
+	if s.batches > 0 {
+		s.log.Info("Found ", s.batches, " spooled batches in ", dir)
+	}
+	return s, nil
+}
+
+// add writes a batch that failed to be emitted to the spool,
+// the oldest batches are dropped to stay within the limits
+func (s *spool) add(metrics []metric.Metric) error {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int64(len(data)) > s.maxBytes {
+		s.evicted += uint64(len(metrics))
+		return fmt.Errorf("batch of %d bytes does not fit in the spool", len(data))
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%019d-%06d-%d%s", time.Now().UnixNano(), s.seq%1000000, len(metrics), spoolFileSuffix)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.spooled += uint64(len(metrics))
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	s.enforceLimits(files)
+	return nil
+}
+
+// replay emits the spooled batches oldest first and stops at the first
+// failure, the batch that failed stays in the spool for the next attempt
+// without the metrics that went through. It also stops once the stop
+// channel is closed, leaving the rest for the next run. Only one replay
+// runs at a time.
+func (s *spool) replay(stop <-chan struct{}, emitFunc func([]metric.Metric) error) {
+	s.mu.Lock()
+	if s.replaying || s.batches == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.replaying = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.replaying = false
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		files, err := s.files()
+		if err == nil {
+			files = s.enforceLimits(files)
+		}
+		s.mu.Unlock()
+		if err != nil {
+			s.log.Error("Failed to read the spool: ", err)
+			return
+		}
+		if len(files) == 0 {
+			return
+		}
+
+		oldest := files[0]
+		metrics, err := s.read(oldest)
+		if err != nil {
+			s.log.Error("Discarding unreadable spooled batch ", oldest.name, ": ", err)
+			s.remove(oldest)
+			continue
+		}
+
+		if err := emitFunc(metrics); err != nil {
+			s.log.Warn("Failed to replay spooled batch ", oldest.name, ", will retry later: ", err)
+			if failed := unsent(metrics, err); len(failed) < len(metrics) {
+				s.rewrite(oldest, failed, len(metrics)-len(failed))
+			}
+			return
+		}
+		s.remove(oldest)
+
+		s.mu.Lock()
+		s.replayed += uint64(len(metrics))
+		s.mu.Unlock()
+		s.log.Info("Replayed ", len(metrics), " spooled metrics")
+	}
+}
+
+// rewrite replaces a spooled batch of which some metrics were replayed with
+// the ones that are left, the new file keeps the place of the batch in the spool
+func (s *spool) rewrite(f spoolFile, metrics []metric.Metric, replayed int) {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		s.log.Error("Failed to rewrite spooled batch ", f.name, ": ", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replayed += uint64(replayed)
+
+	parts := strings.Split(strings.TrimSuffix(f.name, spoolFileSuffix), "-")
+	name := fmt.Sprintf("%s-%s-%d%s", parts[0], parts[1], len(metrics), spoolFileSuffix)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		s.log.Error("Failed to rewrite spooled batch ", f.name, ": ", err)
+		return
+	}
+	os.Remove(filepath.Join(s.dir, f.name))
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		s.log.Error("Failed to rewrite spooled batch ", f.name, ": ", err)
+		return
+	}
+	s.bytes += int64(len(data)) - f.size
+}
+
+func (s *spool) read(f spoolFile) ([]metric.Metric, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, f.name))
+	if err != nil {
+		return nil, err
+	}
+	metrics := []metric.Metric{}
+	err = json.Unmarshal(data, &metrics)
+	return metrics, err
+}
+
+func (s *spool) remove(f spoolFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(filepath.Join(s.dir, f.name)); err != nil {
+		// a batch that is already gone was dropped to enforce the limits
+		if !os.IsNotExist(err) {
+			s.log.Error("Failed to remove spooled batch ", f.name, ": ", err)
+		}
+		return
+	}
+	s.batches--
+	s.bytes -= f.size
+}
+
+// files lists the spooled batches oldest first, must be called with the lock held
+func (s *spool) files() ([]spoolFile, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []spoolFile{}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, spoolFileSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(name, spoolFileSuffix), "-")
+		if len(parts) != 3 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		count, _ := strconv.Atoi(parts[2])
+		files = append(files, spoolFile{
+			name:    name,
+			size:    info.Size(),
+			created: time.Unix(0, nanos),
+			metrics: count,
+		})
+	}
+	sort.Sort(byName(files))
+	return files, nil
+}
+
+// enforceLimits drops the batches older than the max age and then the oldest
+// ones until the spool fits in its size cap, it returns what is left.
+// Must be called with the lock held.
+func (s *spool) enforceLimits(files []spoolFile) []spoolFile {
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	minTime := time.Now().Add(-s.maxAge)
+	for len(files) > 0 && (files[0].created.Before(minTime) || total > s.maxBytes) {
+		oldest := files[0]
+		if err := os.Remove(filepath.Join(s.dir, oldest.name)); err != nil && !os.IsNotExist(err) {
+			s.log.Error("Failed to remove spooled batch ", oldest.name, ": ", err)
+			break
+		}
+		if oldest.created.Before(minTime) {
+			s.expired += uint64(oldest.metrics)
+		} else {
+			s.evicted += uint64(oldest.metrics)
+		}
+		total -= oldest.size
+		files = files[1:]
+	}
+
+	s.batches = len(files)
+	s.bytes = total
+	return files
+}
+
+// stats adds the spool counters and gauges to the handler's internal metrics
+func (s *spool) stats(counters, gauges map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counters["metricsSpooled"] = float64(s.spooled)
+	counters["metricsReplayed"] = float64(s.replayed)
+	counters["spoolMetricsExpired"] = float64(s.expired)
+	counters["spoolMetricsEvicted"] = float64(s.evicted)
+	gauges["spoolBatches"] = float64(s.batches)
+	gauges["spoolBytes"] = float64(s.bytes)
+}
+
+type byName []spoolFile
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f byName) Less(i, j int) bool { return f[i].name < f[j].name }
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"fullerite/metric"
+
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestSpool(t *testing.T, maxBytes int64, maxAge time.Duration) (*spool, func()) {
+	dir, err := ioutil.TempDir("", "fullerite_spool")
+	assert.Nil(t, err)
+	s, err := newSpool(dir, maxBytes, maxAge, l.WithField("testing", "spool"))
+	assert.Nil(t, err)
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestSpoolReplaysInOrder(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, time.Hour)
+	defer cleanup()
+
+	first := metric.WithValue("first", 1)
+	first.Timestamp = time.Unix(1476748800, 0)
+	assert.Nil(t, s.add([]metric.Metric{first}))
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("second", 2), metric.WithValue("third", 3)}))
+
+	emitted := []metric.Metric{}
+	s.replay(nil, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics...)
+		return nil
+	})
+
+	assert.Equal(t, 3, len(emitted))
+	assert.Equal(t, "first", emitted[0].Name)
+	assert.Equal(t, first.Timestamp, emitted[0].Timestamp)
+	assert.Equal(t, "second", emitted[1].Name)
+	assert.Equal(t, "third", emitted[2].Name)
+
+	counters := map[string]float64{}
+	gauges := map[string]float64{}
+	s.stats(counters, gauges)
+	assert.Equal(t, 3.0, counters["metricsSpooled"])
+	assert.Equal(t, 3.0, counters["metricsReplayed"])
+	assert.Equal(t, 0.0, gauges["spoolBatches"])
+	assert.Equal(t, 0.0, gauges["spoolBytes"])
+}
+
+func TestSpoolReplayStopsOnFailure(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, time.Hour)
+	defer cleanup()
+
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("first", 1)}))
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("second", 2)}))
+
+	attempts := 0
+	s.replay(nil, func(metrics []metric.Metric) error {
+		attempts++
+		if attempts > 1 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	assert.Equal(t, 2, attempts)
+
+	emitted := []metric.Metric{}
+	s.replay(nil, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics...)
+		return nil
+	})
+	assert.Equal(t, 1, len(emitted))
+	assert.Equal(t, "second", emitted[0].Name)
+}
+
+func TestSpoolKeepsWhatAPartialReplayDidNotSend(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, time.Hour)
+	defer cleanup()
+
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("first", 1), metric.WithValue("second", 2)}))
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("third", 3)}))
+
+	s.replay(nil, func(metrics []metric.Metric) error {
+		return partialError{emissionError{errors.New("rejected"), true}, metrics[1:]}
+	})
+
+	emitted := []metric.Metric{}
+	s.replay(nil, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics...)
+		return nil
+	})
+	assert.Equal(t, 2, len(emitted))
+	assert.Equal(t, "second", emitted[0].Name)
+	assert.Equal(t, "third", emitted[1].Name)
+
+	counters := map[string]float64{}
+	s.stats(counters, map[string]float64{})
+	assert.Equal(t, 3.0, counters["metricsReplayed"])
+}
+
+func TestSpoolReplayStopsWithTheHandler(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, time.Hour)
+	defer cleanup()
+
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("first", 1)}))
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("second", 2)}))
+
+	stop := make(chan struct{})
+	attempts := 0
+	s.replay(stop, func(metrics []metric.Metric) error {
+		attempts++
+		close(stop)
+		return nil
+	})
+	assert.Equal(t, 1, attempts)
+
+	gauges := map[string]float64{}
+	s.stats(map[string]float64{}, gauges)
+	assert.Equal(t, 1.0, gauges["spoolBatches"])
+}
+
+func TestSpoolEvictsOldestOverSizeCap(t *testing.T) {
+	// only one of the batches fits in the cap
+	s, cleanup := buildTestSpool(t, 100, time.Hour)
+	defer cleanup()
+
+	for _, name := range []string{"first", "second", "third"} {
+		assert.Nil(t, s.add([]metric.Metric{metric.WithValue(name, 1)}))
+	}
+
+	counters := map[string]float64{}
+	gauges := map[string]float64{}
+	s.stats(counters, gauges)
+	assert.Equal(t, 3.0, counters["metricsSpooled"])
+	assert.Equal(t, 2.0, counters["spoolMetricsEvicted"])
+	assert.Equal(t, 1.0, gauges["spoolBatches"])
+
+	emitted := []metric.Metric{}
+	s.replay(nil, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics...)
+		return nil
+	})
+	assert.Equal(t, 1, len(emitted))
+	assert.Equal(t, "third", emitted[0].Name)
+}
+
+func TestSpoolExpiresOldBatches(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, 50*time.Millisecond)
+	defer cleanup()
+
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("old", 1)}))
+	time.Sleep(100 * time.Millisecond)
+
+	called := false
+	s.replay(nil, func(metrics []metric.Metric) error {
+		called = true
+		return nil
+	})
+	assert.False(t, called)
+
+	counters := map[string]float64{}
+	s.stats(counters, map[string]float64{})
+	assert.Equal(t, 1.0, counters["spoolMetricsExpired"])
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	s, cleanup := buildTestSpool(t, 1024*1024, time.Hour)
+	defer cleanup()
+	assert.Nil(t, s.add([]metric.Metric{metric.WithValue("first", 1)}))
+
+	restarted, err := newSpool(s.dir, s.maxBytes, s.maxAge, s.log)
+	assert.Nil(t, err)
+	emitted := []metric.Metric{}
+	restarted.replay(nil, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics...)
+		return nil
+	})
+	assert.Equal(t, 1, len(emitted))
+}
+
+func TestHandlerSpoolsFailedEmissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite_spool")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	base := BaseHandler{}
+	base.name = "Test"
+	base.log = l.WithField("testing", "basehandler_spool")
+	base.emissionTimingChannel = make(chan emissionTiming, 10)
+	base.configureCommonParams(map[string]interface{}{"spoolDir": dir})
+	assert.NotNil(t, base.spool)
+	assert.Equal(t, filepath.Join(dir, "Test"), base.spool.dir)
+
+	base.emitAndTime([]metric.Metric{metric.New("failed")}, func([]metric.Metric) error {
+		return errors.New("rejected")
+	})
+	assert.Equal(t, 1.0, base.InternalMetrics().Counters["metricsSpooled"])
+	assert.Equal(t, 0.0, base.InternalMetrics().Counters["metricsDropped"], "a spooled metric is not dropped")
+	assert.Equal(t, 1.0, base.InternalMetrics().Gauges["spoolBatches"])
+
+	// the spooled batch is replayed right after the next successful emission
+	emitted := []string{}
+	base.emitAndTime([]metric.Metric{metric.New("sent")}, func(metrics []metric.Metric) error {
+		emitted = append(emitted, metrics[0].Name)
+		return nil
+	})
+	assert.Equal(t, []string{"sent", "failed"}, emitted)
+	assert.Equal(t, 0.0, base.InternalMetrics().Gauges["spoolBatches"])
+	assert.Equal(t, 1.0, base.InternalMetrics().Counters["metricsReplayed"])
+	assert.Equal(t, 0.0, base.InternalMetrics().Counters["metricsDropped"])
+}
+
+func TestHandlerSpoolPerInstance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fullerite_spool")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := New("Log")
+	second := New("Log other")
+	assert.Nil(t, first.Configure(map[string]interface{}{"spoolDir": dir}))
+	assert.Nil(t, second.Configure(map[string]interface{}{"spoolDir": dir}))
+
+	assert.Equal(t, filepath.Join(dir, "Log"), first.(*Log).spool.dir)
+	assert.Equal(t, filepath.Join(dir, "Log_other"), second.(*Log).spool.dir)
+}
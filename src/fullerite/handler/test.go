@@ -33,8 +33,8 @@ func NewTest(
 }
 
 // Configure accepts the different configuration options for the Test handler
-func (h *Test) Configure(configMap map[string]interface{}) {
-	h.configureCommonParams(configMap)
+func (h *Test) Configure(configMap map[string]interface{}) error {
+	return h.configureCommonParams(configMap)
 }
 
 // Run runs the handler main loop
@@ -42,13 +42,13 @@ func (h *Test) Run() {
 	h.run(h.emitMetrics)
 }
 
-func (h *Test) emitMetrics(metrics []metric.Metric) bool {
+func (h *Test) emitMetrics(metrics []metric.Metric) error {
 	h.log.Info("Starting to emit ", len(metrics), " metrics")
 
 	if len(metrics) == 0 {
 		h.log.Warn("Skipping send because of an empty payload")
-		return false
+		return errEmptyPayload
 	}
 
-	return true
+	return nil
 }
@@ -3,12 +3,20 @@ package main
 import (
 	"fullerite/config"
 	"fullerite/handler"
+	"fullerite/internalserver"
 	"fullerite/metric"
+
+	"errors"
+	"sync"
+	"time"
 )
 
 func createHandlers(c config.Config) (handlers []handler.Handler) {
 	for name, config := range c.Handlers {
-		handlers = append(handlers, createHandler(name, c, config))
+		handlerInst := createHandler(name, c, config)
+		if handlerInst != nil {
+			handlers = append(handlers, handlerInst)
+		}
 	}
 	return handlers
 }
@@ -19,13 +27,10 @@ func createHandler(name string, globalConfig config.Config, instanceConfig map[s
 		return nil
 	}
 
-	// apply any global configs
-	handlerInst.SetInterval(config.GetAsInt(globalConfig.Interval, handler.DefaultInterval))
-	handlerInst.SetPrefix(globalConfig.Prefix)
-	handlerInst.SetDefaultDimensions(globalConfig.DefaultDimensions)
-
-	// now apply the handler level configs
-	handlerInst.Configure(instanceConfig)
+	if err := configureHandler(handlerInst, globalConfig, instanceConfig); err != nil {
+		log.Error("Handler ", name, " is misconfigured, not starting it: ", err)
+		return nil
+	}
 
 	// now run a listener channel for each collector
 	handlerInst.InitListeners(globalConfig)
@@ -33,6 +38,16 @@ func createHandler(name string, globalConfig config.Config, instanceConfig map[s
 	return handlerInst
 }
 
+// configureHandler applies the global and then the handler level configs,
+// check-config goes through it too so that it fails the same configs the
+// daemon does
+func configureHandler(handlerInst handler.Handler, globalConfig config.Config, instanceConfig map[string]interface{}) error {
+	handlerInst.SetInterval(config.GetAsInt(globalConfig.Interval, handler.DefaultInterval))
+	handlerInst.SetPrefix(globalConfig.Prefix)
+	handlerInst.SetDefaultDimensions(globalConfig.DefaultDimensions)
+	return handlerInst.Configure(instanceConfig)
+}
+
 func startHandlers(handlers []handler.Handler) {
 	log.Info("Starting handlers...")
 	for _, handler := range handlers {
@@ -42,8 +57,113 @@ func startHandlers(handlers []handler.Handler) {
 	}
 }
 
-func writeToHandlers(handlers []handler.Handler, metric metric.Metric) {
-	for i := range handlers {
-		handlers[i].Channel() <- metric
+// stopHandlers stops all the handlers in parallel, each one
+// flushes its buffers within its own stop timeout
+func stopHandlers(handlers []handler.Handler) {
+	log.Info("Stopping handlers...")
+	var wg sync.WaitGroup
+	for _, h := range handlers {
+		if h != nil {
+			wg.Add(1)
+			go func(h handler.Handler) {
+				defer wg.Done()
+				h.Stop()
+			}(h)
+		}
+	}
+	wg.Wait()
+}
+
+// handlerWriteTimeout is how long a write to the channel of a handler
+// waits for the handler to read it
+var handlerWriteTimeout = 5 * time.Second
+
+// handlerSet holds the running handlers the collectors write to. The handlers
+// and their collector endpoints can be swapped on a config reload, the writers
+// only hold the lock to pick the endpoints and send without it, so a reload
+// doesn't wait for a handler to make room. The endpoint of a handler that was
+// taken out lets go of its writers once the handler stops.
+type handlerSet struct {
+	sync.RWMutex
+	handlers []handler.Handler
+}
+
+func newHandlerSet(handlers []handler.Handler) *handlerSet {
+	return &handlerSet{handlers: handlers}
+}
+
+// List returns a copy of the running handlers
+func (s *handlerSet) List() []handler.Handler {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]handler.Handler{}, s.handlers...)
+}
+
+// writeToCollectorEndpoints sends the metric to every handler listening
+// for the given collector
+func (s *handlerSet) writeToCollectorEndpoints(collectorName string, m metric.Metric) {
+	for _, collectorEnd := range s.collectorEndpoints(collectorName) {
+		collectorEnd.Send(m)
+	}
+}
+
+// collectorEndpoints returns the endpoints of the handlers listening for
+// the given collector, a reload swaps them holding the lock
+func (s *handlerSet) collectorEndpoints(collectorName string) []handler.CollectorEnd {
+	s.RLock()
+	defer s.RUnlock()
+	endpoints := make([]handler.CollectorEnd, 0, len(s.handlers))
+	for _, h := range s.handlers {
+		if collectorEnd, exists := h.CollectorEndpoints()[collectorName]; exists {
+			endpoints = append(endpoints, collectorEnd)
+		}
+	}
+	return endpoints
+}
+
+// flushHandler sends a sentinel to every channel of the handler so that
+// all its buffers are flushed, it gives up on a channel nobody reads
+// within timeout
+func (s *handlerSet) flushHandler(name string, timeout time.Duration) error {
+	s.RLock()
+	var channels []chan metric.Metric
+	for _, h := range s.handlers {
+		if h.CanonicalName() == name {
+			channels = []chan metric.Metric{h.Channel()}
+			for _, collectorEnd := range h.CollectorEndpoints() {
+				channels = append(channels, collectorEnd.Channel)
+			}
+			break
+		}
+	}
+	s.RUnlock()
+
+	if channels == nil {
+		return internalserver.ErrUnknownComponent
+	}
+	for _, channel := range channels {
+		select {
+		case channel <- metric.Sentinel():
+		case <-time.After(timeout):
+			return errors.New("is too busy to flush")
+		}
+	}
+	return nil
+}
+
+// writeToHandlers sends the metric to every handler's own channel
+func (s *handlerSet) writeToHandlers(m metric.Metric) {
+	writeToHandlers(s.List(), m)
+}
+
+// writeToHandlers gives up on a handler that doesn't read the metric
+// within handlerWriteTimeout, like one that stopped
+func writeToHandlers(handlers []handler.Handler, m metric.Metric) {
+	for _, h := range handlers {
+		select {
+		case h.Channel() <- m:
+		case <-time.After(handlerWriteTimeout):
+			log.Warn("Handler ", h.Name(), " didn't take the metric ", m.Name, " in time, dropping it")
+		}
 	}
 }
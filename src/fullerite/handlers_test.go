@@ -31,6 +31,32 @@ func TestStartHandlerUnknownHandler(t *testing.T) {
 	assert.Nil(t, h)
 }
 
+func TestStartHandlerMisconfigured(t *testing.T) {
+	logrus.SetLevel(logrus.PanicLevel)
+
+	c := map[string]interface{}{"port": "2003"}
+	h := createHandler("Graphite", config.Config{}, c)
+
+	assert.Nil(t, h, "should NOT create a Graphite handler without a server")
+}
+
+func TestCreateHandlersSkipsMisconfigured(t *testing.T) {
+	logrus.SetLevel(logrus.PanicLevel)
+
+	c := config.Config{Handlers: map[string]map[string]interface{}{
+		"Graphite": {"port": "2003"},
+		"Log":      {},
+		"Unknown":  {},
+	}}
+	handlers := createHandlers(c)
+
+	assert.Equal(t, 1, len(handlers))
+	assert.Equal(t, "Log", handlers[0].Name())
+	for _, h := range handlers {
+		h.Stop()
+	}
+}
+
 func checkEmission(t *testing.T, coll string, h handler.Handler, expected bool) {
 	m := metric.Metric{
 		Name:       "test",
@@ -100,3 +126,60 @@ func TestCanSendMetrics(t *testing.T) {
 	checkEmission(t, "coll2", h, true)
 	checkEmission(t, "coll3", h, true)
 }
+
+func TestWritersDoNotHoldUpReloads(t *testing.T) {
+	logrus.SetLevel(logrus.PanicLevel)
+
+	h := createHandler("Log", config.Config{Collectors: []string{"Test"}}, map[string]interface{}{})
+	set := newHandlerSet([]handler.Handler{h})
+
+	// nothing reads the endpoint, the second write blocks
+	written := make(chan struct{})
+	go func() {
+		set.writeToCollectorEndpoints("Test", metric.New("first"))
+		set.writeToCollectorEndpoints("Test", metric.New("second"))
+		close(written)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	swapped := make(chan struct{})
+	go func() {
+		set.Lock()
+		set.handlers = nil
+		set.Unlock()
+		close(swapped)
+	}()
+	select {
+	case <-swapped:
+	case <-time.After(time.Second):
+		t.Fatal("A writer blocked on a handler held up the swap")
+	}
+
+	// stopping the handler that was taken out lets go of the writer
+	h.Stop()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("A writer is still blocked on a stopped handler")
+	}
+}
+
+func TestWriteToHandlersGivesUpOnStoppedHandler(t *testing.T) {
+	logrus.SetLevel(logrus.PanicLevel)
+	defer func(timeout time.Duration) { handlerWriteTimeout = timeout }(handlerWriteTimeout)
+	handlerWriteTimeout = 50 * time.Millisecond
+
+	h := createHandler("Log", config.Config{}, map[string]interface{}{})
+	h.Stop()
+
+	written := make(chan struct{})
+	go func() {
+		newHandlerSet([]handler.Handler{h}).writeToHandlers(metric.New("test"))
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Writing to a stopped handler should time out")
+	}
+}
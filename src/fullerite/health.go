@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fullerite/handler"
+
+	"fmt"
+	"sort"
+	"time"
+)
+
+// staleCollections is how many collections a collector can go without
+// succeeding or sending a metric before it is reported as unhealthy
+const staleCollections = 3
+
+// healthProblems lists what makes fullerite unhealthy: the handlers that
+// failed continuously and the collectors that stopped producing
+func healthProblems(handlers *handlerSet, now time.Time) []string {
+	problems := handlerProblems(handlers)
+	problems = append(problems, collectorProblems(now)...)
+	return problems
+}
+
+// handlerProblems reports the handlers whose circuit breaker opened, which
+// failed as many times in a row as it takes to open one, or which were
+// disabled for panicking too often
+func handlerProblems(handlers *handlerSet) []string {
+	problems := []string{}
+	for _, h := range handlers.List() {
+		gauges := h.InternalMetrics().Gauges
+		switch {
+		case gauges["handlerDisabled"] == 1:
+			problems = append(problems, fmt.Sprintf("handler %s was disabled after panicking too many times", h.Name()))
+		case gauges["circuitBreakerState"] != 0 || gauges["consecutiveFailures"] >= handler.DefaultCircuitBreakerThreshold:
+			problems = append(problems, fmt.Sprintf("handler %s failed to emit %.0f times in a row", h.Name(), gauges["consecutiveFailures"]))
+		}
+	}
+	return problems
+}
+
+// collectorProblems reports the collectors which were disabled for panicking
+// too often, or which went staleCollections collections without succeeding
+// or sending a metric
+func collectorProblems(now time.Time) []string {
+	collectorSchedules.RLock()
+	schedules := make(map[string]collectorSchedule, len(collectorSchedules.schedules))
+	for name, s := range collectorSchedules.schedules {
+		schedules[name] = *s
+	}
+	collectorSchedules.RUnlock()
+
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+	problems := []string{}
+	for name, h := range collectorsHealth.collectors {
+		s, exists := schedules[name]
+		if !exists {
+			continue
+		}
+		if s.disabled {
+			problems = append(problems, fmt.Sprintf("collector %s was disabled after panicking too many times", name))
+			continue
+		}
+
+		period := s.schedule.Next(s.next, s.next).Sub(s.next)
+		last := latest(h.started.Add(s.schedule.Offset), h.lastSuccess, h.lastMetricTime())
+		if now.Sub(last) > staleCollections*period {
+			problems = append(problems, fmt.Sprintf("collector %s has neither collected nor sent a metric since %s",
+				name, last.Format(time.RFC3339)))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func latest(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/handler"
+	"fullerite/metric"
+	"fullerite/test_utils"
+
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gaugesHandler reports the given gauges as its internal metrics
+type gaugesHandler struct {
+	handler.Handler
+	gauges map[string]float64
+}
+
+func (h gaugesHandler) InternalMetrics() metric.InternalMetrics {
+	return metric.InternalMetrics{Counters: map[string]float64{}, Gauges: h.gauges}
+}
+
+func TestHandlerProblems(t *testing.T) {
+	newHandler := func(gauges map[string]float64) handler.Handler {
+		h := handler.NewTest(make(chan metric.Metric), 10, 10, time.Second, test_utils.BuildLogger())
+		return gaugesHandler{h, gauges}
+	}
+	handlers := newHandlerSet([]handler.Handler{
+		newHandler(map[string]float64{"consecutiveFailures": 1}),
+		newHandler(map[string]float64{"consecutiveFailures": 5}),
+		newHandler(map[string]float64{"circuitBreakerState": 1, "consecutiveFailures": 2}),
+		newHandler(map[string]float64{"handlerDisabled": 1}),
+	})
+
+	problems := handlerProblems(handlers)
+	assert.Equal(t, []string{
+		"handler Test failed to emit 5 times in a row",
+		"handler Test failed to emit 2 times in a row",
+		"handler Test was disabled after panicking too many times",
+	}, problems)
+}
+
+func TestCollectorProblems(t *testing.T) {
+	schedule := collector.Schedule{Interval: 10 * time.Second}
+	now := time.Now()
+	collectors := make(map[string]collector.Collector)
+	for _, name := range []string{"Fresh", "Stale", "Producing", "Disabled"} {
+		col := collector.New("Test")
+		col.SetCanonicalName(name)
+		addCollectorSchedule(col).set(schedule, now.Add(5*time.Second))
+		addCollectorHealth(col)
+		defer removeCollectorSchedule(col)
+		defer removeCollectorHealth(col)
+		collectors[name] = col
+	}
+	collectorScheduleOf(collectors["Disabled"]).disable()
+
+	// collectors get a few collections to show up
+	assert.Equal(t, []string{"collector Disabled was disabled after panicking too many times"}, collectorProblems(now))
+
+	// a minute later
+	for _, name := range []string{"Fresh", "Stale", "Producing"} {
+		updateCollectorHealth(name, func(h *collectorHealth) {
+			h.started = h.started.Add(-time.Minute)
+		})
+	}
+	collectorHealthOf(collectors["Fresh"]).collectionStarted(now.Add(-5 * time.Second))
+	collectorHealthOf(collectors["Fresh"]).collectionFinished(now.Add(-4*time.Second), true)
+	collectorHealthOf(collectors["Producing"]).countMetric()
+
+	problems := collectorProblems(time.Now())
+	if assert.Equal(t, 2, len(problems)) {
+		assert.Equal(t, "collector Disabled was disabled after panicking too many times", problems[0])
+		assert.Contains(t, problems[1], "collector Stale has neither collected nor sent a metric since ")
+	}
+}
@@ -0,0 +1,180 @@
+package internalserver
+
+import (
+	"fullerite/config"
+	"fullerite/metric"
+
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultControlPath = "/control/"
+
+	// how long a log level set through the control API lasts by default
+	defaultLogLevelDuration = 10 * time.Minute
+)
+
+// ErrUnknownComponent is returned by a Controller for a collector
+// or a handler that isn't running
+var ErrUnknownComponent = errors.New("is not running")
+
+// Controller carries out the actions of the control API, the collectors and
+// the handlers are named as in the configuration. The errors tell what is
+// wrong with the component, the way ErrUnknownComponent does.
+type Controller interface {
+	PauseCollector(name string) error
+	ResumeCollector(name string) error
+	Collect(name string) error
+	FlushHandler(name string) error
+	// SetLogLevel changes the log level of a "collector" or a "handler" for a while
+	SetLogLevel(kind string, name string, level string, duration time.Duration) error
+}
+
+// controlOptions are the keys of the internal server config about the
+// control API, the token can be a secret reference
+var controlOptions = config.Options{
+	{Name: "controlToken", Type: config.StringOption,
+		Description: "bearer token the control API requests have to carry, the API is off without one"},
+	{Name: "controlPath", Type: config.StringOption, Default: defaultControlPath,
+		Description: "path the control API is served under"},
+}
+
+// controlStats counts the control requests by action
+type controlStats struct {
+	sync.Mutex
+	counts map[string]uint64
+}
+
+func (s *controlStats) count(name string) {
+	s.Lock()
+	defer s.Unlock()
+	s.counts[name]++
+}
+
+func (s *controlStats) metrics() *metric.InternalMetrics {
+	s.Lock()
+	defer s.Unlock()
+	m := metric.NewInternalMetrics()
+	for name, count := range s.counts {
+		m.Counters[name] = float64(count)
+	}
+	return m
+}
+
+// SetController enables the control API on the control path,
+// provided a control token is configured
+func (srv *InternalServer) SetController(c Controller) {
+	srv.controller = c
+}
+
+// configureControl reads the control token and path, a token that
+// can't be read leaves the control API off
+func (srv *InternalServer) configureControl(cfgMap map[string]interface{}) {
+	values, err := controlOptions.Decode(cfgMap)
+	if err != nil {
+		srv.log.Error("The control API is off: ", err)
+		return
+	}
+	srv.controlToken = values.String("controlToken")
+	srv.controlPath = values.String("controlPath")
+	if !strings.HasSuffix(srv.controlPath, "/") {
+		srv.controlPath += "/"
+	}
+	srv.control = &controlStats{counts: make(map[string]uint64)}
+}
+
+func (srv InternalServer) controlEnabled() bool {
+	return srv.controller != nil && srv.controlToken != "" && srv.control != nil
+}
+
+// authorized checks the bearer token of the request
+func (srv InternalServer) authorized(req *http.Request) bool {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(srv.controlToken)) == 1
+}
+
+// carries out a POST on <control path>/<collectors|handlers>/<name>/<action>:
+// pause, resume and collect for a collector, flush for a handler and loglevel
+// for both, which takes the level and the duration in seconds as parameters
+func (srv InternalServer) handleControlRequest(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "control requires a POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !srv.authorized(req) {
+		srv.control.count("unauthorized")
+		srv.log.Warn("Unauthorized control request from ", req.RemoteAddr, " on ", req.URL.Path)
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(writer, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// the requests that can't be carried out are counted and logged alike
+	reject := func(message string, status int) {
+		srv.control.count("failed")
+		srv.log.Warn("Control request from ", req.RemoteAddr, " on ", req.URL.Path, " failed: ", message)
+		http.Error(writer, message, status)
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, srv.controlPath), "/"), "/")
+	if len(parts) != 3 {
+		reject("expected <collectors|handlers>/<name>/<action>", http.StatusNotFound)
+		return
+	}
+	kind, name, action := parts[0], parts[1], parts[2]
+
+	var err error
+	switch kind + " " + action {
+	case "collectors pause":
+		err = srv.controller.PauseCollector(name)
+	case "collectors resume":
+		err = srv.controller.ResumeCollector(name)
+	case "collectors collect":
+		err = srv.controller.Collect(name)
+	case "handlers flush":
+		err = srv.controller.FlushHandler(name)
+	case "collectors loglevel", "handlers loglevel":
+		duration := defaultLogLevelDuration
+		if val := req.FormValue("duration"); val != "" {
+			seconds, parseErr := strconv.Atoi(val)
+			if parseErr != nil || seconds <= 0 {
+				reject("duration should be a number of seconds", http.StatusBadRequest)
+				return
+			}
+			duration = time.Duration(seconds) * time.Second
+		}
+		err = srv.controller.SetLogLevel(strings.TrimSuffix(kind, "s"), name, req.FormValue("level"), duration)
+		action = fmt.Sprintf("%s %s for %s", action, req.FormValue("level"), duration)
+	default:
+		reject(fmt.Sprintf("%s can't %s", kind, action), http.StatusNotFound)
+		return
+	}
+
+	description := fmt.Sprintf("%s %s", strings.TrimSuffix(kind, "s"), name)
+	if err != nil {
+		srv.control.count("failed")
+		srv.log.Warn("Control request from ", req.RemoteAddr, " to ", action, " ", description, " failed: ", err)
+		status := http.StatusBadRequest
+		if err == ErrUnknownComponent {
+			status = http.StatusNotFound
+		}
+		http.Error(writer, fmt.Sprintf("%s %s", description, err), status)
+		return
+	}
+	srv.control.count(parts[2])
+	srv.log.Info("Control request from ", req.RemoteAddr, ": ", action, " ", description)
+	io.WriteString(writer, "done\n")
+}
@@ -0,0 +1,133 @@
+package internalserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testController records the actions it is asked to carry out
+type testController struct {
+	actions []string
+}
+
+func (c *testController) do(action string, name string) error {
+	if name == "Missing" {
+		return ErrUnknownComponent
+	}
+	c.actions = append(c.actions, action+" "+name)
+	return nil
+}
+
+func (c *testController) PauseCollector(name string) error  { return c.do("pause", name) }
+func (c *testController) ResumeCollector(name string) error { return c.do("resume", name) }
+func (c *testController) Collect(name string) error         { return c.do("collect", name) }
+func (c *testController) FlushHandler(name string) error    { return c.do("flush", name) }
+func (c *testController) SetLogLevel(kind string, name string, level string, duration time.Duration) error {
+	if level != "debug" {
+		return errors.New("can't log at level " + level)
+	}
+	return c.do("loglevel "+kind+" "+level+" "+duration.String(), name)
+}
+
+func buildControlServer(c Controller) *InternalServer {
+	srv := &InternalServer{log: l.WithField("testing", "internal_server")}
+	srv.configureControl(map[string]interface{}{"controlToken": "s3cret"})
+	srv.SetController(c)
+	return srv
+}
+
+func controlRequest(srv *InternalServer, method string, path string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rsp := httptest.NewRecorder()
+	srv.handleControlRequest(rsp, req)
+	return rsp
+}
+
+func TestControlRequiresTheToken(t *testing.T) {
+	c := new(testController)
+	srv := buildControlServer(c)
+
+	assert.Equal(t, http.StatusUnauthorized, controlRequest(srv, "POST", "/control/collectors/Test/pause", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, controlRequest(srv, "POST", "/control/collectors/Test/pause", "guess").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, controlRequest(srv, "GET", "/control/collectors/Test/pause", "s3cret").Code)
+	assert.Empty(t, c.actions)
+	assert.Equal(t, 2.0, srv.control.metrics().Counters["unauthorized"])
+}
+
+func TestControlActions(t *testing.T) {
+	c := new(testController)
+	srv := buildControlServer(c)
+
+	for _, path := range []string{
+		"/control/collectors/Test/pause",
+		"/control/collectors/Test/resume",
+		"/control/collectors/Test/collect",
+		"/control/handlers/SignalFx/flush",
+		"/control/handlers/SignalFx/loglevel?level=debug&duration=60",
+		"/control/collectors/Test/loglevel?level=debug",
+	} {
+		rsp := controlRequest(srv, "POST", path, "s3cret")
+		assert.Equal(t, http.StatusOK, rsp.Code, path, rsp.Body.String())
+	}
+	assert.Equal(t, []string{
+		"pause Test",
+		"resume Test",
+		"collect Test",
+		"flush SignalFx",
+		"loglevel handler debug 1m0s SignalFx",
+		"loglevel collector debug 10m0s Test",
+	}, c.actions)
+
+	counters := srv.control.metrics().Counters
+	assert.Equal(t, 1.0, counters["pause"])
+	assert.Equal(t, 1.0, counters["flush"])
+	assert.Equal(t, 2.0, counters["loglevel"])
+}
+
+func TestControlFailures(t *testing.T) {
+	c := new(testController)
+	srv := buildControlServer(c)
+
+	rsp := controlRequest(srv, "POST", "/control/collectors/Missing/pause", "s3cret")
+	assert.Equal(t, http.StatusNotFound, rsp.Code)
+	assert.Contains(t, rsp.Body.String(), "collector Missing is not running")
+
+	rsp = controlRequest(srv, "POST", "/control/collectors/Test/loglevel?level=chatty", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, rsp.Code)
+	assert.Contains(t, rsp.Body.String(), "collector Test can't log at level chatty")
+
+	rsp = controlRequest(srv, "POST", "/control/collectors/Test/loglevel?level=debug&duration=soon", "s3cret")
+	assert.Equal(t, http.StatusBadRequest, rsp.Code)
+
+	assert.Equal(t, http.StatusNotFound, controlRequest(srv, "POST", "/control/handlers/SignalFx/pause", "s3cret").Code)
+	assert.Equal(t, http.StatusNotFound, controlRequest(srv, "POST", "/control/collectors/Test", "s3cret").Code)
+	assert.Empty(t, c.actions)
+	assert.Equal(t, 5.0, srv.control.metrics().Counters["failed"], "the malformed requests should be counted too")
+}
+
+func TestControlIsOffWithoutAToken(t *testing.T) {
+	srv := &InternalServer{log: l.WithField("testing", "internal_server")}
+	srv.configureControl(map[string]interface{}{})
+	srv.SetController(new(testController))
+	assert.False(t, srv.controlEnabled())
+
+	os.Setenv("FULLERITE_TEST_CONTROL_TOKEN", "s3cret")
+	defer os.Unsetenv("FULLERITE_TEST_CONTROL_TOKEN")
+	srv.configureControl(map[string]interface{}{
+		"controlToken": map[string]interface{}{"env": "FULLERITE_TEST_CONTROL_TOKEN"},
+		"controlPath":  "/admin",
+	})
+	assert.True(t, srv.controlEnabled())
+	assert.Equal(t, "/admin/", srv.controlPath)
+	assert.Equal(t, http.StatusOK, controlRequest(srv, "POST", "/admin/collectors/Test/pause", "s3cret").Code)
+}
@@ -9,7 +9,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"runtime"
+	"strings"
 
 	l "github.com/Sirupsen/logrus"
 )
@@ -17,6 +19,12 @@ import (
 const (
 	defaultPort        = 19090
 	defaultMetricsPath = "/metrics"
+	defaultReloadPath  = "/reload"
+
+	defaultCardinalityPath = "/cardinality"
+	defaultPrometheusPath  = "/prometheus"
+	defaultHealthPath      = "/healthz"
+	defaultReadyPath       = "/ready"
 )
 
 // InternalServer will collect from each handler the status and return it over HTTP
@@ -24,18 +32,62 @@ type InternalServer struct {
 	log               *l.Entry
 	handlerStatFunc   InternalStatFunc
 	collectorStatFunc InternalStatFunc
+	reloadFunc        ReloadFunc
+	cardinalityFunc   CardinalityFunc
+	healthFunc        HealthFunc
+	readyFunc         ReadyFunc
+	controller        Controller
 	port              int
 	path              string
+	reloadPath        string
+	cardinalityPath   string
+	prometheusPath    string
+	healthPath        string
+	readyPath         string
+	pprof             bool
+	controlToken      string
+	controlPath       string
+	control           *controlStats
 }
 
 // InternalStatFunc can be used to extract metrics
 type InternalStatFunc func() (stats map[string]metric.InternalMetrics)
 
+// ReloadFunc reloads the fullerite configuration
+type ReloadFunc func() error
+
+// HealthFunc returns what makes fullerite unhealthy, nothing when it is healthy
+type HealthFunc func() []string
+
+// ReadyFunc tells whether the collectors and handlers have started
+type ReadyFunc func() bool
+
+// CardinalityFunc returns the cardinality report of every collector
+type CardinalityFunc func() map[string]CardinalityReport
+
+// CardinalityReport describes the series a collector emitted during its last interval
+type CardinalityReport struct {
+	Series    int
+	Limit     int
+	OverLimit uint64
+	// the metric names and the dimension keys with the most series
+	TopMetrics    []SeriesCount
+	TopDimensions []SeriesCount
+}
+
+// SeriesCount is how many series a metric name or a dimension key is part of
+type SeriesCount struct {
+	Name   string
+	Series int
+}
+
 // ResponseFormat is the structure of the response from an http request
 type ResponseFormat struct {
 	Memory     metric.InternalMetrics
 	Handlers   map[string]metric.InternalMetrics
 	Collectors map[string]metric.InternalMetrics
+	// the control requests, when the control API is on
+	Control *metric.InternalMetrics `json:",omitempty"`
 }
 
 // New createse a new internal server instance
@@ -48,10 +100,58 @@ func New(cfg config.Config, h InternalStatFunc, c InternalStatFunc) *InternalSer
 	return srv
 }
 
+// SetReloadFunc enables reloading the configuration with a POST on the reload path
+func (srv *InternalServer) SetReloadFunc(f ReloadFunc) {
+	srv.reloadFunc = f
+}
+
+// SetCardinalityFunc enables the cardinality report on the cardinality path
+func (srv *InternalServer) SetCardinalityFunc(f CardinalityFunc) {
+	srv.cardinalityFunc = f
+}
+
+// SetHealthFunc enables the health check on the health path
+func (srv *InternalServer) SetHealthFunc(f HealthFunc) {
+	srv.healthFunc = f
+}
+
+// SetReadyFunc enables the readiness check on the ready path
+func (srv *InternalServer) SetReadyFunc(f ReadyFunc) {
+	srv.readyFunc = f
+}
+
 // Run starts a server on the specified port listening for the provided path
 func (srv *InternalServer) Run() {
 	srv.log.Info(fmt.Sprintf("Starting to run internal metrics server on port %d on path %s", srv.port, srv.path))
-	http.HandleFunc(srv.path, srv.handleInternalMetricsRequest)
+	mux := http.NewServeMux()
+	mux.HandleFunc(srv.path, srv.handleInternalMetricsRequest)
+	if srv.reloadFunc != nil {
+		mux.HandleFunc(srv.reloadPath, srv.handleReloadRequest)
+	}
+	if srv.cardinalityFunc != nil {
+		mux.HandleFunc(srv.cardinalityPath, srv.handleCardinalityRequest)
+	}
+	mux.HandleFunc(srv.prometheusPath, srv.handlePrometheusRequest)
+	if srv.healthFunc != nil {
+		mux.HandleFunc(srv.healthPath, srv.handleHealthRequest)
+	}
+	if srv.readyFunc != nil {
+		mux.HandleFunc(srv.readyPath, srv.handleReadyRequest)
+	}
+	if srv.pprof {
+		srv.log.Info("Serving the profiles of fullerite on /debug/pprof/")
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if srv.controlEnabled() {
+		srv.log.Info("Serving the control API on ", srv.controlPath)
+		mux.HandleFunc(srv.controlPath, srv.handleControlRequest)
+	} else if srv.controller != nil {
+		srv.log.Info("The control API is off, it needs a controlToken")
+	}
 
 	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", srv.port))
 	if err != nil {
@@ -60,24 +160,54 @@ func (srv *InternalServer) Run() {
 
 	srv.port = ln.Addr().(*net.TCPAddr).Port // reset the port with the bind port number (would change if port 0 is used)
 
-	if http.Serve(ln, nil) != nil {
+	if http.Serve(ln, mux) != nil {
 		srv.log.Error("Failed to start internal server: ", err)
 	}
 }
 
-func (srv *InternalServer) configure(cfgMap map[string]interface{}) {
+// serverOptions are the keys of the internal server config,
+// the control API ones aside
+var serverOptions = config.Options{
+	{Name: "port", Type: config.IntOption, Default: defaultPort,
+		Description: "port the internal server listens on, 0 picks a free one"},
+	{Name: "path", Type: config.StringOption, Default: defaultMetricsPath,
+		Description: "path the internal metrics are served on in JSON"},
+	{Name: "reloadPath", Type: config.StringOption, Default: defaultReloadPath,
+		Description: "path a POST reloads the configuration on"},
+	{Name: "cardinalityPath", Type: config.StringOption, Default: defaultCardinalityPath,
+		Description: "path the cardinality report is served on"},
+	{Name: "prometheusPath", Type: config.StringOption, Default: defaultPrometheusPath,
+		Description: "path the internal metrics are served on in the Prometheus text format"},
+	{Name: "healthPath", Type: config.StringOption, Default: defaultHealthPath,
+		Description: "path of the health check"},
+	{Name: "readyPath", Type: config.StringOption, Default: defaultReadyPath,
+		Description: "path of the readiness check"},
+	{Name: "pprof", Type: config.BoolOption, Default: false,
+		Description: "serve the profiles of fullerite on /debug/pprof/"},
+}
 
-	if val, exists := (cfgMap)["port"]; exists {
-		srv.port = config.GetAsInt(val, defaultPort)
-	} else {
-		srv.port = defaultPort
-	}
+// CheckConfig returns what is wrong with the internal server config
+func CheckConfig(cfgMap map[string]interface{}) []string {
+	return append(serverOptions, controlOptions...).Check(cfgMap)
+}
 
-	if val, exists := (cfgMap)["path"]; exists {
-		srv.path = val.(string)
-	} else {
-		srv.path = defaultMetricsPath
+// configure reads the internal server config, the keys that can't
+// be read fall back to their default
+func (srv *InternalServer) configure(cfgMap map[string]interface{}) {
+	values, err := serverOptions.Decode(cfgMap)
+	if err != nil {
+		srv.log.Error("Invalid internal server config: ", err)
 	}
+	srv.port = values.Int("port")
+	srv.path = values.String("path")
+	srv.reloadPath = values.String("reloadPath")
+	srv.cardinalityPath = values.String("cardinalityPath")
+	srv.prometheusPath = values.String("prometheusPath")
+	srv.healthPath = values.String("healthPath")
+	srv.readyPath = values.String("readyPath")
+	srv.pprof = values.Bool("pprof")
+
+	srv.configureControl(cfgMap)
 }
 
 // this is what services the request. The response will be JSON formatted like this:
@@ -111,6 +241,55 @@ func (srv InternalServer) handleInternalMetricsRequest(writer http.ResponseWrite
 	io.WriteString(writer, rspString)
 }
 
+// reloads the configuration, only POST requests are accepted
+// since the request changes what fullerite is running
+func (srv InternalServer) handleReloadRequest(writer http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "reload requires a POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	srv.log.Info("Configuration reload requested by ", req.RemoteAddr)
+	if err := srv.reloadFunc(); err != nil {
+		srv.log.Error("Failed to reload the configuration: ", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	io.WriteString(writer, "reloaded\n")
+}
+
+// answers 200 when fullerite is healthy, 503 listing what is wrong otherwise
+func (srv InternalServer) handleHealthRequest(writer http.ResponseWriter, req *http.Request) {
+	problems := srv.healthFunc()
+	if len(problems) > 0 {
+		http.Error(writer, strings.Join(problems, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(writer, "ok\n")
+}
+
+// answers 200 once the collectors and handlers have started, 503 until then
+func (srv InternalServer) handleReadyRequest(writer http.ResponseWriter, req *http.Request) {
+	if !srv.readyFunc() {
+		http.Error(writer, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(writer, "ready\n")
+}
+
+// reports the series of every collector, to find the ones emitting too many
+func (srv InternalServer) handleCardinalityRequest(writer http.ResponseWriter, req *http.Request) {
+	rsp, err := json.Marshal(srv.cardinalityFunc())
+	if err != nil {
+		srv.log.Warn("Failed to marshal the cardinality report because of error ", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(rsp)
+}
+
 // responsible for querying each handler and serializing the total response
 func (srv InternalServer) buildResponse() *[]byte {
 	memoryStats := getMemoryStats()
@@ -118,6 +297,9 @@ func (srv InternalServer) buildResponse() *[]byte {
 	rsp.Memory = *memoryStats
 	rsp.Handlers = srv.handlerStatFunc()
 	rsp.Collectors = srv.collectorStatFunc()
+	if srv.controlEnabled() {
+		rsp.Control = srv.control.metrics()
+	}
 	asString, err := json.Marshal(rsp)
 	if err != nil {
 		srv.log.Warn("Failed to marshal response ", rsp, " because of error ", err)
@@ -8,9 +8,11 @@ import (
 	"fullerite/metric"
 
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -25,7 +27,7 @@ type testHandler struct {
 }
 
 func (h testHandler) Run()                             {} // noop
-func (h testHandler) Configure(map[string]interface{}) {} // noop
+func (h testHandler) Configure(map[string]interface{}) error { return nil } // noop
 func (h testHandler) InternalMetrics() metric.InternalMetrics {
 	return h.metrics
 }
@@ -177,3 +179,129 @@ func TestRespondToHttp(t *testing.T) {
 	assert.Equal(t, 456.2, handlerMetrics.Counters["secondcounter"])
 	assert.Equal(t, 890.2, handlerMetrics.Gauges["secondgauge"])
 }
+
+func TestHandleReloadRequest(t *testing.T) {
+	reloads := 0
+	srv := InternalServer{
+		log: l.WithField("testing", "internal_server"),
+		reloadFunc: func() error {
+			reloads++
+			return nil
+		},
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handleReloadRequest(rsp, httptest.NewRequest("GET", "/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rsp.Code)
+	assert.Equal(t, 0, reloads)
+
+	rsp = httptest.NewRecorder()
+	srv.handleReloadRequest(rsp, httptest.NewRequest("POST", "/reload", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, 1, reloads)
+}
+
+func TestHandleReloadRequestFailure(t *testing.T) {
+	srv := InternalServer{
+		log: l.WithField("testing", "internal_server"),
+		reloadFunc: func() error {
+			return errors.New("invalid JSON in config")
+		},
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handleReloadRequest(rsp, httptest.NewRequest("POST", "/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, rsp.Code)
+	assert.Contains(t, rsp.Body.String(), "invalid JSON in config")
+}
+
+func TestHandleCardinalityRequest(t *testing.T) {
+	srv := InternalServer{
+		log: l.WithField("testing", "internal_server"),
+		cardinalityFunc: func() map[string]CardinalityReport {
+			return map[string]CardinalityReport{
+				"DockerStats": CardinalityReport{
+					Series:        12,
+					Limit:         10,
+					OverLimit:     2,
+					TopMetrics:    []SeriesCount{{"DockerCpuPercentage", 12}},
+					TopDimensions: []SeriesCount{{"container_id", 12}},
+				},
+			}
+		},
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handleCardinalityRequest(rsp, httptest.NewRequest("GET", "/cardinality", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+
+	reports := map[string]CardinalityReport{}
+	assert.Nil(t, json.Unmarshal(rsp.Body.Bytes(), &reports))
+	assert.Equal(t, 12, reports["DockerStats"].Series)
+	assert.Equal(t, "container_id", reports["DockerStats"].TopDimensions[0].Name)
+}
+
+func TestHandleHealthRequest(t *testing.T) {
+	problems := []string{}
+	srv := InternalServer{
+		log:        l.WithField("testing", "internal_server"),
+		healthFunc: func() []string { return problems },
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handleHealthRequest(rsp, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+
+	problems = []string{"handler SignalFx failed to emit 5 times in a row", "collector Test has neither collected nor sent a metric"}
+	rsp = httptest.NewRecorder()
+	srv.handleHealthRequest(rsp, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rsp.Code)
+	assert.Contains(t, rsp.Body.String(), "handler SignalFx failed to emit 5 times in a row\ncollector Test")
+}
+
+func TestHandleReadyRequest(t *testing.T) {
+	ready := false
+	srv := InternalServer{
+		log:       l.WithField("testing", "internal_server"),
+		readyFunc: func() bool { return ready },
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handleReadyRequest(rsp, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rsp.Code)
+
+	ready = true
+	rsp = httptest.NewRecorder()
+	srv.handleReadyRequest(rsp, httptest.NewRequest("GET", "/ready", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+}
+
+func TestPprofIsOptional(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		cfg := config.Config{}
+		cfg.InternalServerConfig = map[string]interface{}{"port": 0, "pprof": enabled}
+		srv := New(cfg, handlerStatFunc(nil), collectorStatFunc)
+		go srv.Run()
+
+		time.Sleep(100 * time.Millisecond) // wait for server to bind on port
+		rsp, err := http.Get(fmt.Sprintf("http://localhost:%d/debug/pprof/", srv.port))
+		if assert.Nil(t, err) {
+			rsp.Body.Close()
+			if enabled {
+				assert.Equal(t, http.StatusOK, rsp.StatusCode)
+			} else {
+				assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	assert.Empty(t, CheckConfig(map[string]interface{}{
+		"port": "19191", "pprof": true, "controlToken": "secret",
+	}))
+	assert.Equal(t, []string{
+		`"path" should be of type string, got float64`,
+		`unknown key "pprofs"`,
+	}, CheckConfig(map[string]interface{}{"path": 5.0, "pprofs": true}))
+}
@@ -0,0 +1,141 @@
+package internalserver
+
+import (
+	"fullerite/metric"
+
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusContentType is the version of the text format that is rendered
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promSample is a value of a Prometheus metric, with its labels
+type promSample struct {
+	label string
+	owner string
+	value float64
+}
+
+// promSamples sort by the handler or collector they belong to
+type promSamples []promSample
+
+func (s promSamples) Len() int           { return len(s) }
+func (s promSamples) Less(i, j int) bool { return s[i].owner < s[j].owner }
+func (s promSamples) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// promMetric is a Prometheus metric and all its samples
+type promMetric struct {
+	kind    string
+	samples promSamples
+}
+
+// promMetrics groups the internal metrics by Prometheus metric name,
+// every name gets a single type
+type promMetrics map[string]*promMetric
+
+// add the counters and the gauges of stats, named after section. The
+// handler or collector they belong to is set as the label when given.
+func (pm promMetrics) add(section string, label string, owner string, stats metric.InternalMetrics) {
+	for name, value := range stats.Counters {
+		pm.addSample(section, name, "counter", promSample{label, owner, value})
+	}
+	for name, value := range stats.Gauges {
+		pm.addSample(section, name, "gauge", promSample{label, owner, value})
+	}
+}
+
+func (pm promMetrics) addSample(section string, name string, kind string, sample promSample) {
+	name = prometheusName(section, name)
+	m, exists := pm[name]
+	if !exists {
+		m = &promMetric{kind: kind}
+		pm[name] = m
+	}
+	if m.kind != kind {
+		// a name can't be both a counter and a gauge
+		return
+	}
+	m.samples = append(m.samples, sample)
+}
+
+// write renders the metrics in the Prometheus text format, sorted by name and label
+func (pm promMetrics) write(buf *bytes.Buffer) {
+	names := make([]string, 0, len(pm))
+	for name := range pm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := pm[name]
+		sort.Sort(m.samples)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, m.kind)
+		for _, s := range m.samples {
+			buf.WriteString(name)
+			if s.label != "" {
+				fmt.Fprintf(buf, "{%s=\"%s\"}", s.label, escapeLabelValue(s.owner))
+			}
+			buf.WriteString(" ")
+			buf.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			buf.WriteString("\n")
+		}
+	}
+}
+
+// prometheusName turns an internal metric name into a valid Prometheus one
+// prefixed with the section, e.g. fullerite.collector_errors of a collector
+// becomes fullerite_collector_errors and totalEmissions of a handler
+// fullerite_handler_totalEmissions
+func prometheusName(section string, name string) string {
+	name = strings.TrimPrefix(name, "fullerite.")
+	if !strings.HasPrefix(name, section+"_") {
+		name = section + "_" + name
+	}
+	name = "fullerite_" + name
+
+	sanitized := []byte(name)
+	for i, c := range sanitized {
+		valid := c == '_' || c == ':' ||
+			(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !valid {
+			sanitized[i] = '_'
+		}
+	}
+	return string(sanitized)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// renders the same internal metrics as the metrics path in the Prometheus
+// text format, the handler and collector metrics are labelled with their name
+func (srv InternalServer) handlePrometheusRequest(writer http.ResponseWriter, req *http.Request) {
+	writer.Header().Set("Content-Type", prometheusContentType)
+	writer.Write(srv.buildPrometheusResponse())
+}
+
+func (srv InternalServer) buildPrometheusResponse() []byte {
+	pm := promMetrics{}
+	pm.add("memory", "", "", *getMemoryStats())
+	for name, stats := range srv.handlerStatFunc() {
+		pm.add("handler", "handler", name, stats)
+	}
+	for name, stats := range srv.collectorStatFunc() {
+		pm.add("collector", "collector", name, stats)
+	}
+	if srv.controlEnabled() {
+		pm.add("control", "", "", *srv.control.metrics())
+	}
+
+	buf := new(bytes.Buffer)
+	pm.write(buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,81 @@
+package internalserver
+
+import (
+	"fullerite/metric"
+
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusName(t *testing.T) {
+	assert.Equal(t, "fullerite_handler_totalEmissions", prometheusName("handler", "totalEmissions"))
+	assert.Equal(t, "fullerite_handler_panics", prometheusName("handler", "fullerite.handler_panics"))
+	assert.Equal(t, "fullerite_collector_errors", prometheusName("collector", "fullerite.collector_errors"))
+	assert.Equal(t, "fullerite_collector_collections_skipped", prometheusName("collector", "fullerite.collections_skipped"))
+	assert.Equal(t, "fullerite_handler_filterMatches_include0", prometheusName("handler", "filterMatches.include0"))
+	assert.Equal(t, "fullerite_memory_HeapAlloc", prometheusName("memory", "HeapAlloc"))
+}
+
+func TestHandlePrometheusRequest(t *testing.T) {
+	srv := InternalServer{
+		log: l.WithField("testing", "internal_server"),
+		handlerStatFunc: func() map[string]metric.InternalMetrics {
+			return map[string]metric.InternalMetrics{
+				"SignalFx": {
+					Counters: map[string]float64{"totalEmissions": 12},
+					Gauges:   map[string]float64{"emissionsInFlight": 1},
+				},
+				"Graphite": {
+					Counters: map[string]float64{"totalEmissions": 3},
+					Gauges:   map[string]float64{},
+				},
+			}
+		},
+		collectorStatFunc: func() map[string]metric.InternalMetrics {
+			return map[string]metric.InternalMetrics{
+				`Nerve "uwsgi"`: {
+					Counters: map[string]float64{"fullerite.collector_errors": 2},
+					Gauges:   map[string]float64{"fullerite.last_collection_duration": 0.5},
+				},
+			}
+		},
+	}
+
+	rsp := httptest.NewRecorder()
+	srv.handlePrometheusRequest(rsp, httptest.NewRequest("GET", "/prometheus", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, prometheusContentType, rsp.Header().Get("Content-Type"))
+
+	body := rsp.Body.String()
+	assert.Contains(t, body, "# TYPE fullerite_handler_totalEmissions counter\n"+
+		"fullerite_handler_totalEmissions{handler=\"Graphite\"} 3\n"+
+		"fullerite_handler_totalEmissions{handler=\"SignalFx\"} 12\n")
+	assert.Contains(t, body, "# TYPE fullerite_handler_emissionsInFlight gauge\n"+
+		"fullerite_handler_emissionsInFlight{handler=\"SignalFx\"} 1\n")
+	assert.Contains(t, body, "# TYPE fullerite_collector_errors counter\n"+
+		"fullerite_collector_errors{collector=\"Nerve \\\"uwsgi\\\"\"} 2\n")
+	assert.Contains(t, body, "fullerite_collector_last_collection_duration{collector=\"Nerve \\\"uwsgi\\\"\"} 0.5\n")
+	assert.Contains(t, body, "# TYPE fullerite_memory_HeapAlloc gauge\nfullerite_memory_HeapAlloc ")
+	assert.Contains(t, body, "# TYPE fullerite_memory_NumGC counter\n")
+
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			_, err := strconv.ParseFloat(line[strings.LastIndex(line, " ")+1:], 64)
+			assert.Nil(t, err, "malformed sample: ", line)
+		}
+	}
+}
+
+func TestPrometheusKeepsASingleType(t *testing.T) {
+	pm := promMetrics{}
+	pm.add("handler", "handler", "first", metric.InternalMetrics{Counters: map[string]float64{"clash": 1}})
+	pm.add("handler", "handler", "second", metric.InternalMetrics{Gauges: map[string]float64{"clash": 2}})
+	assert.Equal(t, "counter", pm["fullerite_handler_clash"].kind)
+	assert.Equal(t, 1, len(pm["fullerite_handler_clash"].samples))
+}
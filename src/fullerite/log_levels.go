@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// componentLevels lets the log level of a collector or a handler be changed
+// for a while. The logger is set to the most verbose level asked for, and
+// the formatter drops the entries of every other component that are below
+// the level the logger was at to begin with.
+type componentLevels struct {
+	sync.RWMutex
+	logger    *logrus.Logger
+	formatter logrus.Formatter
+	base      logrus.Level
+
+	// keyed by the kind of the component and its name, like "collector Test"
+	levels map[string]logrus.Level
+	resets map[string]*time.Timer
+}
+
+// newComponentLevels wraps the formatter of the logger, it should be
+// called before the logger is used by other goroutines
+func newComponentLevels(logger *logrus.Logger) *componentLevels {
+	c := &componentLevels{
+		logger:    logger,
+		formatter: logger.Formatter,
+		base:      logger.Level,
+		levels:    make(map[string]logrus.Level),
+		resets:    make(map[string]*time.Timer),
+	}
+	logger.Formatter = c
+	return c
+}
+
+// set changes the level of the "collector" or "handler" for the given
+// duration, it goes back to the base level after that
+func (c *componentLevels) set(kind string, name string, level logrus.Level, duration time.Duration) {
+	key := kind + " " + name
+	c.Lock()
+	defer c.Unlock()
+	if reset, exists := c.resets[key]; exists {
+		reset.Stop()
+	}
+	c.levels[key] = level
+	c.resets[key] = time.AfterFunc(duration, func() { c.reset(key) })
+	c.apply()
+}
+
+func (c *componentLevels) reset(key string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.levels, key)
+	delete(c.resets, key)
+	c.apply()
+}
+
+// apply sets the logger to the most verbose level needed, it must be
+// called holding the lock
+func (c *componentLevels) apply() {
+	level := c.base
+	for _, l := range c.levels {
+		if l > level {
+			level = l
+		}
+	}
+	c.logger.Level = level
+}
+
+// Format drops the entries below the level of their component
+func (c *componentLevels) Format(entry *logrus.Entry) ([]byte, error) {
+	if !c.allows(entry) {
+		return nil, nil
+	}
+	return c.formatter.Format(entry)
+}
+
+func (c *componentLevels) allows(entry *logrus.Entry) bool {
+	c.RLock()
+	defer c.RUnlock()
+	if len(c.levels) == 0 {
+		return true
+	}
+	level := c.base
+	for _, kind := range []string{"collector", "handler"} {
+		if name, ok := entry.Data[kind].(string); ok {
+			if l, exists := c.levels[kind+" "+name]; exists {
+				level = l
+			}
+		}
+	}
+	return entry.Level <= level
+}
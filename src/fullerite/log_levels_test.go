@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentLevels(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.Out = out
+	logger.Level = logrus.InfoLevel
+	levels := newComponentLevels(logger)
+
+	logger.WithField("collector", "Test").Debug("hidden")
+	assert.Empty(t, out.String())
+
+	levels.set("collector", "Test", logrus.DebugLevel, 50*time.Millisecond)
+	assert.Equal(t, logrus.DebugLevel, logger.Level)
+	logger.WithField("collector", "Test").Debug("collector debug")
+	logger.WithField("collector", "Other").Debug("other debug")
+	logger.WithField("handler", "Test").Debug("handler debug")
+	logger.WithField("collector", "Other").Info("other info")
+	assert.Contains(t, out.String(), "collector debug")
+	assert.Contains(t, out.String(), "other info")
+	assert.NotContains(t, out.String(), "other debug")
+	assert.NotContains(t, out.String(), "handler debug")
+
+	for reset := false; !reset; {
+		time.Sleep(10 * time.Millisecond)
+		levels.RLock()
+		reset = len(levels.levels) == 0
+		levels.RUnlock()
+	}
+	out.Reset()
+	assert.Equal(t, logrus.InfoLevel, logger.Level, "the level should go back once the duration is over")
+	logger.WithField("collector", "Test").Debug("collector debug")
+	assert.Empty(t, out.String())
+}
+
+func TestComponentLevelsCanQuietAComponent(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := logrus.New()
+	logger.Out = out
+	logger.Level = logrus.InfoLevel
+	levels := newComponentLevels(logger)
+
+	levels.set("handler", "SignalFx", logrus.ErrorLevel, time.Minute)
+	logger.WithField("handler", "SignalFx").Warn("noisy")
+	logger.WithField("handler", "SignalFx").Error("broken")
+	logger.WithField("handler", "Graphite").Info("fine")
+	assert.NotContains(t, out.String(), "noisy")
+	assert.Contains(t, out.String(), "broken")
+	assert.Contains(t, out.String(), "fine")
+}
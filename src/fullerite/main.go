@@ -1,13 +1,18 @@
 package main
 
 import (
+	"fullerite/collector"
 	"fullerite/config"
 	"fullerite/handler"
 	"fullerite/internalserver"
 	"fullerite/metric"
 
+	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -94,6 +99,55 @@ func main() {
 				"NOTE: Make sure you flush out all your metrics either as a list OR individually separated\n" +
 				"with a newline '\\n'otherwise your metrics will not be parsed and will be IGNORED\n",
 		},
+		{
+			Name:   "check-config",
+			Action: checkConfig,
+			Flags:  app.Flags,
+			Usage:  "validate the configuration and the collector configurations it references",
+			UsageText: "Reports the unknown collectors and handlers, the missing required keys,\n" +
+				"the values of the wrong type and the keys that are not understood.\n" +
+				"Exits with a non-zero status when there is any problem.\n",
+		},
+		{
+			Name:   "collect-once",
+			Action: collectOnceCommand,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "collector-config, f",
+					Usage: "Configuration of the collector, looked up in the collectorsConfigPath by default",
+				},
+				cli.IntFlag{
+					Name:  "times, n",
+					Value: 1,
+					Usage: "How many times to run the collector",
+				},
+				cli.IntFlag{
+					Name:  "wait, w",
+					Usage: "How long (in seconds) to gather the metrics of each run, the collector interval by default",
+				},
+				cli.StringFlag{
+					Name:  "format, o",
+					Value: jsonOutput,
+					Usage: "How to print the metrics (json, table, graphite)",
+				},
+			}, app.Flags...),
+			Usage:     "run a collector and print its metrics without sending them to any handler",
+			ArgsUsage: "<collector>",
+			UsageText: "Builds the collector from its configuration and runs it once, or --times times,\n" +
+				"waiting --wait seconds for its metrics every time. The metrics are printed\n" +
+				"to stdout after going through the collector filters and processors.\n",
+		},
+		{
+			Name:   "list",
+			Action: list,
+			Usage:  "list the collectors and the handlers fullerite knows",
+		},
+		{
+			Name:      "describe",
+			Action:    describe,
+			Usage:     "show the options of a collector or a handler and the metrics of a collector",
+			ArgsUsage: "<collector or handler>...",
+		},
 	}
 	app.Run(os.Args)
 }
@@ -105,53 +159,95 @@ func start(ctx *cli.Context) {
 		defer profile.Start(profile.BlockProfile).Stop()
 		defer profile.Start(profile.ProfilePath("."))
 	}
-	quit := make(chan bool)
 	initLogrus(ctx)
 	log.Info("Starting fullerite...")
+	// the collector splays should differ from one host to the other
+	rand.Seed(time.Now().UnixNano())
 
-	c, err := config.ReadConfig(ctx.String("config"))
+	configFile := ctx.String("config")
+	c, err := config.ReadConfig(configFile)
 	if err != nil {
 		return
 	}
-	handlers := createHandlers(c)
-	hook := NewLogErrorHook(handlers)
-	log.Logger.Hooks.Add(hook)
-
-	startHandlers(handlers)
-	collectors := startCollectors(c)
 
 	collectorStatChan := make(chan metric.CollectorEmission)
+	d := newDaemon(configFile, collectorStatChan)
+	hook := NewLogErrorHook(d.handlerSet)
+	log.Logger.Hooks.Add(hook)
 
+	// the internal server is up first so that it can tell when fullerite is ready
 	internalServer := internalserver.New(c,
-		handlerStatFunc(handlers),
+		handlerStatFunc(d.handlerSet),
 		readCollectorStat(collectorStatChan))
+	internalServer.SetReloadFunc(d.reload)
+	internalServer.SetCardinalityFunc(readCardinalityReports)
+	internalServer.SetHealthFunc(func() []string {
+		return healthProblems(d.handlerSet, time.Now())
+	})
+	internalServer.SetReadyFunc(d.ready)
+	internalServer.SetController(controller{d.handlerSet, newComponentLevels(logrus.StandardLogger())})
 
 	go internalServer.Run()
 
-	readFromCollectors(collectors, handlers, collectorStatChan)
+	d.apply(c)
+
+	waitForShutdown(d.reload)
+	d.stop()
+}
+
+// waitForShutdown blocks until we are asked to terminate,
+// the configuration is reloaded on SIGHUP in the meantime
+func waitForShutdown(reload func() error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			if err := reload(); err != nil {
+				log.Error("Failed to reload the configuration: ", err)
+			}
+			continue
+		}
+		log.Info("Received ", sig, ", shutting down fullerite...")
+		return
+	}
+}
 
-	<-quit
+// shutdown stops the collectors first, waits for the metrics they produced
+// to reach the handlers and then has the handlers flush their buffers.
+func shutdown(collectors []collector.Collector, readers *sync.WaitGroup, handlers []handler.Handler) {
+	stopCollectors(collectors)
+	readers.Wait()
+	stopHandlers(handlers)
+	log.Info("fullerite stopped")
 }
 
-func handlerStatFunc(handlers []handler.Handler) internalserver.InternalStatFunc {
+func handlerStatFunc(handlers *handlerSet) internalserver.InternalStatFunc {
 	return func() map[string]metric.InternalMetrics {
 		stats := map[string]metric.InternalMetrics{}
-		for _, inst := range handlers {
+		for _, inst := range handlers.List() {
 			stats[inst.Name()] = inst.InternalMetrics()
 		}
 		return stats
 	}
 }
 
+// readCollectorStat keeps the datapoints counted for every collector and
+// returns them along with the schedule and the health of the collectors
 func readCollectorStat(collectorStatChan <-chan metric.CollectorEmission) internalserver.InternalStatFunc {
+	var mu sync.Mutex
 	collectorMetrics := map[string]uint64{}
 	go func() {
 		for collectorMetric := range collectorStatChan {
+			mu.Lock()
 			collectorMetrics[collectorMetric.Name] = collectorMetric.EmissionCount
+			mu.Unlock()
 		}
 	}()
 	return func() map[string]metric.InternalMetrics {
 		metricStats := map[string]metric.InternalMetrics{}
+		mu.Lock()
+		defer mu.Unlock()
 		for k, v := range collectorMetrics {
 			counters := map[string]float64{"fullerite.collector_datapoints": float64(v)}
 			gauges := map[string]float64{}
@@ -162,6 +258,8 @@ func readCollectorStat(collectorStatChan <-chan metric.CollectorEmission) intern
 			}
 			metricStats[k] = m
 		}
+		mergeStats(metricStats, scheduleStats())
+		mergeStats(metricStats, healthStats())
 		return metricStats
 	}
 }
@@ -187,14 +285,18 @@ func visualize(ctx *cli.Context) {
 	configMap["collectorFile"] = collectorFile
 
 	// Start collector and handlers
-	collector := startCollector("AdHoc", c, configMap)
+	adHoc := startCollector("AdHoc", c, configMap)
+	if adHoc == nil {
+		return
+	}
 	c.Collectors = []string{"AdHoc"}
 	c.DiamondCollectors = []string{}
 	handlers := createHandlers(c)
 	startHandlers(handlers)
 
 	// Read the metrics from the AdHoc collector
-	go readFromCollector(collector, handlers)
+	collectors := []collector.Collector{adHoc}
+	readers := readFromCollectors(collectors, newHandlerSet(handlers))
 
 	// Stop collecting after `die-after` duration expires
 	quitChannel := make(chan bool, 1)
@@ -207,4 +309,5 @@ func visualize(ctx *cli.Context) {
 	})
 	// Wait to quit
 	<-quitChannel
+	shutdown(collectors, readers, handlers)
 }
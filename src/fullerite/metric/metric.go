@@ -1,5 +1,13 @@
 package metric
 
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
 // The different types of metrics that are supported
 const (
 	Gauge             = "gauge"
@@ -9,15 +17,28 @@ const (
 
 // Metric type holds all the information for a single metric data
 // point. Metrics are generated in collectors and passed to handlers.
+// Timestamp is the collection time; a zero Timestamp means it is unknown.
 type Metric struct {
 	Name       string            `json:"name"`
 	MetricType string            `json:"type"`
 	Value      float64           `json:"value"`
 	Dimensions map[string]string `json:"dimensions"`
+	Timestamp  time.Time         `json:"-"`
+}
+
+// jsonMetric is the wire format of a Metric, as spoken by Diamond
+// collectors and AdHoc scripts. The timestamp is expressed in
+// (possibly fractional) seconds since the epoch and is optional.
+type jsonMetric struct {
+	Name       string            `json:"name"`
+	MetricType string            `json:"type"`
+	Value      float64           `json:"value"`
+	Dimensions map[string]string `json:"dimensions"`
+	Timestamp  float64           `json:"timestamp,omitempty"`
 }
 
 // New returns a new metric with name. Default metric type is "gauge"
-// and timestamp is set to now. Value is initialized to 0.0.
+// and the timestamp is left unset. Value is initialized to 0.0.
 func New(name string) Metric {
 	return Metric{
 		Name:       name,
@@ -40,6 +61,51 @@ func Sentinel() Metric {
 	return WithValue("fullerite.emit_now", 0)
 }
 
+// MarshalJSON encodes the metric in the wire format, the timestamp
+// is only included when it is set.
+func (m Metric) MarshalJSON() ([]byte, error) {
+	jm := jsonMetric{
+		Name:       m.Name,
+		MetricType: m.MetricType,
+		Value:      m.Value,
+		Dimensions: m.Dimensions,
+	}
+	if !m.Timestamp.IsZero() {
+		jm.Timestamp = float64(m.Timestamp.UnixNano()/int64(time.Millisecond)) / 1000
+	}
+	return json.Marshal(jm)
+}
+
+// UnmarshalJSON decodes a metric from the wire format.
+func (m *Metric) UnmarshalJSON(data []byte) error {
+	var jm jsonMetric
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+	m.Name = jm.Name
+	m.MetricType = jm.MetricType
+	m.Value = jm.Value
+	m.Dimensions = jm.Dimensions
+	if m.Dimensions == nil {
+		m.Dimensions = make(map[string]string)
+	}
+	m.Timestamp = time.Time{}
+	if jm.Timestamp > 0 {
+		sec, frac := math.Modf(jm.Timestamp)
+		m.Timestamp = time.Unix(int64(sec), int64(frac*float64(time.Second)))
+	}
+	return nil
+}
+
+// GetTimestamp returns the collection time of the metric, falling back
+// to now when the metric does not carry one.
+func (m *Metric) GetTimestamp() time.Time {
+	if m.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return m.Timestamp
+}
+
 // AddDimension adds a new dimension to the Metric.
 func (m *Metric) AddDimension(name, value string) {
 	m.Dimensions[name] = value
@@ -75,6 +141,16 @@ func (m *Metric) GetDimensionValue(dimension string) (value string, ok bool) {
 	return
 }
 
+// SeriesKey identifies the series of the metric by its name and dimensions
+func (m *Metric) SeriesKey() string {
+	dimensions := make([]string, 0, len(m.Dimensions))
+	for k, v := range m.Dimensions {
+		dimensions = append(dimensions, k+"="+v)
+	}
+	sort.Strings(dimensions)
+	return m.Name + "\x00" + strings.Join(dimensions, "\x00")
+}
+
 // ZeroValue is metric zero value
 func (m *Metric) ZeroValue() bool {
 	return (len(m.Name) == 0) &&
@@ -3,7 +3,9 @@ package metric_test
 import (
 	"fullerite/metric"
 
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -89,3 +91,68 @@ func TestAddDimensions(t *testing.T) {
 
 	assert.Equal(t, m1, m2)
 }
+
+func TestGetTimestampFallsBackToNow(t *testing.T) {
+	m := metric.New("TestMetric")
+	before := time.Now()
+
+	assert.True(t, m.Timestamp.IsZero())
+	assert.False(t, m.GetTimestamp().Before(before))
+}
+
+func TestGetTimestamp(t *testing.T) {
+	m := metric.New("TestMetric")
+	m.Timestamp = time.Unix(1476748800, 0)
+
+	assert.Equal(t, int64(1476748800), m.GetTimestamp().Unix())
+}
+
+func TestMetricJSONWithoutTimestamp(t *testing.T) {
+	m := metric.New("TestMetric")
+	b, err := json.Marshal(m)
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"TestMetric","type":"gauge","value":0,"dimensions":{}}`, string(b))
+}
+
+func TestMetricJSONRoundTrip(t *testing.T) {
+	m := metric.WithValue("TestMetric", 12)
+	m.AddDimension("TestDimension", "test value")
+	m.Timestamp = time.Unix(1476748800, int64(250*time.Millisecond))
+
+	b, err := json.Marshal(m)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"timestamp":1476748800.25`)
+
+	var decoded metric.Metric
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, m.Name, decoded.Name)
+	assert.Equal(t, m.Value, decoded.Value)
+	assert.Equal(t, m.Dimensions, decoded.Dimensions)
+	assert.True(t, m.Timestamp.Equal(decoded.Timestamp))
+}
+
+func TestMetricUnmarshalIntegerTimestamp(t *testing.T) {
+	var m metric.Metric
+	err := json.Unmarshal([]byte(`{"name":"foo","type":"gauge","value":1,"timestamp":1476748800}`), &m)
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(int64(1476748800), m.Timestamp.Unix())
+	assert.NotNil(m.Dimensions, "dimensions should be initialized")
+}
+
+func TestSeriesKey(t *testing.T) {
+	m1 := metric.New("TestMetric")
+	m1.AddDimension("a", "1")
+	m1.AddDimension("b", "2")
+	m2 := metric.New("TestMetric")
+	m2.AddDimension("b", "2")
+	m2.AddDimension("a", "1")
+	m3 := metric.New("TestMetric")
+	m3.AddDimension("a", "2")
+
+	assert := assert.New(t)
+	assert.Equal(m1.SeriesKey(), m2.SeriesKey())
+	assert.NotEqual(m1.SeriesKey(), m3.SeriesKey())
+}
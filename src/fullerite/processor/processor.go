@@ -0,0 +1,387 @@
+// Package processor transforms the metrics on their way from the collectors
+// to the handlers, see the README for the processors that can be configured.
+package processor
+
+import (
+	"fullerite/metric"
+
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// The types of processors that can be configured
+const (
+	Rename           = "rename"
+	AddDimension     = "add_dimension"
+	DropDimension    = "drop_dimension"
+	RenameDimension  = "rename_dimension"
+	RewriteDimension = "rewrite_dimension"
+	Drop             = "drop"
+	SetType          = "set_type"
+)
+
+// Processor transforms a metric on its way from a collector to a handler
+type Processor interface {
+	// Process changes the metric in place, it returns false
+	// when the metric should be dropped
+	Process(*metric.Metric) bool
+}
+
+// Chain is an ordered list of processors, every metric goes through all
+// of them until one decides to drop it
+type Chain []Processor
+
+// Process runs the metric through the chain and returns false if it was dropped.
+// The dimensions are copied before being changed since the same metric
+// is handed to every handler.
+func (chain Chain) Process(m *metric.Metric) bool {
+	if len(chain) == 0 {
+		return true
+	}
+
+	dimensions := make(map[string]string, len(m.Dimensions))
+	for k, v := range m.Dimensions {
+		dimensions[k] = v
+	}
+	m.Dimensions = dimensions
+
+	for _, p := range chain {
+		if !p.Process(m) {
+			return false
+		}
+	}
+	return true
+}
+
+var processorConstructs = map[string]func(map[string]interface{}) (Processor, error){
+	Rename:           newRename,
+	AddDimension:     newAddDimension,
+	DropDimension:    newDropDimension,
+	RenameDimension:  newRenameDimension,
+	RewriteDimension: newRewriteDimension,
+	Drop:             newDrop,
+	SetType:          newSetType,
+}
+
+// New compiles the processors described in configs, in order. The processors
+// that fail to compile are left out of the chain and reported in the error.
+func New(configs []map[string]interface{}) (Chain, error) {
+	chain := Chain{}
+	problems := []string{}
+	for i, c := range configs {
+		p, err := newProcessor(c)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("processor %d: %s", i, err))
+			continue
+		}
+		chain = append(chain, p)
+	}
+
+	if len(problems) > 0 {
+		return chain, fmt.Errorf("invalid processors: %s", strings.Join(problems, "; "))
+	}
+	return chain, nil
+}
+
+// FromConfig compiles the processors listed under the "processors" key
+// of a collector or handler config
+func FromConfig(value interface{}) (Chain, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return Chain{}, fmt.Errorf("processors should be a list, got %v", reflect.TypeOf(value))
+	}
+
+	configs := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		if configs[i], ok = item.(map[string]interface{}); !ok {
+			configs[i] = map[string]interface{}{}
+		}
+	}
+	return New(configs)
+}
+
+func newProcessor(c map[string]interface{}) (Processor, error) {
+	kind, _ := c["type"].(string)
+	f, exists := processorConstructs[kind]
+	if !exists {
+		return nil, fmt.Errorf("unknown type %q", kind)
+	}
+
+	p, err := f(c)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", kind, err)
+	}
+
+	// any processor can be limited to the metrics with a matching name
+	if asInterface, exists := c["metric"]; exists {
+		only, err := compile(asInterface)
+		if err != nil {
+			return nil, fmt.Errorf("%s: metric: %s", kind, err)
+		}
+		p = filtered{only, p}
+	}
+	return p, nil
+}
+
+// filtered only hands the metrics whose name matches to its processor
+type filtered struct {
+	only      *regexp.Regexp
+	processor Processor
+}
+
+func (f filtered) Process(m *metric.Metric) bool {
+	if !f.only.MatchString(m.Name) {
+		return true
+	}
+	return f.processor.Process(m)
+}
+
+// rename replaces the parts of the metric name matching pattern,
+// replacement can refer to the groups of the pattern as $1, $2, ...
+type rename struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func newRename(c map[string]interface{}) (Processor, error) {
+	pattern, err := compileKey(c, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := stringKey(c, "replacement")
+	if err != nil {
+		return nil, err
+	}
+	return rename{pattern, replacement}, nil
+}
+
+func (r rename) Process(m *metric.Metric) bool {
+	m.Name = r.pattern.ReplaceAllString(m.Name, r.replacement)
+	return true
+}
+
+// addDimension sets a dimension, the value can refer to
+// other dimensions of the metric as %{dimension}
+type addDimension struct {
+	key   string
+	value template
+}
+
+func newAddDimension(c map[string]interface{}) (Processor, error) {
+	key, err := stringKey(c, "key")
+	if err != nil {
+		return nil, err
+	}
+	value, err := stringKey(c, "value")
+	if err != nil {
+		return nil, err
+	}
+	return addDimension{key, template(value)}, nil
+}
+
+func (a addDimension) Process(m *metric.Metric) bool {
+	m.AddDimension(a.key, a.value.expand(m))
+	return true
+}
+
+// dropDimension removes dimensions from the metric
+type dropDimension struct {
+	keys []string
+}
+
+func newDropDimension(c map[string]interface{}) (Processor, error) {
+	asInterface, exists := c["keys"]
+	if !exists {
+		return nil, fmt.Errorf("keys is required")
+	}
+	list, ok := asInterface.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keys should be a list of strings")
+	}
+
+	keys := make([]string, len(list))
+	for i, item := range list {
+		if keys[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("keys should be a list of strings")
+		}
+	}
+	return dropDimension{keys}, nil
+}
+
+func (d dropDimension) Process(m *metric.Metric) bool {
+	for _, key := range d.keys {
+		m.RemoveDimension(key)
+	}
+	return true
+}
+
+// renameDimension moves the value of a dimension to another key
+type renameDimension struct {
+	from string
+	to   string
+}
+
+func newRenameDimension(c map[string]interface{}) (Processor, error) {
+	from, err := stringKey(c, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := stringKey(c, "to")
+	if err != nil {
+		return nil, err
+	}
+	return renameDimension{from, to}, nil
+}
+
+func (r renameDimension) Process(m *metric.Metric) bool {
+	if value, ok := m.GetDimensionValue(r.from); ok {
+		m.RemoveDimension(r.from)
+		m.AddDimension(r.to, value)
+	}
+	return true
+}
+
+// rewriteDimension replaces the parts of a dimension value matching pattern,
+// replacement can refer to the groups of the pattern as $1 and to
+// other dimensions as %{dimension}
+type rewriteDimension struct {
+	key         string
+	pattern     *regexp.Regexp
+	replacement template
+}
+
+func newRewriteDimension(c map[string]interface{}) (Processor, error) {
+	key, err := stringKey(c, "key")
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := compileKey(c, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := stringKey(c, "replacement")
+	if err != nil {
+		return nil, err
+	}
+	return rewriteDimension{key, pattern, template(replacement)}, nil
+}
+
+func (r rewriteDimension) Process(m *metric.Metric) bool {
+	if value, ok := m.GetDimensionValue(r.key); ok {
+		m.AddDimension(r.key, r.pattern.ReplaceAllString(value, r.replacement.expandEscaped(m)))
+	}
+	return true
+}
+
+// drop removes the metrics whose dimension matches pattern,
+// without a dimension the pattern is matched against the metric name
+type drop struct {
+	dimension string
+	pattern   *regexp.Regexp
+}
+
+func newDrop(c map[string]interface{}) (Processor, error) {
+	pattern, err := compileKey(c, "pattern")
+	if err != nil {
+		return nil, err
+	}
+	dimension := ""
+	if _, exists := c["dimension"]; exists {
+		if dimension, err = stringKey(c, "dimension"); err != nil {
+			return nil, err
+		}
+	}
+	return drop{dimension, pattern}, nil
+}
+
+func (d drop) Process(m *metric.Metric) bool {
+	if d.dimension == "" {
+		return !d.pattern.MatchString(m.Name)
+	}
+	value, ok := m.GetDimensionValue(d.dimension)
+	return !(ok && d.pattern.MatchString(value))
+}
+
+// setType changes the type of the metric
+type setType struct {
+	metricType string
+}
+
+func newSetType(c map[string]interface{}) (Processor, error) {
+	metricType, err := stringKey(c, "metricType")
+	if err != nil {
+		return nil, err
+	}
+	switch metricType {
+	case metric.Gauge, metric.Counter, metric.CumulativeCounter:
+		return setType{metricType}, nil
+	}
+	return nil, fmt.Errorf("unknown metricType %q", metricType)
+}
+
+func (s setType) Process(m *metric.Metric) bool {
+	m.MetricType = s.metricType
+	return true
+}
+
+// template is a string referring to dimensions of a metric as %{dimension},
+// the dimensions the metric doesn't have expand to nothing
+type template string
+
+var templateField = regexp.MustCompile(`%\{([^{}]+)\}`)
+
+func (t template) expand(m *metric.Metric) string {
+	return t.expandWith(m, func(value string) string { return value })
+}
+
+// expandEscaped expands the template into a regexp replacement,
+// a $ in a dimension value doesn't refer to a group of the pattern
+func (t template) expandEscaped(m *metric.Metric) string {
+	return t.expandWith(m, func(value string) string {
+		return strings.Replace(value, "$", "$$", -1)
+	})
+}
+
+func (t template) expandWith(m *metric.Metric, escape func(string) string) string {
+	if !strings.Contains(string(t), "%{") {
+		return string(t)
+	}
+	return templateField.ReplaceAllStringFunc(string(t), func(field string) string {
+		value, _ := m.GetDimensionValue(field[2 : len(field)-1])
+		return escape(value)
+	})
+}
+
+func stringKey(c map[string]interface{}, key string) (string, error) {
+	asInterface, exists := c[key]
+	if !exists {
+		return "", fmt.Errorf("%s is required", key)
+	}
+	str, ok := asInterface.(string)
+	if !ok {
+		return "", fmt.Errorf("%s should be a string", key)
+	}
+	return str, nil
+}
+
+func compileKey(c map[string]interface{}, key string) (*regexp.Regexp, error) {
+	asInterface, exists := c[key]
+	if !exists {
+		return nil, fmt.Errorf("%s is required", key)
+	}
+	pattern, err := compile(asInterface)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", key, err)
+	}
+	return pattern, nil
+}
+
+func compile(value interface{}) (*regexp.Regexp, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("should be a regular expression")
+	}
+	return regexp.Compile(str)
+}
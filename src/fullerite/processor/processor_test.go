@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"fullerite/metric"
+
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildChain(t *testing.T, configs string) Chain {
+	var value interface{}
+	assert.Nil(t, json.Unmarshal([]byte(configs), &value))
+	chain, err := FromConfig(value)
+	assert.Nil(t, err)
+	return chain
+}
+
+func TestRename(t *testing.T) {
+	chain := buildChain(t, `[{"type": "rename", "pattern": "^cpu\\.(\\w+)$", "replacement": "system.cpu.$1"}]`)
+
+	m := metric.New("cpu.idle")
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, "system.cpu.idle", m.Name)
+
+	m = metric.New("memory.free")
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, "memory.free", m.Name)
+}
+
+func TestDimensionProcessors(t *testing.T) {
+	chain := buildChain(t, `[
+		{"type": "add_dimension", "key": "service", "value": "%{app}.%{instance}"},
+		{"type": "rename_dimension", "from": "container_name", "to": "container"},
+		{"type": "rewrite_dimension", "key": "host", "pattern": "^([^.]+)\\..*$", "replacement": "$1-%{region}"},
+		{"type": "drop_dimension", "keys": ["pid", "instance"]}
+	]`)
+
+	m := metric.New("test")
+	m.AddDimensions(map[string]string{
+		"app":            "web",
+		"instance":       "main",
+		"container_name": "web_1",
+		"host":           "box1.example.com",
+		"region":         "east",
+		"pid":            "123",
+	})
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, map[string]string{
+		"app":       "web",
+		"service":   "web.main",
+		"container": "web_1",
+		"host":      "box1-east",
+		"region":    "east",
+	}, m.Dimensions)
+}
+
+func TestRewriteDimensionKeepsDollarsOfDimensionValues(t *testing.T) {
+	chain := buildChain(t, `[
+		{"type": "rewrite_dimension", "key": "host", "pattern": "^([^.]+)\\..*$", "replacement": "$1-%{account}"}
+	]`)
+
+	m := metric.New("test")
+	m.AddDimensions(map[string]string{"host": "box1.example.com", "account": "$1$x"})
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, "box1-$1$x", m.Dimensions["host"])
+}
+
+func TestProcessCopiesDimensions(t *testing.T) {
+	chain := buildChain(t, `[{"type": "add_dimension", "key": "added", "value": "yes"}]`)
+
+	original := metric.New("test")
+	original.AddDimension("kept", "yes")
+	processed := original
+	chain.Process(&processed)
+
+	assert.Equal(t, map[string]string{"kept": "yes"}, original.Dimensions)
+	assert.Equal(t, map[string]string{"kept": "yes", "added": "yes"}, processed.Dimensions)
+}
+
+func TestDrop(t *testing.T) {
+	chain := buildChain(t, `[
+		{"type": "drop", "dimension": "env", "pattern": "^(dev|test)$"},
+		{"type": "drop", "pattern": "^debug\\."}
+	]`)
+
+	m := metric.New("requests")
+	m.AddDimension("env", "test")
+	assert.False(t, chain.Process(&m))
+
+	m = metric.New("requests")
+	m.AddDimension("env", "prod")
+	assert.True(t, chain.Process(&m))
+
+	m = metric.New("requests")
+	assert.True(t, chain.Process(&m), "metrics without the dimension are kept")
+
+	m = metric.New("debug.requests")
+	assert.False(t, chain.Process(&m))
+}
+
+func TestSetTypeLimitedToMatchingMetrics(t *testing.T) {
+	chain := buildChain(t, `[{"type": "set_type", "metric": "\\.count$", "metricType": "cumcounter"}]`)
+
+	m := metric.New("requests.count")
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, metric.CumulativeCounter, m.MetricType)
+
+	m = metric.New("requests.latency")
+	assert.True(t, chain.Process(&m))
+	assert.Equal(t, metric.Gauge, m.MetricType)
+}
+
+func TestChainStopsAtDrop(t *testing.T) {
+	chain := buildChain(t, `[
+		{"type": "rename", "pattern": "^old\\.", "replacement": "new."},
+		{"type": "drop", "pattern": "^new\\.skip$"},
+		{"type": "add_dimension", "key": "seen", "value": "yes"}
+	]`)
+
+	m := metric.New("old.skip")
+	assert.False(t, chain.Process(&m))
+	assert.Equal(t, "new.skip", m.Name)
+	_, seen := m.GetDimensionValue("seen")
+	assert.False(t, seen)
+}
+
+func TestInvalidProcessors(t *testing.T) {
+	var value interface{}
+	json.Unmarshal([]byte(`[
+		{"type": "unknown"},
+		{"type": "rename", "pattern": "(", "replacement": "x"},
+		{"type": "set_type", "metricType": "histogram"},
+		{"type": "add_dimension", "key": "valid", "value": "yes"},
+		{"type": "drop_dimension", "keys": "pid"}
+	]`), &value)
+
+	chain, err := FromConfig(value)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(chain), "the valid processors are kept")
+	assert.Contains(t, err.Error(), "processor 0: unknown type")
+	assert.Contains(t, err.Error(), "processor 1: rename: pattern")
+	assert.Contains(t, err.Error(), "processor 2: set_type: unknown metricType")
+	assert.Contains(t, err.Error(), "processor 4: drop_dimension: keys should be a list")
+
+	_, err = FromConfig("not a list")
+	assert.NotNil(t, err)
+}
+
+func TestEmptyChain(t *testing.T) {
+	var chain Chain
+	m := metric.New("test")
+	dimensions := m.Dimensions
+	assert.True(t, chain.Process(&m))
+	m.AddDimension("same", "map")
+	assert.Equal(t, "map", dimensions["same"])
+}
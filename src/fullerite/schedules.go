@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fullerite/collector"
+	"fullerite/internalserver"
+	"fullerite/metric"
+
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// collectorSchedule is how a running collector is scheduled, when it
+// collects next, how many collections it had to skip, whether it was
+// disabled for panicking too often or paused through the control API,
+// and where to ask it for a collection out of schedule
+type collectorSchedule struct {
+	// the collector instance the schedule belongs to, so that a collector
+	// replaced on reload leaves the schedule of its successor alone
+	owner    collector.Collector
+	schedule collector.Schedule
+	next     time.Time
+	skipped  uint64
+	disabled bool
+	trigger  chan<- struct{}
+
+	// 1 while paused, the reader checks it on every metric
+	paused int32
+}
+
+// collectorSchedules keeps the schedule of every running collector
+// for the internal server
+var collectorSchedules = struct {
+	sync.RWMutex
+	schedules map[string]*collectorSchedule
+}{schedules: make(map[string]*collectorSchedule)}
+
+// addCollectorSchedule registers the schedule of the collector
+// in place of the one of a collector with the same name
+func addCollectorSchedule(c collector.Collector) *collectorSchedule {
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	s := &collectorSchedule{owner: c}
+	collectorSchedules.schedules[c.CanonicalName()] = s
+	return s
+}
+
+// removeCollectorSchedule removes the schedule of the collector
+// unless another collector with the same name replaced it
+func removeCollectorSchedule(c collector.Collector) {
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	if s, exists := collectorSchedules.schedules[c.CanonicalName()]; exists && s.owner == c {
+		delete(collectorSchedules.schedules, c.CanonicalName())
+	}
+}
+
+// collectorScheduleOf returns the schedule of the collector,
+// nil when it isn't registered
+func collectorScheduleOf(c collector.Collector) *collectorSchedule {
+	collectorSchedules.RLock()
+	defer collectorSchedules.RUnlock()
+	if s, exists := collectorSchedules.schedules[c.CanonicalName()]; exists && s.owner == c {
+		return s
+	}
+	return nil
+}
+
+func (s *collectorSchedule) set(schedule collector.Schedule, next time.Time) {
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	s.schedule = schedule
+	s.next = next
+}
+
+// countSkipped records a collection skipped because
+// the previous one was still running
+func (s *collectorSchedule) countSkipped() {
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	s.skipped++
+}
+
+// disable records that the collector won't collect anymore
+func (s *collectorSchedule) disable() {
+	if s == nil {
+		return
+	}
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	s.disabled = true
+}
+
+// setTrigger records where the collector takes the requests
+// to collect out of schedule, listeners don't have one
+func (s *collectorSchedule) setTrigger(trigger chan<- struct{}) {
+	collectorSchedules.Lock()
+	defer collectorSchedules.Unlock()
+	s.trigger = trigger
+}
+
+// isPaused tells whether the collections and the metrics of the
+// collector are held back, it doesn't take the registry lock
+func (s *collectorSchedule) isPaused() bool {
+	return s != nil && atomic.LoadInt32(&s.paused) == 1
+}
+
+// pause holds back or lets through the collections
+// and the metrics of the collector
+func (s *collectorSchedule) pause(paused bool) {
+	var flag int32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreInt32(&s.paused, flag)
+}
+
+// triggerCollection asks the collector to collect as soon as possible
+func triggerCollection(collectorName string) error {
+	collectorSchedules.RLock()
+	defer collectorSchedules.RUnlock()
+	s, exists := collectorSchedules.schedules[collectorName]
+	if !exists {
+		return internalserver.ErrUnknownComponent
+	}
+	if s.trigger == nil {
+		return errors.New("listens for metrics, it can't collect on demand")
+	}
+	select {
+	case s.trigger <- struct{}{}:
+		return nil
+	default:
+		return errors.New("already has a collection on demand pending")
+	}
+}
+
+// pauseCollector stops or resumes the collections of the collector
+func pauseCollector(collectorName string, paused bool) error {
+	collectorSchedules.RLock()
+	defer collectorSchedules.RUnlock()
+	s, exists := collectorSchedules.schedules[collectorName]
+	if !exists {
+		return internalserver.ErrUnknownComponent
+	}
+	s.pause(paused)
+	return nil
+}
+
+func collectorPaused(collectorName string) bool {
+	collectorSchedules.RLock()
+	defer collectorSchedules.RUnlock()
+	return collectorSchedules.schedules[collectorName].isPaused()
+}
+
+// scheduleStats returns the internal metrics describing
+// the schedule of every running collector
+func scheduleStats() map[string]metric.InternalMetrics {
+	collectorSchedules.RLock()
+	defer collectorSchedules.RUnlock()
+	stats := make(map[string]metric.InternalMetrics, len(collectorSchedules.schedules))
+	for name, s := range collectorSchedules.schedules {
+		cron, aligned, disabled, paused := 0.0, 0.0, 0.0, 0.0
+		if s.schedule.Cron != "" {
+			cron = 1
+		}
+		if s.schedule.Align {
+			aligned = 1
+		}
+		if s.disabled {
+			disabled = 1
+		}
+		if s.isPaused() {
+			paused = 1
+		}
+		m := metric.NewInternalMetrics()
+		m.Counters["fullerite.collections_skipped"] = float64(s.skipped)
+		m.Counters["fullerite.collector_panics"] = float64(collector.Panics(name))
+		m.Gauges["fullerite.collector_disabled"] = disabled
+		m.Gauges["fullerite.collector_paused"] = paused
+		m.Gauges["fullerite.schedule_interval"] = s.schedule.Interval.Seconds()
+		m.Gauges["fullerite.schedule_splay"] = s.schedule.Offset.Seconds()
+		m.Gauges["fullerite.schedule_aligned"] = aligned
+		m.Gauges["fullerite.schedule_cron"] = cron
+		m.Gauges["fullerite.next_collection"] = float64(s.next.Unix())
+		stats[name] = *m
+	}
+	return stats
+}
+
+// mergeStats adds the metrics of from to the collector stats
+func mergeStats(stats map[string]metric.InternalMetrics, from map[string]metric.InternalMetrics) {
+	for name, extra := range from {
+		m, exists := stats[name]
+		if !exists {
+			m = *metric.NewInternalMetrics()
+		}
+		for k, v := range extra.Counters {
+			m.Counters[k] = v
+		}
+		for k, v := range extra.Gauges {
+			m.Gauges[k] = v
+		}
+		stats[name] = m
+	}
+}
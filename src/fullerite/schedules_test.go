@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fullerite/collector"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCollectorReportsItsSchedule(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := collector.New("Test")
+	col.SetInterval(60)
+	col.Configure(map[string]interface{}{"align": true})
+
+	addCollectorSchedule(col)
+	done := make(chan bool)
+	go func() {
+		runCollector(col)
+		done <- true
+	}()
+
+	var gauges map[string]float64
+	for i := 0; i < 100 && gauges["fullerite.schedule_interval"] == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		gauges = scheduleStats()["Test"].Gauges
+	}
+	if assert.NotNil(t, gauges) {
+		assert.Equal(t, 60.0, gauges["fullerite.schedule_interval"])
+		assert.Equal(t, 1.0, gauges["fullerite.schedule_aligned"])
+		assert.Equal(t, 0.0, gauges["fullerite.schedule_cron"])
+		assert.Equal(t, 0.0, gauges["fullerite.schedule_splay"])
+		next := int64(gauges["fullerite.next_collection"])
+		assert.Equal(t, int64(0), next%60, "the next collection should be on the minute")
+		assert.True(t, next > time.Now().Unix())
+	}
+
+	col.Stop()
+	<-done
+	_, exists := scheduleStats()["Test"]
+	assert.False(t, exists, "the schedule should be removed once the collector stops")
+}
+
+// blockingCollector collects until it is released
+type blockingCollector struct {
+	collector.Collector
+	release  chan bool
+	returned chan bool
+}
+
+func (c blockingCollector) Collect(ctx context.Context) {
+	<-c.release
+	c.returned <- true
+}
+
+func TestRunCollectorSkipsOverlappingCollections(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	col := collector.New("Test")
+	col.SetInterval(1)
+	col.Configure(map[string]interface{}{"collection_timeout": 5})
+	blocking := blockingCollector{col, make(chan bool), make(chan bool, 1)}
+
+	addCollectorSchedule(blocking)
+	done := make(chan bool)
+	go func() {
+		runCollector(blocking)
+		done <- true
+	}()
+
+	time.Sleep(2500 * time.Millisecond)
+	counters := scheduleStats()["Test"].Counters
+	col.Stop()
+	<-done
+	close(blocking.release)
+	<-blocking.returned
+
+	assert.Equal(t, 1.0, counters["fullerite.collections_skipped"], "the collection due while the first one runs should be skipped")
+}
+
+// panickingCollector panics on every collection
+type panickingCollector struct {
+	collector.Collector
+}
+
+func (c panickingCollector) Collect(ctx context.Context) {
+	panic("boom")
+}
+
+func TestRunCollectorRecoversPanics(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	col := collector.New("Test")
+	col.SetInterval(1)
+	before := collector.Panics("Test")
+
+	panicking := panickingCollector{col}
+	addCollectorSchedule(panicking)
+	done := make(chan bool)
+	go func() {
+		runCollector(panicking)
+		done <- true
+	}()
+
+	time.Sleep(1500 * time.Millisecond)
+	stats := scheduleStats()["Test"]
+	col.Stop()
+	<-done
+
+	assert.Equal(t, float64(before+1), stats.Counters["fullerite.collector_panics"])
+	assert.Equal(t, 0.0, stats.Gauges["fullerite.collector_disabled"], "a single panic should not disable the collector")
+}
+
+func TestRunCollectorLeavesScheduleOfReplacement(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	run := func(col collector.Collector) chan bool {
+		addCollectorSchedule(col)
+		done := make(chan bool)
+		go func() {
+			runCollector(col)
+			done <- true
+		}()
+		return done
+	}
+	replaced := collector.New("Test")
+	replaced.SetInterval(60)
+	replacedDone := run(replaced)
+	col := collector.New("Test")
+	col.SetInterval(60)
+	replacement := countingCollector{col, make(chan bool, 1)}
+	replacementDone := run(replacement)
+
+	// the replaced collector returns after its replacement started
+	time.Sleep(50 * time.Millisecond)
+	replaced.Stop()
+	<-replacedDone
+
+	gauges := scheduleStats()["Test"].Gauges
+	assert.True(t, gauges["fullerite.next_collection"] > float64(time.Now().Unix()), "the next collection should still be reported")
+	assert.Nil(t, triggerCollection("Test"), "the replacement should still collect on demand")
+	select {
+	case <-replacement.collected:
+	case <-time.After(time.Second):
+		t.Error("the replacement should collect on demand")
+	}
+
+	col.Stop()
+	<-replacementDone
+}
@@ -0,0 +1,15 @@
+package util
+
+import (
+	"context"
+	"net/http"
+)
+
+// HTTPGet performs a GET with client which is abandoned once ctx is done
+func HTTPGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req.WithContext(ctx))
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -32,10 +33,10 @@ func (e leaderError) Error() string {
 }
 
 // IsLeader checks if a given host is the marathon leader
-func IsLeader(host string, endpoint string, client http.Client) (bool, error) {
+func IsLeader(ctx context.Context, host string, endpoint string, client http.Client) (bool, error) {
 	url := getLeaderURL(host, endpoint)
 
-	contents, err := MarathonGet(url, client)
+	contents, err := MarathonGet(ctx, url, client)
 	if err != nil {
 		return false, err
 	}
@@ -62,8 +63,8 @@ func IsLeader(host string, endpoint string, client http.Client) (bool, error) {
 }
 
 // MarathonGet performs a get against a URL and return either the body of the response or an error
-func MarathonGet(url string, client http.Client) ([]byte, error) {
-	r, err := client.Get(url)
+func MarathonGet(ctx context.Context, url string, client http.Client) ([]byte, error) {
+	r, err := HTTPGet(ctx, &client, url)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -39,7 +40,7 @@ func TestIsLeader(t *testing.T) {
 		getLeaderURL = func(ip string, _ string) string { return ts.URL }
 		hostname = func() (string, error) { return test.ourHostname, nil }
 
-		actual, _ := IsLeader("", "", http.Client{})
+		actual, _ := IsLeader(context.Background(), "", "", http.Client{})
 
 		assert.Equal(t, test.expected, actual, test.msg)
 	}
@@ -0,0 +1,99 @@
+package util
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+)
+
+// Defaults for restarting the components that panicked
+const (
+	DefaultPanicBackoff    = time.Second
+	DefaultMaxPanicBackoff = time.Minute
+	DefaultMaxPanics       = 5
+)
+
+// Recover is meant to be deferred by the goroutines that should survive a
+// panic: it recovers it, logs it with its stack trace and calls onPanic with
+// what was recovered
+func Recover(log *l.Entry, onPanic func(recovered interface{})) {
+	if recovered := recover(); recovered != nil {
+		log.Error("Recovered from panic: ", recovered, "\n", string(debug.Stack()))
+		if onPanic != nil {
+			onPanic(recovered)
+		}
+	}
+}
+
+// PanicBackoff tells when a component that panicked can be restarted: the
+// delay doubles with every consecutive panic up to Max, and the component
+// is disabled for good after MaxPanics of them. The zero value uses the defaults.
+type PanicBackoff struct {
+	Initial   time.Duration
+	Max       time.Duration
+	MaxPanics int
+
+	mu       sync.Mutex
+	panics   int
+	resumeAt time.Time
+	disabled bool
+}
+
+// Panicked records a panic at now, it returns how long to wait before
+// restarting the component and whether it should be disabled instead
+func (b *PanicBackoff) Panicked(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	maxPanics := b.MaxPanics
+	if maxPanics <= 0 {
+		maxPanics = DefaultMaxPanics
+	}
+	delay := b.Initial
+	if delay <= 0 {
+		delay = DefaultPanicBackoff
+	}
+	maxDelay := b.Max
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxPanicBackoff
+	}
+
+	b.panics++
+	if b.panics >= maxPanics {
+		b.disabled = true
+		return 0, true
+	}
+	for i := 1; i < b.panics && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	b.resumeAt = now.Add(delay)
+	return delay, false
+}
+
+// Succeeded records a run without panic, the next panic starts over
+func (b *PanicBackoff) Succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.disabled {
+		b.panics = 0
+	}
+}
+
+// Ready tells whether the component can run at now
+func (b *PanicBackoff) Ready(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.disabled && !now.Before(b.resumeAt)
+}
+
+// Disabled tells whether the component panicked too many times in a row
+func (b *PanicBackoff) Disabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.disabled
+}
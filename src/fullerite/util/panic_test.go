@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	l "github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	var recovered interface{}
+	func() {
+		defer Recover(l.WithField("testing", "panic"), func(r interface{}) {
+			recovered = r
+		})
+		panic("boom")
+	}()
+	assert.Equal(t, "boom", recovered)
+
+	called := false
+	func() {
+		defer Recover(l.WithField("testing", "panic"), func(interface{}) {
+			called = true
+		})
+	}()
+	assert.False(t, called, "onPanic should only be called on a panic")
+}
+
+func TestPanicBackoff(t *testing.T) {
+	b := &PanicBackoff{Initial: time.Second, Max: 3 * time.Second, MaxPanics: 4}
+	now := time.Unix(100, 0)
+	assert.True(t, b.Ready(now))
+
+	delay, disabled := b.Panicked(now)
+	assert.Equal(t, time.Second, delay)
+	assert.False(t, disabled)
+	assert.False(t, b.Ready(now.Add(500*time.Millisecond)))
+	assert.True(t, b.Ready(now.Add(time.Second)))
+
+	delay, _ = b.Panicked(now)
+	assert.Equal(t, 2*time.Second, delay)
+	delay, _ = b.Panicked(now)
+	assert.Equal(t, 3*time.Second, delay, "the delay should be capped")
+
+	b.Succeeded()
+	delay, _ = b.Panicked(now)
+	assert.Equal(t, time.Second, delay, "a run without panic should reset the backoff")
+
+	for i := 0; i < 3; i++ {
+		_, disabled = b.Panicked(now)
+	}
+	assert.True(t, disabled)
+	assert.True(t, b.Disabled())
+	b.Succeeded()
+	assert.False(t, b.Ready(now.Add(time.Hour)), "a disabled component should stay disabled")
+}